@@ -20,6 +20,9 @@ type Config struct {
 	DBName     string
 	DBSSLMode  string
 
+	// SMS provider used for sending: "egosms", or "fake" to send nothing (for offline testing)
+	SMSProvider string
+
 	// SMS Provider configuration (egosms.co)
 	SMSLiveURL     string
 	SMSSandboxURL  string
@@ -28,11 +31,76 @@ type Config struct {
 	SMSSenderID    string
 	SMSSandboxMode bool
 
+	// Provider batching
+	SMSBatchSize        int // Maximum messages per provider request
+	SMSBatchConcurrency int // Default provider requests in flight per priority lane
+
+	// Provider account balance monitoring
+	ProviderBalanceCheckMinutes int    // How often the balance is checked, 0 disables checks
+	ProviderBalanceLowThreshold int    // Balance below which a low-balance alert is sent, 0 disables alerts
+	ProviderBalanceAlertURL     string // Webhook receiving low-balance alerts
+	ProviderBalanceAlertSecret  string // Signs low-balance alerts, like client webhooks
+
+	// Priority lanes. Messages are sent through the transactional, standard or bulk lane,
+	// each with its own limits; a lane only starts provider requests while no higher lane is waiting.
+	LaneTransactionalConcurrency int // Provider requests in flight
+	LaneTransactionalRate        int // Messages per second, 0 for no limit
+	LaneStandardConcurrency      int
+	LaneStandardRate             int
+	LaneBulkConcurrency          int
+	LaneBulkRate                 int
+
+	// Maximum messages accepted in one bulk request
+	MaxBulkMessages int
+
+	// Maximum (and default) campaign send rate in messages per second
+	CampaignRate int
+
+	// Link shortening. Short links are served at ShortLinkBaseURL + "/l/{code}";
+	// messages cannot ask for shortened links unless it is set.
+	ShortLinkBaseURL    string // Public URL of the gateway, e.g. "https://sms.example.com"
+	ShortLinkCodeLength int
+
+	// Token providers must present when pushing inbound messages. Empty refuses every request.
+	InboundWebhookToken string
+
+	// One-time verification codes
+	OTPSecret                   string // Key for hashing stored codes
+	OTPLength                   int    // Digits per code
+	OTPTTLSeconds               int    // How long a code is valid
+	OTPMaxAttempts              int    // Wrong codes allowed before a verification is locked
+	OTPResendCooldownSeconds    int    // Minimum time between sends to a verification
+	OTPMaxSendsPerNumberPerHour int    // Codes sent to one number per client per hour
+
+	// Prepaid billing. When enabled, messages are priced per segment and charged to the client's wallet.
+	WalletEnabled    bool
+	WalletCurrency   string  // Currency of wallet balances and prices, amounts are in its smallest unit
+	ProviderCostRate float64 // Wallet currency units per unit of the cost the provider reports
+
+	// Fraud guard (SMS pumping protection). Messages are scored from 0 to 100 and
+	// blocked, delayed or flagged when their score reaches the matching threshold.
+	FraudGuardEnabled        bool
+	FraudBlockScore          int
+	FraudDelayScore          int
+	FraudFlagScore           int
+	FraudDelayMinutes        int    // How long delayed messages are held for review
+	FraudHighRiskCountries   string // Comma-separated ISO 3166-1 alpha-2 codes
+	FraudPrefixThreshold     int    // Distinct recipients in one number range within the velocity window
+	FraudSequentialThreshold int    // Neighbouring numbers within the velocity window
+	FraudSpikeMultiplier     int    // Hourly volume over the client's average that counts as a spike
+	FraudSpikeMinimum        int    // Hourly volume below which no spike is reported
+
 	// API configuration
 	JWTSecret string
 
 	// Rate limiting
 	RateLimitRPS int
+
+	// Region (ISO 3166-1 alpha-2) used to read phone numbers without a country code
+	DefaultPhoneRegion string
+
+	// JSON file overriding the built-in operator prefix table
+	OperatorPrefixesFile string
 }
 
 var AppConfig *Config
@@ -52,6 +120,8 @@ func LoadConfig() error {
 		DBName:     getEnv("DB_NAME", "sms_gateway"),
 		DBSSLMode:  getEnv("DB_SSLMODE", "disable"),
 
+		SMSProvider: getEnv("SMS_PROVIDER", "egosms"),
+
 		SMSLiveURL:     getEnv("SMS_LIVE_URL", "https://www.egosms.co/api/v1/json/"),
 		SMSSandboxURL:  getEnv("SMS_SANDBOX_URL", "http://sandbox.egosms.co/api/v1/json/"),
 		SMSUsername:    getEnv("SMS_USERNAME", ""),
@@ -59,11 +129,61 @@ func LoadConfig() error {
 		SMSSenderID:    getEnv("SMS_SENDER_ID", ""),
 		SMSSandboxMode: getEnv("SMS_SANDBOX_MODE", "true") == "true",
 
+		SMSBatchSize:        getEnvAsInt("SMS_BATCH_SIZE", 500),
+		SMSBatchConcurrency: getEnvAsInt("SMS_BATCH_CONCURRENCY", 4),
+		MaxBulkMessages:     getEnvAsInt("MAX_BULK_MESSAGES", 10000),
+		CampaignRate:        getEnvAsInt("CAMPAIGN_RATE", 10),
+
+		ShortLinkBaseURL:    getEnv("SHORT_LINK_BASE_URL", ""),
+		ShortLinkCodeLength: getEnvAsInt("SHORT_LINK_CODE_LENGTH", 7),
+
+		ProviderBalanceCheckMinutes: getEnvAsInt("PROVIDER_BALANCE_CHECK_MINUTES", 15),
+		ProviderBalanceLowThreshold: getEnvAsInt("PROVIDER_BALANCE_LOW_THRESHOLD", 0),
+		ProviderBalanceAlertURL:     getEnv("PROVIDER_BALANCE_ALERT_URL", ""),
+		ProviderBalanceAlertSecret:  getEnv("PROVIDER_BALANCE_ALERT_SECRET", ""),
+
+		InboundWebhookToken: getEnv("INBOUND_WEBHOOK_TOKEN", ""),
+
+		WalletEnabled:    getEnv("WALLET_ENABLED", "false") == "true",
+		WalletCurrency:   getEnv("WALLET_CURRENCY", "UGX"),
+		ProviderCostRate: getEnvAsFloat("PROVIDER_COST_RATE", 1),
+
+		FraudGuardEnabled:        getEnv("FRAUD_GUARD_ENABLED", "false") == "true",
+		FraudBlockScore:          getEnvAsInt("FRAUD_BLOCK_SCORE", 80),
+		FraudDelayScore:          getEnvAsInt("FRAUD_DELAY_SCORE", 60),
+		FraudFlagScore:           getEnvAsInt("FRAUD_FLAG_SCORE", 40),
+		FraudDelayMinutes:        getEnvAsInt("FRAUD_DELAY_MINUTES", 30),
+		FraudHighRiskCountries:   getEnv("FRAUD_HIGH_RISK_COUNTRIES", ""),
+		FraudPrefixThreshold:     getEnvAsInt("FRAUD_PREFIX_THRESHOLD", 20),
+		FraudSequentialThreshold: getEnvAsInt("FRAUD_SEQUENTIAL_THRESHOLD", 5),
+		FraudSpikeMultiplier:     getEnvAsInt("FRAUD_SPIKE_MULTIPLIER", 10),
+		FraudSpikeMinimum:        getEnvAsInt("FRAUD_SPIKE_MINIMUM", 200),
+
 		JWTSecret: getEnv("JWT_SECRET", "your-secret-key-change-in-production"),
 
+		OTPLength:                   getEnvAsInt("OTP_LENGTH", 6),
+		OTPTTLSeconds:               getEnvAsInt("OTP_TTL_SECONDS", 300),
+		OTPMaxAttempts:              getEnvAsInt("OTP_MAX_ATTEMPTS", 5),
+		OTPResendCooldownSeconds:    getEnvAsInt("OTP_RESEND_COOLDOWN_SECONDS", 30),
+		OTPMaxSendsPerNumberPerHour: getEnvAsInt("OTP_MAX_SENDS_PER_NUMBER_PER_HOUR", 5),
+
 		RateLimitRPS: getEnvAsInt("RATE_LIMIT_RPS", 100),
+
+		DefaultPhoneRegion:   getEnv("DEFAULT_PHONE_REGION", "UG"),
+		OperatorPrefixesFile: getEnv("OPERATOR_PREFIXES_FILE", ""),
 	}
 
+	// Lanes send as many requests at once as a single send did unless configured
+	AppConfig.LaneTransactionalConcurrency = getEnvAsInt("LANE_TRANSACTIONAL_CONCURRENCY", AppConfig.SMSBatchConcurrency)
+	AppConfig.LaneTransactionalRate = getEnvAsInt("LANE_TRANSACTIONAL_RATE", 0)
+	AppConfig.LaneStandardConcurrency = getEnvAsInt("LANE_STANDARD_CONCURRENCY", AppConfig.SMSBatchConcurrency)
+	AppConfig.LaneStandardRate = getEnvAsInt("LANE_STANDARD_RATE", 0)
+	AppConfig.LaneBulkConcurrency = getEnvAsInt("LANE_BULK_CONCURRENCY", AppConfig.SMSBatchConcurrency)
+	AppConfig.LaneBulkRate = getEnvAsInt("LANE_BULK_RATE", 0)
+
+	// Codes are hashed with the JWT secret unless a dedicated key is configured
+	AppConfig.OTPSecret = getEnv("OTP_SECRET", AppConfig.JWTSecret)
+
 	return nil
 }
 
@@ -74,6 +194,16 @@ func getEnv(key, defaultValue string) string {
 	return defaultValue
 }
 
+func getEnvAsFloat(key string, defaultValue float64) float64 {
+	if value := os.Getenv(key); value != "" {
+		var result float64
+		if _, err := fmt.Sscanf(value, "%g", &result); err == nil {
+			return result
+		}
+	}
+	return defaultValue
+}
+
 func getEnvAsInt(key string, defaultValue int) int {
 	if value := os.Getenv(key); value != "" {
 		var result int
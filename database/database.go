@@ -63,6 +63,30 @@ func InitDB() error {
 	err = DB.AutoMigrate(
 		&models.APIClient{},
 		&models.SMSLog{},
+		&models.RecurringJob{},
+		&models.RecurringJobRun{},
+		&models.Contact{},
+		&models.Group{},
+		&models.GroupMember{},
+		&models.ContactImport{},
+		&models.ContactImportError{},
+		&models.Suppression{},
+		&models.Campaign{},
+		&models.InboundRoute{},
+		&models.InboundMessage{},
+		&models.KeywordRule{},
+		&models.InboundRuleHit{},
+		&models.Verification{},
+		&models.FraudWhitelist{},
+		&models.Wallet{},
+		&models.WalletTransaction{},
+		&models.PriceRule{},
+		&models.Statement{},
+		&models.ProviderBalance{},
+		&models.SenderID{},
+		&models.ContentRule{},
+		&models.ShortLink{},
+		&models.LinkClick{},
 	)
 
 	if err != nil {
@@ -0,0 +1,491 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/Ian-Balijawa/sms-gateway/config"
+	"github.com/Ian-Balijawa/sms-gateway/database"
+	"github.com/Ian-Balijawa/sms-gateway/models"
+	"github.com/Ian-Balijawa/sms-gateway/service"
+	"github.com/Ian-Balijawa/sms-gateway/utils"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+type CampaignHandler struct{}
+
+func NewCampaignHandler() *CampaignHandler {
+	return &CampaignHandler{}
+}
+
+// campaignRequest is the payload for creating and updating campaigns
+type campaignRequest struct {
+	Name         *string    `json:"name"`
+	Message      *string    `json:"message"`
+	SenderID     *string    `json:"senderid"`
+	Priority     *string    `json:"priority"`
+	ShortenLinks *bool      `json:"shorten_links"`
+	GroupID      *string    `json:"group_id"`
+	Recipients   *[]string  `json:"recipients"`
+	ScheduledAt  *time.Time `json:"scheduled_at"`
+	Rate         *int       `json:"rate"`
+}
+
+// apply copies the fields present in the request onto the campaign. An empty group_id clears the group.
+func (req *campaignRequest) apply(campaign *models.Campaign) error {
+	if req.Name != nil {
+		campaign.Name = *req.Name
+	}
+	if req.Message != nil {
+		campaign.Message = *req.Message
+	}
+	if req.SenderID != nil {
+		campaign.SenderID = *req.SenderID
+	}
+	if req.Priority != nil {
+		campaign.Priority = *req.Priority
+	}
+	if req.ShortenLinks != nil {
+		campaign.ShortenLinks = *req.ShortenLinks
+	}
+	if req.GroupID != nil {
+		campaign.GroupID = nil
+		if *req.GroupID != "" {
+			groupID, err := uuid.Parse(*req.GroupID)
+			if err != nil {
+				return fmt.Errorf("invalid group_id")
+			}
+			campaign.GroupID = &groupID
+		}
+	}
+	if req.Recipients != nil {
+		campaign.Recipients = *req.Recipients
+	}
+	if req.ScheduledAt != nil {
+		scheduledAt := req.ScheduledAt.UTC()
+		campaign.ScheduledAt = &scheduledAt
+	}
+	if req.Rate != nil {
+		campaign.Rate = *req.Rate
+	}
+	return nil
+}
+
+// validateCampaign checks the campaign and normalizes its recipients,
+// reading numbers without a country code in the given region
+func validateCampaign(campaign *models.Campaign, region string) (string, bool) {
+	if campaign.Name == "" {
+		return "name is required", false
+	}
+	if campaign.Message == "" {
+		return "message is required", false
+	}
+	if campaign.GroupID == nil && len(campaign.Recipients) == 0 {
+		return "group_id or at least one recipient is required", false
+	}
+	if campaign.GroupID != nil {
+		var group models.Group
+		if err := database.DB.Where("id = ? AND client_id = ?", campaign.GroupID, campaign.ClientID).First(&group).Error; err != nil {
+			return "group not found", false
+		}
+	}
+	for i, recipient := range campaign.Recipients {
+		parsed, err := utils.ParsePhone(recipient, region)
+		if err != nil {
+			return err.Error(), false
+		}
+		campaign.Recipients[i] = parsed.E164
+	}
+	if err := service.CheckSenderID(campaign.ClientID, campaign.SenderID); err != nil {
+		return err.Error(), false
+	}
+	if campaign.ShortenLinks && !service.ShortLinksEnabled() {
+		return "link shortening is not configured, set SHORT_LINK_BASE_URL", false
+	}
+	if campaign.Rate < 0 || campaign.Rate > config.AppConfig.CampaignRate {
+		return fmt.Sprintf("rate must be between 1 and %d messages per second, or 0 for the default", config.AppConfig.CampaignRate), false
+	}
+	return "", true
+}
+
+// findClientCampaign loads a campaign owned by the authenticated client
+func findClientCampaign(c *gin.Context) (*models.Campaign, bool) {
+	clientID, _ := c.Get("client_id")
+
+	var campaign models.Campaign
+	if err := database.DB.Where("id = ? AND client_id = ?", c.Param("id"), clientID).First(&campaign).Error; err != nil {
+		c.JSON(http.StatusNotFound, models.SMSResponse{
+			Success: false,
+			Message: "Campaign not found",
+		})
+		return nil, false
+	}
+	return &campaign, true
+}
+
+// transitionCampaign moves the campaign from one of the allowed statuses to the given status.
+// The update only applies if the campaign is still in the status it was loaded with, so it
+// cannot race with the campaign runner.
+func transitionCampaign(c *gin.Context, campaign *models.Campaign, status string, allowed []string, action string, updates map[string]interface{}) bool {
+	permitted := false
+	for _, from := range allowed {
+		if campaign.Status == from {
+			permitted = true
+			break
+		}
+	}
+	if !permitted {
+		c.JSON(http.StatusConflict, models.SMSResponse{
+			Success: false,
+			Message: fmt.Sprintf("Campaign cannot be %s", action),
+			Error:   fmt.Sprintf("campaign is %s", campaign.Status),
+		})
+		return false
+	}
+
+	if updates == nil {
+		updates = map[string]interface{}{}
+	}
+	updates["status"] = status
+	result := database.DB.Model(&models.Campaign{}).
+		Where("id = ? AND status = ?", campaign.ID, campaign.Status).
+		Updates(updates)
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, models.SMSResponse{
+			Success: false,
+			Message: "Failed to update campaign",
+			Error:   result.Error.Error(),
+		})
+		return false
+	}
+	if result.RowsAffected == 0 {
+		c.JSON(http.StatusConflict, models.SMSResponse{
+			Success: false,
+			Message: fmt.Sprintf("Campaign cannot be %s", action),
+			Error:   "campaign status changed, try again",
+		})
+		return false
+	}
+
+	database.DB.First(campaign, "id = ?", campaign.ID)
+	return true
+}
+
+// CreateCampaign creates a draft campaign for the authenticated client
+func (h *CampaignHandler) CreateCampaign(c *gin.Context) {
+	var req campaignRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.SMSResponse{
+			Success: false,
+			Message: "Invalid request payload",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	clientID, exists := c.Get("client_id")
+	if !exists {
+		c.JSON(http.StatusInternalServerError, models.SMSResponse{
+			Success: false,
+			Message: "Client ID not found",
+		})
+		return
+	}
+
+	campaign := models.Campaign{
+		ClientID: clientID.(uuid.UUID),
+		Status:   models.CampaignDraft,
+	}
+	if err := req.apply(&campaign); err != nil {
+		c.JSON(http.StatusBadRequest, models.SMSResponse{
+			Success: false,
+			Message: "Invalid campaign",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	if msg, ok := validateCampaign(&campaign, clientRegion(c)); !ok {
+		c.JSON(http.StatusBadRequest, models.SMSResponse{
+			Success: false,
+			Message: "Invalid campaign",
+			Error:   msg,
+		})
+		return
+	}
+
+	if err := database.DB.Create(&campaign).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, models.SMSResponse{
+			Success: false,
+			Message: "Failed to create campaign",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, models.SMSResponse{
+		Success: true,
+		Message: "Campaign created successfully",
+		Data:    campaign,
+	})
+}
+
+// ListCampaigns lists the authenticated client's campaigns
+func (h *CampaignHandler) ListCampaigns(c *gin.Context) {
+	clientID, _ := c.Get("client_id")
+	limit, offset := paginationParams(c)
+
+	query := database.DB.Where("client_id = ?", clientID).Order("created_at DESC")
+	if status := c.Query("status"); status != "" {
+		query = query.Where("status = ?", status)
+	}
+
+	var campaigns []models.Campaign
+	if err := query.Limit(limit).Offset(offset).Find(&campaigns).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, models.SMSResponse{
+			Success: false,
+			Message: "Failed to retrieve campaigns",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.SMSResponse{
+		Success: true,
+		Message: "Campaigns retrieved successfully",
+		Data:    campaigns,
+	})
+}
+
+// GetCampaign returns a single campaign
+func (h *CampaignHandler) GetCampaign(c *gin.Context) {
+	campaign, ok := findClientCampaign(c)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, models.SMSResponse{
+		Success: true,
+		Message: "Campaign retrieved successfully",
+		Data:    campaign,
+	})
+}
+
+// UpdateCampaign updates a campaign that has not started yet
+func (h *CampaignHandler) UpdateCampaign(c *gin.Context) {
+	var req campaignRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.SMSResponse{
+			Success: false,
+			Message: "Invalid request payload",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	campaign, ok := findClientCampaign(c)
+	if !ok {
+		return
+	}
+
+	if campaign.StartedAt != nil || (campaign.Status != models.CampaignDraft && campaign.Status != models.CampaignScheduled && campaign.Status != models.CampaignPaused) {
+		c.JSON(http.StatusConflict, models.SMSResponse{
+			Success: false,
+			Message: "Campaign cannot be updated once it has started",
+			Error:   fmt.Sprintf("campaign is %s", campaign.Status),
+		})
+		return
+	}
+
+	if err := req.apply(campaign); err != nil {
+		c.JSON(http.StatusBadRequest, models.SMSResponse{
+			Success: false,
+			Message: "Invalid campaign",
+			Error:   err.Error(),
+		})
+		return
+	}
+	if msg, ok := validateCampaign(campaign, clientRegion(c)); !ok {
+		c.JSON(http.StatusBadRequest, models.SMSResponse{
+			Success: false,
+			Message: "Invalid campaign",
+			Error:   msg,
+		})
+		return
+	}
+
+	// Only save if the runner has not started the campaign in the meantime
+	result := database.DB.Model(&models.Campaign{}).
+		Where("id = ? AND status = ? AND started_at IS NULL", campaign.ID, campaign.Status).
+		Select("name", "message", "sender_id", "priority", "group_id", "recipients", "scheduled_at", "rate").
+		Updates(campaign)
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, models.SMSResponse{
+			Success: false,
+			Message: "Failed to update campaign",
+			Error:   result.Error.Error(),
+		})
+		return
+	}
+	if result.RowsAffected == 0 {
+		c.JSON(http.StatusConflict, models.SMSResponse{
+			Success: false,
+			Message: "Campaign cannot be updated once it has started",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.SMSResponse{
+		Success: true,
+		Message: "Campaign updated successfully",
+		Data:    campaign,
+	})
+}
+
+// DeleteCampaign deletes a campaign that is not running. Its messages are kept.
+func (h *CampaignHandler) DeleteCampaign(c *gin.Context) {
+	campaign, ok := findClientCampaign(c)
+	if !ok {
+		return
+	}
+
+	if campaign.Status == models.CampaignRunning || (campaign.Status == models.CampaignPaused && campaign.StartedAt != nil) {
+		c.JSON(http.StatusConflict, models.SMSResponse{
+			Success: false,
+			Message: "Campaign must be cancelled before it can be deleted",
+			Error:   fmt.Sprintf("campaign is %s", campaign.Status),
+		})
+		return
+	}
+
+	if err := database.DB.Delete(campaign).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, models.SMSResponse{
+			Success: false,
+			Message: "Failed to delete campaign",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.SMSResponse{
+		Success: true,
+		Message: "Campaign deleted successfully",
+	})
+}
+
+// ScheduleCampaign queues a draft campaign to start at its scheduled time, or immediately if none is set
+func (h *CampaignHandler) ScheduleCampaign(c *gin.Context) {
+	campaign, ok := findClientCampaign(c)
+	if !ok {
+		return
+	}
+
+	if !transitionCampaign(c, campaign, models.CampaignScheduled, []string{models.CampaignDraft}, "scheduled", nil) {
+		return
+	}
+
+	c.JSON(http.StatusOK, models.SMSResponse{
+		Success: true,
+		Message: "Campaign scheduled successfully",
+		Data:    campaign,
+	})
+}
+
+// PauseCampaign stops a scheduled or running campaign until it is resumed.
+// Messages already handed to the provider are not affected.
+func (h *CampaignHandler) PauseCampaign(c *gin.Context) {
+	campaign, ok := findClientCampaign(c)
+	if !ok {
+		return
+	}
+
+	if !transitionCampaign(c, campaign, models.CampaignPaused, []string{models.CampaignScheduled, models.CampaignRunning}, "paused", nil) {
+		return
+	}
+
+	c.JSON(http.StatusOK, models.SMSResponse{
+		Success: true,
+		Message: "Campaign paused successfully",
+		Data:    campaign,
+	})
+}
+
+// ResumeCampaign continues a paused campaign from where it stopped.
+// A campaign paused before it started goes back to being scheduled.
+func (h *CampaignHandler) ResumeCampaign(c *gin.Context) {
+	campaign, ok := findClientCampaign(c)
+	if !ok {
+		return
+	}
+
+	status := models.CampaignScheduled
+	if campaign.StartedAt != nil {
+		status = models.CampaignRunning
+	}
+	updates := map[string]interface{}{"last_error": ""}
+	if !transitionCampaign(c, campaign, status, []string{models.CampaignPaused}, "resumed", updates) {
+		return
+	}
+
+	c.JSON(http.StatusOK, models.SMSResponse{
+		Success: true,
+		Message: "Campaign resumed successfully",
+		Data:    campaign,
+	})
+}
+
+// CancelCampaign stops a campaign for good. Messages not yet sent are cancelled.
+func (h *CampaignHandler) CancelCampaign(c *gin.Context) {
+	campaign, ok := findClientCampaign(c)
+	if !ok {
+		return
+	}
+
+	allowed := []string{models.CampaignDraft, models.CampaignScheduled, models.CampaignRunning, models.CampaignPaused}
+	updates := map[string]interface{}{"completed_at": time.Now()}
+	if !transitionCampaign(c, campaign, models.CampaignCancelled, allowed, "cancelled", updates) {
+		return
+	}
+
+	if err := service.CancelCampaignMessages(campaign.ID); err != nil {
+		c.JSON(http.StatusInternalServerError, models.SMSResponse{
+			Success: false,
+			Message: "Campaign cancelled, but its queued messages could not be cancelled",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.SMSResponse{
+		Success: true,
+		Message: "Campaign cancelled successfully",
+		Data:    campaign,
+	})
+}
+
+// GetCampaignStats reports the campaign's progress and message counts by status
+func (h *CampaignHandler) GetCampaignStats(c *gin.Context) {
+	campaign, ok := findClientCampaign(c)
+	if !ok {
+		return
+	}
+
+	stats, err := service.GetCampaignStats(campaign)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.SMSResponse{
+			Success: false,
+			Message: "Failed to retrieve campaign stats",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.SMSResponse{
+		Success: true,
+		Message: "Campaign stats retrieved successfully",
+		Data:    stats,
+	})
+}
@@ -1,11 +1,15 @@
 package handlers
 
 import (
+	"fmt"
 	"net/http"
+	"net/url"
 	"time"
 
 	"github.com/Ian-Balijawa/sms-gateway/database"
 	"github.com/Ian-Balijawa/sms-gateway/models"
+	"github.com/Ian-Balijawa/sms-gateway/service"
+	"github.com/Ian-Balijawa/sms-gateway/utils"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
@@ -26,6 +30,16 @@ func (h *ClientHandler) CreateClient(c *gin.Context) {
 		RateLimit    int    `json:"rate_limit"`
 		DailyLimit   int    `json:"daily_limit"`
 		MonthlyLimit int    `json:"monthly_limit"`
+
+		QuietHoursStart string `json:"quiet_hours_start"`
+		QuietHoursEnd   string `json:"quiet_hours_end"`
+		DefaultRegion   string `json:"default_region"`
+
+		WebhookURL    string `json:"webhook_url"`
+		WebhookSecret string `json:"webhook_secret"`
+		OTPTemplate   string `json:"otp_template"`
+
+		ContentPolicyMode string `json:"content_policy_mode"`
 	}
 
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -37,6 +51,52 @@ func (h *ClientHandler) CreateClient(c *gin.Context) {
 		return
 	}
 
+	if err := validateQuietHoursWindow(req.QuietHoursStart, req.QuietHoursEnd); err != nil {
+		c.JSON(http.StatusBadRequest, models.SMSResponse{
+			Success: false,
+			Message: "Invalid quiet hours",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	defaultRegion, err := normalizeRegion(req.DefaultRegion)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.SMSResponse{
+			Success: false,
+			Message: "Invalid default region",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	if err := validateWebhookURL(req.WebhookURL); err != nil {
+		c.JSON(http.StatusBadRequest, models.SMSResponse{
+			Success: false,
+			Message: "Invalid webhook URL",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	if req.OTPTemplate != "" && !service.OTPTemplateHasCode(req.OTPTemplate) {
+		c.JSON(http.StatusBadRequest, models.SMSResponse{
+			Success: false,
+			Message: "Invalid OTP template",
+			Error:   "otp_template must contain {{code}}",
+		})
+		return
+	}
+
+	if err := validateContentPolicyMode(req.ContentPolicyMode); err != nil {
+		c.JSON(http.StatusBadRequest, models.SMSResponse{
+			Success: false,
+			Message: "Invalid content policy mode",
+			Error:   err.Error(),
+		})
+		return
+	}
+
 	// Check if email already exists
 	var existingClient models.APIClient
 	if err := database.DB.Where("email = ?", req.Email).First(&existingClient).Error; err == nil {
@@ -88,6 +148,16 @@ func (h *ClientHandler) CreateClient(c *gin.Context) {
 		DailyLimit:   dailyLimit,
 		MonthlyLimit: monthlyLimit,
 		LastReset:    time.Now(),
+
+		QuietHoursStart: req.QuietHoursStart,
+		QuietHoursEnd:   req.QuietHoursEnd,
+		DefaultRegion:   defaultRegion,
+
+		WebhookURL:    req.WebhookURL,
+		WebhookSecret: req.WebhookSecret,
+		OTPTemplate:   req.OTPTemplate,
+
+		ContentPolicyMode: req.ContentPolicyMode,
 	}
 
 	if err := database.DB.Create(&client).Error; err != nil {
@@ -112,6 +182,12 @@ func (h *ClientHandler) CreateClient(c *gin.Context) {
 			"rate_limit":  client.RateLimit,
 			"daily_limit": client.DailyLimit,
 			"monthly_limit": client.MonthlyLimit,
+			"quiet_hours_start": client.QuietHoursStart,
+			"quiet_hours_end": client.QuietHoursEnd,
+			"default_region": client.DefaultRegion,
+			"webhook_url": client.WebhookURL,
+			"otp_template": client.OTPTemplate,
+			"content_policy_mode": client.ContentPolicyMode,
 			"warning":     "Save these credentials securely. The API secret will not be shown again.",
 		},
 	})
@@ -159,6 +235,16 @@ func (h *ClientHandler) UpdateClient(c *gin.Context) {
 		RateLimit    *int    `json:"rate_limit"`
 		DailyLimit   *int    `json:"daily_limit"`
 		MonthlyLimit *int    `json:"monthly_limit"`
+
+		QuietHoursStart *string `json:"quiet_hours_start"`
+		QuietHoursEnd   *string `json:"quiet_hours_end"`
+		DefaultRegion   *string `json:"default_region"`
+
+		WebhookURL    *string `json:"webhook_url"`
+		WebhookSecret *string `json:"webhook_secret"`
+		OTPTemplate   *string `json:"otp_template"`
+
+		ContentPolicyMode *string `json:"content_policy_mode"`
 	}
 
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -195,6 +281,68 @@ func (h *ClientHandler) UpdateClient(c *gin.Context) {
 	if req.MonthlyLimit != nil {
 		client.MonthlyLimit = *req.MonthlyLimit
 	}
+	if req.QuietHoursStart != nil {
+		client.QuietHoursStart = *req.QuietHoursStart
+	}
+	if req.QuietHoursEnd != nil {
+		client.QuietHoursEnd = *req.QuietHoursEnd
+	}
+	if err := validateQuietHoursWindow(client.QuietHoursStart, client.QuietHoursEnd); err != nil {
+		c.JSON(http.StatusBadRequest, models.SMSResponse{
+			Success: false,
+			Message: "Invalid quiet hours",
+			Error:   err.Error(),
+		})
+		return
+	}
+	if req.DefaultRegion != nil {
+		defaultRegion, err := normalizeRegion(*req.DefaultRegion)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, models.SMSResponse{
+				Success: false,
+				Message: "Invalid default region",
+				Error:   err.Error(),
+			})
+			return
+		}
+		client.DefaultRegion = defaultRegion
+	}
+	if req.WebhookURL != nil {
+		if err := validateWebhookURL(*req.WebhookURL); err != nil {
+			c.JSON(http.StatusBadRequest, models.SMSResponse{
+				Success: false,
+				Message: "Invalid webhook URL",
+				Error:   err.Error(),
+			})
+			return
+		}
+		client.WebhookURL = *req.WebhookURL
+	}
+	if req.WebhookSecret != nil {
+		client.WebhookSecret = *req.WebhookSecret
+	}
+	if req.OTPTemplate != nil {
+		if *req.OTPTemplate != "" && !service.OTPTemplateHasCode(*req.OTPTemplate) {
+			c.JSON(http.StatusBadRequest, models.SMSResponse{
+				Success: false,
+				Message: "Invalid OTP template",
+				Error:   "otp_template must contain {{code}}",
+			})
+			return
+		}
+		client.OTPTemplate = *req.OTPTemplate
+	}
+	if req.ContentPolicyMode != nil {
+		if err := validateContentPolicyMode(*req.ContentPolicyMode); err != nil {
+			c.JSON(http.StatusBadRequest, models.SMSResponse{
+				Success: false,
+				Message: "Invalid content policy mode",
+				Error:   err.Error(),
+			})
+			return
+		}
+		client.ContentPolicyMode = *req.ContentPolicyMode
+	}
 
 	if err := database.DB.Save(&client).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, models.SMSResponse{
@@ -248,3 +396,36 @@ func (h *ClientHandler) ResetClientUsage(c *gin.Context) {
 	})
 }
 
+// normalizeRegion validates an optional ISO 3166-1 alpha-2 region code and returns it in upper case
+func normalizeRegion(region string) (string, error) {
+	if region == "" {
+		return "", nil
+	}
+	country, ok := utils.CountryByCode(region)
+	if !ok {
+		return "", fmt.Errorf("unknown region %q", region)
+	}
+	return country.Code, nil
+}
+
+// validateContentPolicyMode checks an optional content policy mode
+func validateContentPolicyMode(mode string) error {
+	switch mode {
+	case "", models.ContentPolicyReject, models.ContentPolicyFlag:
+		return nil
+	}
+	return fmt.Errorf("content_policy_mode must be %q or %q", models.ContentPolicyReject, models.ContentPolicyFlag)
+}
+
+// validateWebhookURL checks that an optional webhook URL is an absolute http(s) URL
+func validateWebhookURL(webhookURL string) error {
+	if webhookURL == "" {
+		return nil
+	}
+	parsed, err := url.Parse(webhookURL)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		return fmt.Errorf("webhook_url must be an http or https URL")
+	}
+	return nil
+}
+
@@ -0,0 +1,257 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/Ian-Balijawa/sms-gateway/database"
+	"github.com/Ian-Balijawa/sms-gateway/models"
+	"github.com/Ian-Balijawa/sms-gateway/utils"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+type ContactHandler struct{}
+
+func NewContactHandler() *ContactHandler {
+	return &ContactHandler{}
+}
+
+// normalizeContactPhone parses a phone number in the given region and returns its E.164 form
+func normalizeContactPhone(phone, region string) (string, error) {
+	parsed, err := utils.ParsePhone(phone, region)
+	if err != nil {
+		return "", err
+	}
+	return parsed.E164, nil
+}
+
+// findClientContact loads a contact owned by the authenticated client
+func findClientContact(c *gin.Context) (*models.Contact, bool) {
+	clientID, _ := c.Get("client_id")
+
+	var contact models.Contact
+	if err := database.DB.Where("id = ? AND client_id = ?", c.Param("id"), clientID).First(&contact).Error; err != nil {
+		c.JSON(http.StatusNotFound, models.SMSResponse{
+			Success: false,
+			Message: "Contact not found",
+		})
+		return nil, false
+	}
+	return &contact, true
+}
+
+// CreateContact creates a contact for the authenticated client.
+// A contact with the same normalized phone number must not already exist.
+func (h *ContactHandler) CreateContact(c *gin.Context) {
+	var req struct {
+		Phone      string            `json:"phone" binding:"required"`
+		Name       string            `json:"name"`
+		Attributes map[string]string `json:"attributes"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.SMSResponse{
+			Success: false,
+			Message: "Invalid request payload",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	phone, err := normalizeContactPhone(req.Phone, clientRegion(c))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.SMSResponse{
+			Success: false,
+			Message: "Invalid phone number",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	clientID, _ := c.Get("client_id")
+
+	// Deduplicate by normalized phone number
+	var existing models.Contact
+	if err := database.DB.Where("client_id = ? AND phone = ?", clientID, phone).First(&existing).Error; err == nil {
+		c.JSON(http.StatusConflict, models.SMSResponse{
+			Success: false,
+			Message: "Contact with this phone number already exists",
+			Data:    existing,
+		})
+		return
+	}
+
+	contact := models.Contact{
+		ClientID:   clientID.(uuid.UUID),
+		Phone:      phone,
+		Name:       req.Name,
+		Attributes: req.Attributes,
+	}
+
+	if err := database.DB.Create(&contact).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, models.SMSResponse{
+			Success: false,
+			Message: "Failed to create contact",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, models.SMSResponse{
+		Success: true,
+		Message: "Contact created successfully",
+		Data:    contact,
+	})
+}
+
+// ListContacts lists the authenticated client's contacts
+func (h *ContactHandler) ListContacts(c *gin.Context) {
+	clientID, _ := c.Get("client_id")
+
+	query := database.DB.Where("contacts.client_id = ?", clientID).Order("contacts.created_at DESC")
+
+	// Search by name or phone
+	if search := c.Query("search"); search != "" {
+		like := "%" + search + "%"
+		query = query.Where("contacts.name LIKE ? OR contacts.phone LIKE ?", like, like)
+	}
+
+	// Filter by group membership
+	if groupID := c.Query("group_id"); groupID != "" {
+		query = query.Joins("JOIN group_members ON group_members.contact_id = contacts.id").
+			Where("group_members.group_id = ?", groupID)
+	}
+
+	limit, offset := paginationParams(c)
+	query = query.Limit(limit).Offset(offset)
+
+	var contacts []models.Contact
+	if err := query.Find(&contacts).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, models.SMSResponse{
+			Success: false,
+			Message: "Failed to retrieve contacts",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.SMSResponse{
+		Success: true,
+		Message: "Contacts retrieved successfully",
+		Data:    contacts,
+	})
+}
+
+// GetContact returns a single contact
+func (h *ContactHandler) GetContact(c *gin.Context) {
+	contact, ok := findClientContact(c)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, models.SMSResponse{
+		Success: true,
+		Message: "Contact retrieved successfully",
+		Data:    contact,
+	})
+}
+
+// UpdateContact updates a contact's phone, name or attributes
+func (h *ContactHandler) UpdateContact(c *gin.Context) {
+	var req struct {
+		Phone      *string            `json:"phone"`
+		Name       *string            `json:"name"`
+		Attributes *map[string]string `json:"attributes"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.SMSResponse{
+			Success: false,
+			Message: "Invalid request payload",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	contact, ok := findClientContact(c)
+	if !ok {
+		return
+	}
+
+	if req.Phone != nil {
+		phone, err := normalizeContactPhone(*req.Phone, clientRegion(c))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, models.SMSResponse{
+				Success: false,
+				Message: "Invalid phone number",
+				Error:   err.Error(),
+			})
+			return
+		}
+
+		var existing models.Contact
+		if err := database.DB.Where("client_id = ? AND phone = ? AND id <> ?", contact.ClientID, phone, contact.ID).
+			First(&existing).Error; err == nil {
+			c.JSON(http.StatusConflict, models.SMSResponse{
+				Success: false,
+				Message: "Contact with this phone number already exists",
+				Data:    existing,
+			})
+			return
+		}
+		contact.Phone = phone
+	}
+	if req.Name != nil {
+		contact.Name = *req.Name
+	}
+	if req.Attributes != nil {
+		contact.Attributes = *req.Attributes
+	}
+
+	if err := database.DB.Save(contact).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, models.SMSResponse{
+			Success: false,
+			Message: "Failed to update contact",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.SMSResponse{
+		Success: true,
+		Message: "Contact updated successfully",
+		Data:    contact,
+	})
+}
+
+// DeleteContact deletes a contact and removes it from all groups
+func (h *ContactHandler) DeleteContact(c *gin.Context) {
+	contact, ok := findClientContact(c)
+	if !ok {
+		return
+	}
+
+	if err := database.DB.Where("contact_id = ?", contact.ID).Delete(&models.GroupMember{}).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, models.SMSResponse{
+			Success: false,
+			Message: "Failed to delete contact",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	if err := database.DB.Delete(contact).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, models.SMSResponse{
+			Success: false,
+			Message: "Failed to delete contact",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.SMSResponse{
+		Success: true,
+		Message: "Contact deleted successfully",
+	})
+}
@@ -0,0 +1,190 @@
+package handlers
+
+import (
+	"encoding/csv"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/Ian-Balijawa/sms-gateway/database"
+	"github.com/Ian-Balijawa/sms-gateway/models"
+	"github.com/Ian-Balijawa/sms-gateway/service"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// csvBatchSize is the number of rows loaded per query when streaming CSV downloads
+const csvBatchSize = 1000
+
+// ImportContacts accepts a CSV upload and imports its contacts in the background.
+// The response contains the import record, whose progress can be polled.
+func (h *ContactHandler) ImportContacts(c *gin.Context) {
+	fileHeader, err := c.FormFile("file")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.SMSResponse{
+			Success: false,
+			Message: "Invalid request payload",
+			Error:   "A CSV file is required in the 'file' field",
+		})
+		return
+	}
+
+	clientID, _ := c.Get("client_id")
+
+	imp := models.ContactImport{
+		ClientID:       clientID.(uuid.UUID),
+		FileName:       fileHeader.Filename,
+		PhoneColumn:    c.DefaultPostForm("phone_column", "phone"),
+		NameColumn:     c.DefaultPostForm("name_column", "name"),
+		UpdateExisting: c.PostForm("update_existing") == "true",
+		Status:         models.ImportQueued,
+	}
+	for _, column := range strings.Split(c.PostForm("attribute_columns"), ",") {
+		if column = strings.TrimSpace(column); column != "" {
+			imp.AttributeColumns = append(imp.AttributeColumns, column)
+		}
+	}
+
+	// Optionally add every imported contact to a group
+	if groupID := c.PostForm("group_id"); groupID != "" {
+		var group models.Group
+		if err := database.DB.Where("id = ? AND client_id = ?", groupID, clientID).First(&group).Error; err != nil {
+			c.JSON(http.StatusNotFound, models.SMSResponse{
+				Success: false,
+				Message: "Group not found",
+			})
+			return
+		}
+		imp.GroupID = &group.ID
+	}
+
+	// Keep the upload on disk until the background job has processed it
+	tmp, err := os.CreateTemp("", "contact-import-*.csv")
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.SMSResponse{
+			Success: false,
+			Message: "Failed to store uploaded file",
+			Error:   err.Error(),
+		})
+		return
+	}
+	tmp.Close()
+
+	if err := c.SaveUploadedFile(fileHeader, tmp.Name()); err != nil {
+		os.Remove(tmp.Name())
+		c.JSON(http.StatusInternalServerError, models.SMSResponse{
+			Success: false,
+			Message: "Failed to store uploaded file",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	if err := database.DB.Create(&imp).Error; err != nil {
+		os.Remove(tmp.Name())
+		c.JSON(http.StatusInternalServerError, models.SMSResponse{
+			Success: false,
+			Message: "Failed to create import",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	service.StartContactImport(imp, tmp.Name())
+
+	c.JSON(http.StatusAccepted, models.SMSResponse{
+		Success: true,
+		Message: "Contact import started",
+		Data:    imp,
+	})
+}
+
+// findClientImport loads a contact import owned by the authenticated client
+func findClientImport(c *gin.Context) (*models.ContactImport, bool) {
+	clientID, _ := c.Get("client_id")
+
+	var imp models.ContactImport
+	if err := database.DB.Where("id = ? AND client_id = ?", c.Param("id"), clientID).First(&imp).Error; err != nil {
+		c.JSON(http.StatusNotFound, models.SMSResponse{
+			Success: false,
+			Message: "Import not found",
+		})
+		return nil, false
+	}
+	return &imp, true
+}
+
+// ListContactImports lists the authenticated client's contact imports
+func (h *ContactHandler) ListContactImports(c *gin.Context) {
+	clientID, _ := c.Get("client_id")
+	limit, offset := paginationParams(c)
+
+	var imports []models.ContactImport
+	if err := database.DB.Where("client_id = ?", clientID).
+		Order("created_at DESC").
+		Limit(limit).Offset(offset).
+		Find(&imports).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, models.SMSResponse{
+			Success: false,
+			Message: "Failed to retrieve imports",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.SMSResponse{
+		Success: true,
+		Message: "Imports retrieved successfully",
+		Data:    imports,
+	})
+}
+
+// GetContactImport returns the status and progress of a contact import
+func (h *ContactHandler) GetContactImport(c *gin.Context) {
+	imp, ok := findClientImport(c)
+	if !ok {
+		return
+	}
+
+	progress := 0.0
+	if imp.TotalRows > 0 {
+		progress = float64(imp.ProcessedRows) * 100 / float64(imp.TotalRows)
+	}
+
+	c.JSON(http.StatusOK, models.SMSResponse{
+		Success: true,
+		Message: "Import retrieved successfully",
+		Data: map[string]interface{}{
+			"import":   imp,
+			"progress": progress,
+		},
+	})
+}
+
+// GetContactImportErrors downloads the rows rejected by an import as CSV
+func (h *ContactHandler) GetContactImportErrors(c *gin.Context) {
+	imp, ok := findClientImport(c)
+	if !ok {
+		return
+	}
+
+	c.Header("Content-Type", "text/csv")
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=\"import-%s-errors.csv\"", imp.ID))
+
+	writer := csv.NewWriter(c.Writer)
+	writer.Write([]string{"row", "phone", "reason"})
+
+	var batch []models.ContactImportError
+	database.DB.Where("import_id = ?", imp.ID).FindInBatches(&batch, csvBatchSize, func(tx *gorm.DB, _ int) error {
+		for _, failure := range batch {
+			writer.Write([]string{strconv.Itoa(failure.Row), failure.Phone, failure.Reason})
+		}
+		writer.Flush()
+		return writer.Error()
+	})
+	writer.Flush()
+}
@@ -0,0 +1,310 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/Ian-Balijawa/sms-gateway/database"
+	"github.com/Ian-Balijawa/sms-gateway/models"
+	"github.com/Ian-Balijawa/sms-gateway/service"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+type ContentRuleHandler struct{}
+
+func NewContentRuleHandler() *ContentRuleHandler {
+	return &ContentRuleHandler{}
+}
+
+type contentRuleRequest struct {
+	Name      string            `json:"name"`
+	Type      string            `json:"type"`
+	Values    models.StringList `json:"values"`
+	MaxLength int               `json:"max_length"`
+}
+
+// CheckMessage checks a message against the content rules that apply to the authenticated
+// client without sending it
+func (h *ContentRuleHandler) CheckMessage(c *gin.Context) {
+	var req struct {
+		Message   string `json:"message" binding:"required"`
+		Marketing bool   `json:"marketing"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.SMSResponse{
+			Success: false,
+			Message: "Invalid request payload",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	client, _ := c.Get("client")
+	apiClient := client.(models.APIClient)
+	policy, err := service.LoadContentPolicy(&apiClient)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.SMSResponse{
+			Success: false,
+			Message: "Failed to load content policy",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	violations := policy.Check(req.Message, req.Marketing)
+	if violations == nil {
+		violations = []service.PolicyViolation{}
+	}
+	c.JSON(http.StatusOK, models.SMSResponse{
+		Success: true,
+		Message: "Message checked successfully",
+		Data: map[string]interface{}{
+			"allowed":    len(violations) == 0,
+			"mode":       policy.Mode,
+			"violations": violations,
+		},
+	})
+}
+
+// ListContentRules lists content rules (admin only).
+// Filter with ?client_id= or ?scope=global.
+func (h *ContentRuleHandler) ListContentRules(c *gin.Context) {
+	query := database.DB.Order("created_at")
+	if c.Query("scope") == "global" {
+		query = query.Where("client_id IS NULL")
+	} else if clientID := c.Query("client_id"); clientID != "" {
+		query = query.Where("client_id = ?", clientID)
+	}
+
+	var rules []models.ContentRule
+	if err := query.Find(&rules).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, models.SMSResponse{
+			Success: false,
+			Message: "Failed to retrieve content rules",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.SMSResponse{
+		Success: true,
+		Message: "Content rules retrieved successfully",
+		Data:    rules,
+	})
+}
+
+// CreateContentRule adds a content rule for one client, or for every client when no
+// client_id is given (admin only)
+func (h *ContentRuleHandler) CreateContentRule(c *gin.Context) {
+	var req struct {
+		contentRuleRequest
+		ClientID *uuid.UUID `json:"client_id"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.SMSResponse{
+			Success: false,
+			Message: "Invalid request payload",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	if req.ClientID != nil {
+		var client models.APIClient
+		if err := database.DB.Where("id = ?", req.ClientID).First(&client).Error; err != nil {
+			c.JSON(http.StatusNotFound, models.SMSResponse{
+				Success: false,
+				Message: "Client not found",
+			})
+			return
+		}
+	}
+
+	rule := models.ContentRule{ClientID: req.ClientID}
+	if !applyContentRule(c, &rule, req.contentRuleRequest) {
+		return
+	}
+
+	if err := database.DB.Create(&rule).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, models.SMSResponse{
+			Success: false,
+			Message: "Failed to create content rule",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, models.SMSResponse{
+		Success: true,
+		Message: "Content rule created successfully",
+		Data:    rule,
+	})
+}
+
+// UpdateContentRule changes a content rule's name, type or settings (admin only)
+func (h *ContentRuleHandler) UpdateContentRule(c *gin.Context) {
+	var rule models.ContentRule
+	if err := database.DB.Where("id = ?", c.Param("id")).First(&rule).Error; err != nil {
+		c.JSON(http.StatusNotFound, models.SMSResponse{
+			Success: false,
+			Message: "Content rule not found",
+		})
+		return
+	}
+
+	req := contentRuleRequest{
+		Name:      rule.Name,
+		Type:      rule.Type,
+		Values:    rule.Values,
+		MaxLength: rule.MaxLength,
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.SMSResponse{
+			Success: false,
+			Message: "Invalid request payload",
+			Error:   err.Error(),
+		})
+		return
+	}
+	if !applyContentRule(c, &rule, req) {
+		return
+	}
+
+	if err := database.DB.Save(&rule).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, models.SMSResponse{
+			Success: false,
+			Message: "Failed to update content rule",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.SMSResponse{
+		Success: true,
+		Message: "Content rule updated successfully",
+		Data:    rule,
+	})
+}
+
+// DeleteContentRule removes a content rule (admin only)
+func (h *ContentRuleHandler) DeleteContentRule(c *gin.Context) {
+	result := database.DB.Where("id = ?", c.Param("id")).Delete(&models.ContentRule{})
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, models.SMSResponse{
+			Success: false,
+			Message: "Failed to delete content rule",
+			Error:   result.Error.Error(),
+		})
+		return
+	}
+	if result.RowsAffected == 0 {
+		c.JSON(http.StatusNotFound, models.SMSResponse{
+			Success: false,
+			Message: "Content rule not found",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.SMSResponse{
+		Success: true,
+		Message: "Content rule deleted successfully",
+	})
+}
+
+// ListPolicyMessages lists messages that broke a content rule, newest first (admin only).
+// Filter with ?client_id= and ?status= (rejected for refused messages, sent for flagged ones).
+func (h *ContentRuleHandler) ListPolicyMessages(c *gin.Context) {
+	limit, offset := paginationParams(c)
+
+	query := database.DB.
+		Where("policy_violations IS NOT NULL AND policy_violations NOT IN ?", []string{"", "[]", "null"}).
+		Order("created_at DESC")
+	if clientID := c.Query("client_id"); clientID != "" {
+		query = query.Where("client_id = ?", clientID)
+	}
+	if status := c.Query("status"); status != "" {
+		query = query.Where("status = ?", status)
+	}
+
+	var logs []models.SMSLog
+	if err := query.Limit(limit).Offset(offset).Find(&logs).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, models.SMSResponse{
+			Success: false,
+			Message: "Failed to retrieve messages",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.SMSResponse{
+		Success: true,
+		Message: "Messages retrieved successfully",
+		Data:    logs,
+	})
+}
+
+// applyContentRule validates the request and copies it onto the rule.
+// It writes the error response and returns false if the request is invalid.
+func applyContentRule(c *gin.Context, rule *models.ContentRule, req contentRuleRequest) bool {
+	rule.Name = req.Name
+	rule.Type = req.Type
+	rule.Values = req.Values
+	rule.MaxLength = req.MaxLength
+	if err := service.NormalizeContentRule(rule); err != nil {
+		c.JSON(http.StatusBadRequest, models.SMSResponse{
+			Success: false,
+			Message: "Invalid content rule",
+			Error:   err.Error(),
+		})
+		return false
+	}
+	return true
+}
+
+// checkContentPolicy refuses the request with 422 if the client's content policy rejects
+// any of the messages, listing the rules each message broke. It returns true if the
+// messages can be sent.
+func checkContentPolicy(c *gin.Context, client *models.APIClient, messages []models.SMSRequest) bool {
+	policy, err := service.LoadContentPolicy(client)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.SMSResponse{
+			Success: false,
+			Message: "Failed to check content policy",
+			Error:   err.Error(),
+		})
+		return false
+	}
+	if policy.Mode != models.ContentPolicyReject {
+		return true
+	}
+
+	var rejected []map[string]interface{}
+	firstError := ""
+	for i, msg := range messages {
+		if violations := policy.Check(msg.Message, msg.Marketing); len(violations) > 0 {
+			if firstError == "" {
+				firstError = fmt.Sprintf("message %d %s", i, violations[0].Detail)
+			}
+			rejected = append(rejected, map[string]interface{}{
+				"index":      i,
+				"number":     msg.Number,
+				"violations": violations,
+			})
+		}
+	}
+	if len(rejected) == 0 {
+		return true
+	}
+
+	c.JSON(http.StatusUnprocessableEntity, models.SMSResponse{
+		Success: false,
+		Message: "Message content breaks the content policy",
+		Error:   firstError,
+		Data: map[string]interface{}{
+			"messages": rejected,
+		},
+	})
+	return false
+}
@@ -0,0 +1,111 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/Ian-Balijawa/sms-gateway/models"
+	"github.com/Ian-Balijawa/sms-gateway/service"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+type ConversationHandler struct{}
+
+func NewConversationHandler() *ConversationHandler {
+	return &ConversationHandler{}
+}
+
+// conversationPhone reads the counterpart number from the path, in the client's region
+func conversationPhone(c *gin.Context) (string, bool) {
+	phone, err := normalizeContactPhone(c.Param("phone"), clientRegion(c))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.SMSResponse{
+			Success: false,
+			Message: "Invalid phone number",
+			Error:   err.Error(),
+		})
+		return "", false
+	}
+	return phone, true
+}
+
+// ListConversations lists the authenticated client's conversations, most recently active first.
+// Use ?unread=true to only list conversations with unread inbound messages.
+func (h *ConversationHandler) ListConversations(c *gin.Context) {
+	clientID, _ := c.Get("client_id")
+	limit, offset := paginationParams(c)
+
+	conversations, err := service.ListConversations(clientID.(uuid.UUID), c.Query("unread") == "true", limit, offset)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.SMSResponse{
+			Success: false,
+			Message: "Failed to retrieve conversations",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.SMSResponse{
+		Success: true,
+		Message: "Conversations retrieved successfully",
+		Data:    conversations,
+	})
+}
+
+// GetConversation returns the messages exchanged with a number, newest first
+func (h *ConversationHandler) GetConversation(c *gin.Context) {
+	clientID, _ := c.Get("client_id")
+	phone, ok := conversationPhone(c)
+	if !ok {
+		return
+	}
+	limit, offset := paginationParams(c)
+
+	messages, err := service.ConversationMessages(clientID.(uuid.UUID), phone, limit, offset)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.SMSResponse{
+			Success: false,
+			Message: "Failed to retrieve conversation",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.SMSResponse{
+		Success: true,
+		Message: "Conversation retrieved successfully",
+		Data: map[string]interface{}{
+			"phone":    phone,
+			"messages": messages,
+		},
+	})
+}
+
+// MarkConversationRead marks the inbound messages from a number as read
+func (h *ConversationHandler) MarkConversationRead(c *gin.Context) {
+	clientID, _ := c.Get("client_id")
+	phone, ok := conversationPhone(c)
+	if !ok {
+		return
+	}
+
+	marked, err := service.MarkConversationRead(clientID.(uuid.UUID), phone)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.SMSResponse{
+			Success: false,
+			Message: "Failed to mark conversation read",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.SMSResponse{
+		Success: true,
+		Message: "Conversation marked read",
+		Data: map[string]interface{}{
+			"phone":  phone,
+			"marked": marked,
+		},
+	})
+}
@@ -0,0 +1,274 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/Ian-Balijawa/sms-gateway/database"
+	"github.com/Ian-Balijawa/sms-gateway/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+type FraudHandler struct{}
+
+func NewFraudHandler() *FraudHandler {
+	return &FraudHandler{}
+}
+
+// ListFlaggedMessages lists messages flagged by the fraud guard, newest first (admin only).
+// Filter with ?client_id= and ?status= (e.g. blocked, deferred, sent).
+func (h *FraudHandler) ListFlaggedMessages(c *gin.Context) {
+	limit, offset := paginationParams(c)
+
+	query := database.DB.Where("fraud_flagged = ?", true).Order("created_at DESC")
+	if clientID := c.Query("client_id"); clientID != "" {
+		query = query.Where("client_id = ?", clientID)
+	}
+	if status := c.Query("status"); status != "" {
+		query = query.Where("status = ?", status)
+	}
+
+	var logs []models.SMSLog
+	if err := query.Limit(limit).Offset(offset).Find(&logs).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, models.SMSResponse{
+			Success: false,
+			Message: "Failed to retrieve flagged messages",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.SMSResponse{
+		Success: true,
+		Message: "Flagged messages retrieved successfully",
+		Data:    logs,
+	})
+}
+
+// ReleaseFlaggedMessage sends a blocked or held message on the next deferred run (admin only).
+// With {"whitelist": true} the recipient is also whitelisted for the message's client.
+func (h *FraudHandler) ReleaseFlaggedMessage(c *gin.Context) {
+	var req struct {
+		Whitelist bool `json:"whitelist"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+		c.JSON(http.StatusBadRequest, models.SMSResponse{
+			Success: false,
+			Message: "Invalid request payload",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	now := time.Now().UTC()
+	smsLog, ok := reviewFlaggedMessage(c, []string{models.SMSStatusBlocked, models.SMSStatusDeferred}, map[string]interface{}{
+		"status":       models.SMSStatusDeferred,
+		"scheduled_at": now,
+		"error":        "",
+	})
+	if !ok {
+		return
+	}
+
+	if req.Whitelist {
+		entry := models.FraudWhitelist{
+			ClientID: &smsLog.ClientID,
+			Prefix:   smsLog.Recipient,
+			Reason:   "released from fraud review",
+		}
+		if err := database.DB.Create(&entry).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, models.SMSResponse{
+				Success: false,
+				Message: "Message released, but the recipient could not be whitelisted",
+				Error:   err.Error(),
+				Data:    smsLog,
+			})
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, models.SMSResponse{
+		Success: true,
+		Message: "Message released for sending",
+		Data:    smsLog,
+	})
+}
+
+// BlockFlaggedMessage blocks a message held for fraud review so that it is never sent (admin only)
+func (h *FraudHandler) BlockFlaggedMessage(c *gin.Context) {
+	smsLog, ok := reviewFlaggedMessage(c, []string{models.SMSStatusDeferred}, map[string]interface{}{
+		"status": models.SMSStatusBlocked,
+		"error":  "blocked by fraud review",
+	})
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, models.SMSResponse{
+		Success: true,
+		Message: "Message blocked",
+		Data:    smsLog,
+	})
+}
+
+// reviewFlaggedMessage applies a review decision to the flagged message in the path if it is
+// still in one of the given statuses. It writes the error response and returns false on failure.
+func reviewFlaggedMessage(c *gin.Context, from []string, updates map[string]interface{}) (*models.SMSLog, bool) {
+	var smsLog models.SMSLog
+	if err := database.DB.Where("id = ? AND fraud_flagged = ?", c.Param("id"), true).First(&smsLog).Error; err != nil {
+		c.JSON(http.StatusNotFound, models.SMSResponse{
+			Success: false,
+			Message: "Flagged message not found",
+		})
+		return nil, false
+	}
+
+	// Only update the message if it has not been sent or reviewed in the meantime
+	result := database.DB.Model(&models.SMSLog{}).
+		Where("id = ? AND status IN ?", smsLog.ID, from).
+		Updates(updates)
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, models.SMSResponse{
+			Success: false,
+			Message: "Failed to update message",
+			Error:   result.Error.Error(),
+		})
+		return nil, false
+	}
+	if result.RowsAffected == 0 {
+		c.JSON(http.StatusConflict, models.SMSResponse{
+			Success: false,
+			Message: fmt.Sprintf("Message is %s and can no longer be reviewed", smsLog.Status),
+		})
+		return nil, false
+	}
+
+	database.DB.Where("id = ?", smsLog.ID).First(&smsLog)
+	return &smsLog, true
+}
+
+// ListWhitelist lists fraud whitelist entries (admin only).
+// Filter with ?client_id= or ?scope=global.
+func (h *FraudHandler) ListWhitelist(c *gin.Context) {
+	query := database.DB.Order("created_at DESC")
+	if c.Query("scope") == "global" {
+		query = query.Where("client_id IS NULL")
+	} else if clientID := c.Query("client_id"); clientID != "" {
+		query = query.Where("client_id = ?", clientID)
+	}
+
+	var entries []models.FraudWhitelist
+	if err := query.Find(&entries).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, models.SMSResponse{
+			Success: false,
+			Message: "Failed to retrieve whitelist",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.SMSResponse{
+		Success: true,
+		Message: "Whitelist retrieved successfully",
+		Data:    entries,
+	})
+}
+
+// AddWhitelistEntry exempts a number or number prefix from the fraud guard for one client,
+// or for every client when no client_id is given (admin only)
+func (h *FraudHandler) AddWhitelistEntry(c *gin.Context) {
+	var req struct {
+		ClientID *uuid.UUID `json:"client_id"`
+		Prefix   string     `json:"prefix" binding:"required"`
+		Reason   string     `json:"reason"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.SMSResponse{
+			Success: false,
+			Message: "Invalid request payload",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	prefix, err := normalizeWhitelistPrefix(req.Prefix)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.SMSResponse{
+			Success: false,
+			Message: "Invalid prefix",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	if req.ClientID != nil {
+		var client models.APIClient
+		if err := database.DB.Where("id = ?", req.ClientID).First(&client).Error; err != nil {
+			c.JSON(http.StatusNotFound, models.SMSResponse{
+				Success: false,
+				Message: "Client not found",
+			})
+			return
+		}
+	}
+
+	entry := models.FraudWhitelist{
+		ClientID: req.ClientID,
+		Prefix:   prefix,
+		Reason:   req.Reason,
+	}
+	if err := database.DB.Create(&entry).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, models.SMSResponse{
+			Success: false,
+			Message: "Failed to add whitelist entry",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, models.SMSResponse{
+		Success: true,
+		Message: "Whitelist entry added successfully",
+		Data:    entry,
+	})
+}
+
+// RemoveWhitelistEntry removes a fraud whitelist entry by ID (admin only)
+func (h *FraudHandler) RemoveWhitelistEntry(c *gin.Context) {
+	result := database.DB.Where("id = ?", c.Param("id")).Delete(&models.FraudWhitelist{})
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, models.SMSResponse{
+			Success: false,
+			Message: "Failed to remove whitelist entry",
+			Error:   result.Error.Error(),
+		})
+		return
+	}
+	if result.RowsAffected == 0 {
+		c.JSON(http.StatusNotFound, models.SMSResponse{
+			Success: false,
+			Message: "Whitelist entry not found",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.SMSResponse{
+		Success: true,
+		Message: "Whitelist entry removed successfully",
+	})
+}
+
+// normalizeWhitelistPrefix returns an E.164 prefix ("+" followed by digits) for a whitelist entry
+func normalizeWhitelistPrefix(prefix string) (string, error) {
+	digits := strings.TrimPrefix(strings.TrimSpace(prefix), "+")
+	if len(digits) < 1 || strings.Trim(digits, "0123456789") != "" {
+		return "", fmt.Errorf("prefix must be an international number or prefix, e.g. +25677")
+	}
+	return "+" + digits, nil
+}
@@ -0,0 +1,611 @@
+package handlers
+
+import (
+	"encoding/csv"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/Ian-Balijawa/sms-gateway/database"
+	"github.com/Ian-Balijawa/sms-gateway/models"
+	"github.com/Ian-Balijawa/sms-gateway/service"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type GroupHandler struct {
+	dispatcher *service.Dispatcher
+}
+
+func NewGroupHandler(dispatcher *service.Dispatcher) *GroupHandler {
+	return &GroupHandler{
+		dispatcher: dispatcher,
+	}
+}
+
+// groupMembersRequest identifies contacts by ID or by phone number
+type groupMembersRequest struct {
+	ContactIDs []uuid.UUID `json:"contact_ids"`
+	Phones     []string    `json:"phones"`
+}
+
+// findClientGroup loads a group owned by the authenticated client
+func findClientGroup(c *gin.Context) (*models.Group, bool) {
+	clientID, _ := c.Get("client_id")
+
+	var group models.Group
+	if err := database.DB.Where("id = ? AND client_id = ?", c.Param("id"), clientID).First(&group).Error; err != nil {
+		c.JSON(http.StatusNotFound, models.SMSResponse{
+			Success: false,
+			Message: "Group not found",
+		})
+		return nil, false
+	}
+	return &group, true
+}
+
+// fillContactCounts sets ContactCount on each group
+func fillContactCounts(groups []models.Group) error {
+	if len(groups) == 0 {
+		return nil
+	}
+
+	ids := make([]uuid.UUID, len(groups))
+	for i := range groups {
+		ids[i] = groups[i].ID
+	}
+
+	var counts []struct {
+		GroupID uuid.UUID
+		Count   int64
+	}
+	if err := database.DB.Model(&models.GroupMember{}).
+		Select("group_id, COUNT(*) AS count").
+		Where("group_id IN ?", ids).
+		Group("group_id").
+		Scan(&counts).Error; err != nil {
+		return err
+	}
+
+	byGroup := make(map[uuid.UUID]int64, len(counts))
+	for _, count := range counts {
+		byGroup[count.GroupID] = count.Count
+	}
+	for i := range groups {
+		groups[i].ContactCount = byGroup[groups[i].ID]
+	}
+	return nil
+}
+
+// resolveGroupMembers finds the client's contacts referenced by the request. Phone numbers
+// are read in the client's region; when create is set, numbers without a contact get a new one.
+// Invalid phone numbers are returned separately.
+func resolveGroupMembers(clientID uuid.UUID, region string, req groupMembersRequest, create bool) ([]uuid.UUID, []string, error) {
+	contactIDs := make([]uuid.UUID, 0, len(req.ContactIDs)+len(req.Phones))
+	invalid := make([]string, 0)
+
+	if len(req.ContactIDs) > 0 {
+		var owned []uuid.UUID
+		if err := database.DB.Model(&models.Contact{}).
+			Where("client_id = ? AND id IN ?", clientID, req.ContactIDs).
+			Pluck("id", &owned).Error; err != nil {
+			return nil, nil, err
+		}
+		contactIDs = append(contactIDs, owned...)
+	}
+
+	for _, raw := range req.Phones {
+		phone, err := normalizeContactPhone(raw, region)
+		if err != nil {
+			invalid = append(invalid, raw)
+			continue
+		}
+
+		var contact models.Contact
+		if err := database.DB.Where("client_id = ? AND phone = ?", clientID, phone).First(&contact).Error; err != nil {
+			if !create {
+				continue
+			}
+			contact = models.Contact{ClientID: clientID, Phone: phone}
+			if err := database.DB.Create(&contact).Error; err != nil {
+				return nil, nil, err
+			}
+		}
+		contactIDs = append(contactIDs, contact.ID)
+	}
+
+	return contactIDs, invalid, nil
+}
+
+// CreateGroup creates a contact group for the authenticated client
+func (h *GroupHandler) CreateGroup(c *gin.Context) {
+	var req struct {
+		Name        string `json:"name" binding:"required"`
+		Description string `json:"description"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.SMSResponse{
+			Success: false,
+			Message: "Invalid request payload",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	clientID, _ := c.Get("client_id")
+
+	group := models.Group{
+		ClientID:    clientID.(uuid.UUID),
+		Name:        req.Name,
+		Description: req.Description,
+	}
+
+	if err := database.DB.Create(&group).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, models.SMSResponse{
+			Success: false,
+			Message: "Failed to create group",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, models.SMSResponse{
+		Success: true,
+		Message: "Group created successfully",
+		Data:    group,
+	})
+}
+
+// ListGroups lists the authenticated client's groups with their member counts
+func (h *GroupHandler) ListGroups(c *gin.Context) {
+	clientID, _ := c.Get("client_id")
+
+	var groups []models.Group
+	if err := database.DB.Where("client_id = ?", clientID).Order("created_at DESC").Find(&groups).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, models.SMSResponse{
+			Success: false,
+			Message: "Failed to retrieve groups",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	if err := fillContactCounts(groups); err != nil {
+		c.JSON(http.StatusInternalServerError, models.SMSResponse{
+			Success: false,
+			Message: "Failed to retrieve groups",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.SMSResponse{
+		Success: true,
+		Message: "Groups retrieved successfully",
+		Data:    groups,
+	})
+}
+
+// GetGroup returns a single group
+func (h *GroupHandler) GetGroup(c *gin.Context) {
+	group, ok := findClientGroup(c)
+	if !ok {
+		return
+	}
+
+	groups := []models.Group{*group}
+	if err := fillContactCounts(groups); err != nil {
+		c.JSON(http.StatusInternalServerError, models.SMSResponse{
+			Success: false,
+			Message: "Failed to retrieve group",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.SMSResponse{
+		Success: true,
+		Message: "Group retrieved successfully",
+		Data:    groups[0],
+	})
+}
+
+// UpdateGroup updates a group's name or description
+func (h *GroupHandler) UpdateGroup(c *gin.Context) {
+	var req struct {
+		Name        *string `json:"name"`
+		Description *string `json:"description"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.SMSResponse{
+			Success: false,
+			Message: "Invalid request payload",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	group, ok := findClientGroup(c)
+	if !ok {
+		return
+	}
+
+	if req.Name != nil {
+		group.Name = *req.Name
+	}
+	if req.Description != nil {
+		group.Description = *req.Description
+	}
+
+	if err := database.DB.Save(group).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, models.SMSResponse{
+			Success: false,
+			Message: "Failed to update group",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.SMSResponse{
+		Success: true,
+		Message: "Group updated successfully",
+		Data:    group,
+	})
+}
+
+// DeleteGroup deletes a group. Its contacts are kept.
+func (h *GroupHandler) DeleteGroup(c *gin.Context) {
+	group, ok := findClientGroup(c)
+	if !ok {
+		return
+	}
+
+	if err := database.DB.Where("group_id = ?", group.ID).Delete(&models.GroupMember{}).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, models.SMSResponse{
+			Success: false,
+			Message: "Failed to delete group",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	if err := database.DB.Delete(group).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, models.SMSResponse{
+			Success: false,
+			Message: "Failed to delete group",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.SMSResponse{
+		Success: true,
+		Message: "Group deleted successfully",
+	})
+}
+
+// ListGroupContacts lists the contacts in a group
+func (h *GroupHandler) ListGroupContacts(c *gin.Context) {
+	group, ok := findClientGroup(c)
+	if !ok {
+		return
+	}
+
+	limit, offset := paginationParams(c)
+
+	var contacts []models.Contact
+	if err := database.DB.
+		Joins("JOIN group_members ON group_members.contact_id = contacts.id").
+		Where("group_members.group_id = ?", group.ID).
+		Order("group_members.created_at").
+		Limit(limit).Offset(offset).
+		Find(&contacts).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, models.SMSResponse{
+			Success: false,
+			Message: "Failed to retrieve group contacts",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.SMSResponse{
+		Success: true,
+		Message: "Group contacts retrieved successfully",
+		Data:    contacts,
+	})
+}
+
+// AddGroupContacts adds contacts to a group by ID or phone number.
+// Phone numbers without an existing contact create one.
+func (h *GroupHandler) AddGroupContacts(c *gin.Context) {
+	var req groupMembersRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.SMSResponse{
+			Success: false,
+			Message: "Invalid request payload",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	group, ok := findClientGroup(c)
+	if !ok {
+		return
+	}
+
+	contactIDs, invalid, err := resolveGroupMembers(group.ClientID, clientRegion(c), req, true)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.SMSResponse{
+			Success: false,
+			Message: "Failed to add contacts",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	added := int64(0)
+	if len(contactIDs) > 0 {
+		members := make([]models.GroupMember, 0, len(contactIDs))
+		for _, contactID := range contactIDs {
+			members = append(members, models.GroupMember{GroupID: group.ID, ContactID: contactID})
+		}
+
+		// Contacts already in the group are skipped
+		result := database.DB.Clauses(clause.OnConflict{DoNothing: true}).Create(&members)
+		if result.Error != nil {
+			c.JSON(http.StatusInternalServerError, models.SMSResponse{
+				Success: false,
+				Message: "Failed to add contacts",
+				Error:   result.Error.Error(),
+			})
+			return
+		}
+		added = result.RowsAffected
+	}
+
+	c.JSON(http.StatusOK, models.SMSResponse{
+		Success: true,
+		Message: "Contacts added to group",
+		Data: map[string]interface{}{
+			"added":          added,
+			"invalid_phones": invalid,
+		},
+	})
+}
+
+// RemoveGroupContacts removes contacts from a group by ID or phone number.
+// The contacts themselves are kept.
+func (h *GroupHandler) RemoveGroupContacts(c *gin.Context) {
+	var req groupMembersRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.SMSResponse{
+			Success: false,
+			Message: "Invalid request payload",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	group, ok := findClientGroup(c)
+	if !ok {
+		return
+	}
+
+	contactIDs, invalid, err := resolveGroupMembers(group.ClientID, clientRegion(c), req, false)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.SMSResponse{
+			Success: false,
+			Message: "Failed to remove contacts",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	removed := int64(0)
+	if len(contactIDs) > 0 {
+		result := database.DB.Where("group_id = ? AND contact_id IN ?", group.ID, contactIDs).Delete(&models.GroupMember{})
+		if result.Error != nil {
+			c.JSON(http.StatusInternalServerError, models.SMSResponse{
+				Success: false,
+				Message: "Failed to remove contacts",
+				Error:   result.Error.Error(),
+			})
+			return
+		}
+		removed = result.RowsAffected
+	}
+
+	c.JSON(http.StatusOK, models.SMSResponse{
+		Success: true,
+		Message: "Contacts removed from group",
+		Data: map[string]interface{}{
+			"removed":        removed,
+			"invalid_phones": invalid,
+		},
+	})
+}
+
+// SendToGroup sends a message template to every contact in a group.
+// The template can reference {{name}}, {{phone}} and any contact attribute.
+func (h *GroupHandler) SendToGroup(c *gin.Context) {
+	var req struct {
+		Message   string `json:"message" binding:"required"`
+		SenderID  string `json:"senderid"`
+		Priority  string `json:"priority"`
+		Marketing bool   `json:"marketing"`
+
+		ShortenLinks bool `json:"shorten_links"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.SMSResponse{
+			Success: false,
+			Message: "Invalid request payload",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	group, ok := findClientGroup(c)
+	if !ok {
+		return
+	}
+
+	client, exists := c.Get("client")
+	if !exists {
+		c.JSON(http.StatusInternalServerError, models.SMSResponse{
+			Success: false,
+			Message: "Client not found in context",
+		})
+		return
+	}
+	apiClient := client.(models.APIClient)
+
+	messages, err := service.GroupMessages(apiClient.ID, group.ID, req.Message, req.SenderID, req.Priority)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.SMSResponse{
+			Success: false,
+			Message: "Failed to load group contacts",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	if len(messages) == 0 {
+		c.JSON(http.StatusBadRequest, models.SMSResponse{
+			Success: false,
+			Message: "Group has no contacts",
+		})
+		return
+	}
+
+	// Check if the group send exceeds limits
+	if apiClient.DailyUsage+len(messages) > apiClient.DailyLimit {
+		c.JSON(http.StatusTooManyRequests, models.SMSResponse{
+			Success: false,
+			Message: "Group send would exceed daily limit",
+			Error:   "Requested messages exceed available daily quota",
+		})
+		return
+	}
+
+	for i := range messages {
+		messages[i].Marketing = req.Marketing
+		messages[i].ShortenLinks = req.ShortenLinks
+	}
+	if !checkSenderIDs(c, apiClient.ID, messages) || !checkContentPolicy(c, &apiClient, messages) {
+		return
+	}
+	if !checkShortLinks(c, messages) {
+		return
+	}
+	if !checkWalletBalance(c, &apiClient, messages) {
+		return
+	}
+
+	result, err := h.dispatcher.Dispatch(service.DispatchRequest{
+		Client:    &apiClient,
+		Messages:  messages,
+		Bulk:      true,
+		IPAddress: c.ClientIP(),
+		UserAgent: c.GetHeader("User-Agent"),
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.SMSResponse{
+			Success: false,
+			Message: "Failed to send group SMS",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.SMSResponse{
+		Success: true,
+		Message: "Group SMS processing completed",
+		Data: map[string]interface{}{
+			"group_id":   group.ID,
+			"total":      len(messages),
+			"successful": result.Successful,
+			"failed":     result.Failed,
+			"suppressed": result.Suppressed,
+			"deferred":   result.Deferred,
+			"unknown":    result.Unknown,
+			"blocked":    result.Blocked,
+			"results":    logResults(result.Logs),
+		},
+	})
+}
+
+// ExportGroupContacts streams a group's contacts as CSV. Columns are phone, name and one
+// column per attribute; the attribute columns can be chosen with ?attributes=a,b.
+func (h *GroupHandler) ExportGroupContacts(c *gin.Context) {
+	group, ok := findClientGroup(c)
+	if !ok {
+		return
+	}
+
+	members := func() *gorm.DB {
+		return database.DB.Model(&models.Contact{}).
+			Joins("JOIN group_members ON group_members.contact_id = contacts.id").
+			Where("group_members.group_id = ?", group.ID)
+	}
+
+	var attributes []string
+	if requested := c.Query("attributes"); requested != "" {
+		for _, column := range strings.Split(requested, ",") {
+			if column = strings.TrimSpace(column); column != "" {
+				attributes = append(attributes, column)
+			}
+		}
+	} else {
+		// Collect every attribute name used in the group
+		keys := make(map[string]bool)
+		var batch []models.Contact
+		if err := members().Select("contacts.id", "contacts.attributes").FindInBatches(&batch, csvBatchSize, func(tx *gorm.DB, _ int) error {
+			for _, contact := range batch {
+				for key := range contact.Attributes {
+					keys[key] = true
+				}
+			}
+			return nil
+		}).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, models.SMSResponse{
+				Success: false,
+				Message: "Failed to export group contacts",
+				Error:   err.Error(),
+			})
+			return
+		}
+		for key := range keys {
+			attributes = append(attributes, key)
+		}
+		sort.Strings(attributes)
+	}
+
+	c.Header("Content-Type", "text/csv")
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=\"group-%s.csv\"", group.ID))
+
+	writer := csv.NewWriter(c.Writer)
+	writer.Write(append([]string{"phone", "name"}, attributes...))
+
+	var batch []models.Contact
+	members().Select("contacts.*").FindInBatches(&batch, csvBatchSize, func(tx *gorm.DB, _ int) error {
+		for _, contact := range batch {
+			record := make([]string, 0, len(attributes)+2)
+			record = append(record, contact.Phone, contact.Name)
+			for _, key := range attributes {
+				record = append(record, contact.Attributes[key])
+			}
+			writer.Write(record)
+		}
+		writer.Flush()
+		return writer.Error()
+	})
+	writer.Flush()
+}
@@ -0,0 +1,393 @@
+package handlers
+
+import (
+	"crypto/subtle"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/Ian-Balijawa/sms-gateway/config"
+	"github.com/Ian-Balijawa/sms-gateway/database"
+	"github.com/Ian-Balijawa/sms-gateway/models"
+	"github.com/Ian-Balijawa/sms-gateway/service"
+	"github.com/Ian-Balijawa/sms-gateway/utils"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// inboundProviders are the providers allowed to push inbound messages
+var inboundProviders = map[string]bool{
+	"egosms": true,
+	"fake":   true,
+}
+
+// inboundTimeLayouts are the received_at formats accepted from providers, tried in order
+var inboundTimeLayouts = []string{time.RFC3339, "2006-01-02 15:04:05"}
+
+type InboundHandler struct {
+	dispatcher *service.Dispatcher
+}
+
+func NewInboundHandler(dispatcher *service.Dispatcher) *InboundHandler {
+	return &InboundHandler{
+		dispatcher: dispatcher,
+	}
+}
+
+// inboundPayload is an inbound message pushed by a provider, as JSON or form fields.
+// Providers name the fields differently, so common alternatives are accepted.
+type inboundPayload struct {
+	From       string `json:"from" form:"from"`
+	Sender     string `json:"sender" form:"sender"`
+	MSISDN     string `json:"msisdn" form:"msisdn"`
+	To         string `json:"to" form:"to"`
+	Shortcode  string `json:"shortcode" form:"shortcode"`
+	Text       string `json:"text" form:"text"`
+	Message    string `json:"message" form:"message"`
+	ID         string `json:"id" form:"id"`
+	MessageID  string `json:"message_id" form:"message_id"`
+	ReceivedAt string `json:"received_at" form:"received_at"`
+}
+
+// message converts the payload to an inbound message from the given provider
+func (p *inboundPayload) message(provider string) (*models.InboundMessage, error) {
+	msg := &models.InboundMessage{
+		From:              firstNonEmpty(p.From, p.Sender, p.MSISDN),
+		To:                firstNonEmpty(p.To, p.Shortcode),
+		Text:              firstNonEmpty(p.Text, p.Message),
+		ProviderMessageID: firstNonEmpty(p.ID, p.MessageID),
+		Provider:          provider,
+	}
+	if msg.From == "" {
+		return nil, fmt.Errorf("from is required")
+	}
+	if msg.To == "" {
+		return nil, fmt.Errorf("to is required")
+	}
+
+	if p.ReceivedAt != "" {
+		for _, layout := range inboundTimeLayouts {
+			if receivedAt, err := time.Parse(layout, p.ReceivedAt); err == nil {
+				msg.ReceivedAt = receivedAt
+				break
+			}
+		}
+		if msg.ReceivedAt.IsZero() {
+			return nil, fmt.Errorf("invalid received_at %q", p.ReceivedAt)
+		}
+	}
+	return msg, nil
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, value := range values {
+		if value = strings.TrimSpace(value); value != "" {
+			return value
+		}
+	}
+	return ""
+}
+
+// receiveInbound binds a provider payload, records the message and writes the response
+func (h *InboundHandler) receiveInbound(c *gin.Context, provider string) {
+	var payload inboundPayload
+	if err := c.ShouldBind(&payload); err != nil {
+		c.JSON(http.StatusBadRequest, models.SMSResponse{
+			Success: false,
+			Message: "Invalid request payload",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	msg, err := payload.message(provider)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.SMSResponse{
+			Success: false,
+			Message: "Invalid inbound message",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	duplicate, err := h.dispatcher.ReceiveInbound(msg)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.SMSResponse{
+			Success: false,
+			Message: "Failed to record inbound message",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	message := "Inbound message received"
+	if duplicate {
+		message = "Inbound message already received"
+	}
+	c.JSON(http.StatusOK, models.SMSResponse{
+		Success: true,
+		Message: message,
+		Data: gin.H{
+			"id":        msg.ID,
+			"status":    msg.Status,
+			"duplicate": duplicate,
+		},
+	})
+}
+
+// ReceiveInbound accepts inbound messages pushed by a provider. The provider must pass
+// INBOUND_WEBHOOK_TOKEN in the X-Webhook-Token header or the token query parameter; while no
+// token is configured every request is refused.
+func (h *InboundHandler) ReceiveInbound(c *gin.Context) {
+	provider := strings.ToLower(c.Param("provider"))
+	if !inboundProviders[provider] {
+		c.JSON(http.StatusNotFound, models.SMSResponse{
+			Success: false,
+			Message: "Unknown provider",
+		})
+		return
+	}
+
+	expected := config.AppConfig.InboundWebhookToken
+	if expected == "" {
+		c.JSON(http.StatusServiceUnavailable, models.SMSResponse{
+			Success: false,
+			Message: "Inbound webhook is not configured",
+		})
+		return
+	}
+
+	token := c.GetHeader("X-Webhook-Token")
+	if token == "" {
+		token = c.Query("token")
+	}
+	if subtle.ConstantTimeCompare([]byte(token), []byte(expected)) != 1 {
+		c.JSON(http.StatusUnauthorized, models.SMSResponse{
+			Success: false,
+			Message: "Invalid webhook token",
+		})
+		return
+	}
+
+	h.receiveInbound(c, provider)
+}
+
+// SimulateInbound records an inbound message as if the fake provider had pushed it (admin only)
+func (h *InboundHandler) SimulateInbound(c *gin.Context) {
+	h.receiveInbound(c, "fake")
+}
+
+// ListInboundMessages lists the authenticated client's inbound messages, newest first
+func (h *InboundHandler) ListInboundMessages(c *gin.Context) {
+	clientID, _ := c.Get("client_id")
+	limit, offset := paginationParams(c)
+
+	query := database.DB.Where("client_id = ?", clientID).Order("received_at DESC")
+	if from := c.Query("from"); from != "" {
+		phone, err := normalizeContactPhone(from, clientRegion(c))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, models.SMSResponse{
+				Success: false,
+				Message: "Invalid from number",
+				Error:   err.Error(),
+			})
+			return
+		}
+		query = query.Where("\"from\" = ?", phone)
+	}
+	if keyword := c.Query("keyword"); keyword != "" {
+		query = query.Where("keyword = ?", service.NormalizeInboundKeyword(keyword))
+	}
+	if status := c.Query("status"); status != "" {
+		query = query.Where("status = ?", status)
+	}
+
+	var messages []models.InboundMessage
+	if err := query.Limit(limit).Offset(offset).Find(&messages).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, models.SMSResponse{
+			Success: false,
+			Message: "Failed to retrieve inbound messages",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.SMSResponse{
+		Success: true,
+		Message: "Inbound messages retrieved successfully",
+		Data:    messages,
+	})
+}
+
+// GetInboundMessage returns a single inbound message
+func (h *InboundHandler) GetInboundMessage(c *gin.Context) {
+	clientID, _ := c.Get("client_id")
+
+	var msg models.InboundMessage
+	if err := database.DB.Preload("RuleHits", func(db *gorm.DB) *gorm.DB {
+		return db.Order("created_at")
+	}).Where("id = ? AND client_id = ?", c.Param("id"), clientID).First(&msg).Error; err != nil {
+		c.JSON(http.StatusNotFound, models.SMSResponse{
+			Success: false,
+			Message: "Inbound message not found",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.SMSResponse{
+		Success: true,
+		Message: "Inbound message retrieved successfully",
+		Data:    msg,
+	})
+}
+
+// AdminListInboundMessages lists inbound messages across clients (admin only).
+// Use status=unrouted to find messages sent to numbers or keywords without a route.
+func (h *InboundHandler) AdminListInboundMessages(c *gin.Context) {
+	limit, offset := paginationParams(c)
+
+	query := database.DB.Order("received_at DESC")
+	if clientID := c.Query("client_id"); clientID != "" {
+		query = query.Where("client_id = ?", clientID)
+	}
+	if to := c.Query("to"); to != "" {
+		query = query.Where("\"to\" = ?", service.NormalizeInboundNumber(to, utils.DefaultRegion()))
+	}
+	if status := c.Query("status"); status != "" {
+		query = query.Where("status = ?", status)
+	}
+
+	var messages []models.InboundMessage
+	if err := query.Limit(limit).Offset(offset).Find(&messages).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, models.SMSResponse{
+			Success: false,
+			Message: "Failed to retrieve inbound messages",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.SMSResponse{
+		Success: true,
+		Message: "Inbound messages retrieved successfully",
+		Data:    messages,
+	})
+}
+
+// ListInboundRoutes lists the numbers and keywords assigned to clients (admin only)
+func (h *InboundHandler) ListInboundRoutes(c *gin.Context) {
+	query := database.DB.Order("number, keyword")
+	if clientID := c.Query("client_id"); clientID != "" {
+		query = query.Where("client_id = ?", clientID)
+	}
+
+	var routes []models.InboundRoute
+	if err := query.Find(&routes).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, models.SMSResponse{
+			Success: false,
+			Message: "Failed to retrieve inbound routes",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.SMSResponse{
+		Success: true,
+		Message: "Inbound routes retrieved successfully",
+		Data:    routes,
+	})
+}
+
+// CreateInboundRoute assigns a shortcode or long number, optionally limited to a keyword,
+// to a client (admin only)
+func (h *InboundHandler) CreateInboundRoute(c *gin.Context) {
+	var req struct {
+		ClientID uuid.UUID `json:"client_id" binding:"required"`
+		Number   string    `json:"number" binding:"required"`
+		Keyword  string    `json:"keyword"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.SMSResponse{
+			Success: false,
+			Message: "Invalid request payload",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	var client models.APIClient
+	if err := database.DB.Where("id = ?", req.ClientID).First(&client).Error; err != nil {
+		c.JSON(http.StatusNotFound, models.SMSResponse{
+			Success: false,
+			Message: "Client not found",
+		})
+		return
+	}
+
+	route := models.InboundRoute{
+		ClientID: client.ID,
+		Number:   service.NormalizeInboundNumber(req.Number, utils.DefaultRegion()),
+		Keyword:  service.NormalizeInboundKeyword(req.Keyword),
+	}
+	if route.Number == "" || strings.ContainsAny(route.Keyword, " \t") {
+		c.JSON(http.StatusBadRequest, models.SMSResponse{
+			Success: false,
+			Message: "Invalid inbound route",
+			Error:   "number must contain digits and keyword must be a single word",
+		})
+		return
+	}
+
+	var existing models.InboundRoute
+	if err := database.DB.Where("number = ? AND keyword = ?", route.Number, route.Keyword).First(&existing).Error; err == nil {
+		c.JSON(http.StatusConflict, models.SMSResponse{
+			Success: false,
+			Message: "Number and keyword are already routed",
+			Data:    existing,
+		})
+		return
+	}
+
+	if err := database.DB.Create(&route).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, models.SMSResponse{
+			Success: false,
+			Message: "Failed to create inbound route",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, models.SMSResponse{
+		Success: true,
+		Message: "Inbound route created successfully",
+		Data:    route,
+	})
+}
+
+// DeleteInboundRoute removes a route (admin only). Messages already received are kept.
+func (h *InboundHandler) DeleteInboundRoute(c *gin.Context) {
+	result := database.DB.Where("id = ?", c.Param("id")).Delete(&models.InboundRoute{})
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, models.SMSResponse{
+			Success: false,
+			Message: "Failed to delete inbound route",
+			Error:   result.Error.Error(),
+		})
+		return
+	}
+	if result.RowsAffected == 0 {
+		c.JSON(http.StatusNotFound, models.SMSResponse{
+			Success: false,
+			Message: "Inbound route not found",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.SMSResponse{
+		Success: true,
+		Message: "Inbound route deleted successfully",
+	})
+}
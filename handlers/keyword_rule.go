@@ -0,0 +1,275 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/Ian-Balijawa/sms-gateway/database"
+	"github.com/Ian-Balijawa/sms-gateway/models"
+	"github.com/Ian-Balijawa/sms-gateway/service"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+type KeywordRuleHandler struct{}
+
+func NewKeywordRuleHandler() *KeywordRuleHandler {
+	return &KeywordRuleHandler{}
+}
+
+// keywordRuleRequest is the payload for creating and updating keyword rules
+type keywordRuleRequest struct {
+	Name      *string              `json:"name"`
+	IsActive  *bool                `json:"is_active"`
+	Position  *int                 `json:"position"`
+	MatchType *string              `json:"match_type"`
+	Pattern   *string              `json:"pattern"`
+	Actions   *[]models.RuleAction `json:"actions"`
+}
+
+// apply copies the fields present in the request onto the rule
+func (req *keywordRuleRequest) apply(rule *models.KeywordRule) {
+	if req.Name != nil {
+		rule.Name = *req.Name
+	}
+	if req.IsActive != nil {
+		rule.IsActive = *req.IsActive
+	}
+	if req.Position != nil {
+		rule.Position = *req.Position
+	}
+	if req.MatchType != nil {
+		rule.MatchType = *req.MatchType
+	}
+	if req.Pattern != nil {
+		rule.Pattern = *req.Pattern
+	}
+	if req.Actions != nil {
+		rule.Actions = *req.Actions
+	}
+}
+
+// validateKeywordRule checks the rule and that the groups and URLs its actions use are valid
+func validateKeywordRule(rule *models.KeywordRule) (string, bool) {
+	if rule.Name == "" {
+		return "name is required", false
+	}
+	if err := service.ValidateKeywordRule(rule); err != nil {
+		return err.Error(), false
+	}
+	for i, action := range rule.Actions {
+		if action.GroupID != nil {
+			var group models.Group
+			if err := database.DB.Where("id = ? AND client_id = ?", action.GroupID, rule.ClientID).First(&group).Error; err != nil {
+				return fmt.Sprintf("action %d: group not found", i+1), false
+			}
+		}
+		if err := validateWebhookURL(action.URL); err != nil {
+			return fmt.Sprintf("action %d: %v", i+1, err), false
+		}
+	}
+	return "", true
+}
+
+// findClientKeywordRule loads a keyword rule owned by the authenticated client
+func findClientKeywordRule(c *gin.Context) (*models.KeywordRule, bool) {
+	clientID, _ := c.Get("client_id")
+
+	var rule models.KeywordRule
+	if err := database.DB.Where("id = ? AND client_id = ?", c.Param("id"), clientID).First(&rule).Error; err != nil {
+		c.JSON(http.StatusNotFound, models.SMSResponse{
+			Success: false,
+			Message: "Keyword rule not found",
+		})
+		return nil, false
+	}
+	return &rule, true
+}
+
+// CreateKeywordRule creates a keyword rule for the authenticated client. Rules are active unless is_active is false.
+func (h *KeywordRuleHandler) CreateKeywordRule(c *gin.Context) {
+	var req keywordRuleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.SMSResponse{
+			Success: false,
+			Message: "Invalid request payload",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	clientID, exists := c.Get("client_id")
+	if !exists {
+		c.JSON(http.StatusInternalServerError, models.SMSResponse{
+			Success: false,
+			Message: "Client ID not found",
+		})
+		return
+	}
+
+	rule := models.KeywordRule{
+		ClientID: clientID.(uuid.UUID),
+		IsActive: true,
+	}
+	req.apply(&rule)
+
+	if msg, ok := validateKeywordRule(&rule); !ok {
+		c.JSON(http.StatusBadRequest, models.SMSResponse{
+			Success: false,
+			Message: "Invalid keyword rule",
+			Error:   msg,
+		})
+		return
+	}
+
+	if err := database.DB.Create(&rule).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, models.SMSResponse{
+			Success: false,
+			Message: "Failed to create keyword rule",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, models.SMSResponse{
+		Success: true,
+		Message: "Keyword rule created successfully",
+		Data:    rule,
+	})
+}
+
+// ListKeywordRules lists the authenticated client's keyword rules in the order they are tried
+func (h *KeywordRuleHandler) ListKeywordRules(c *gin.Context) {
+	clientID, _ := c.Get("client_id")
+
+	var rules []models.KeywordRule
+	if err := database.DB.Where("client_id = ?", clientID).Order("position, created_at").Find(&rules).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, models.SMSResponse{
+			Success: false,
+			Message: "Failed to retrieve keyword rules",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.SMSResponse{
+		Success: true,
+		Message: "Keyword rules retrieved successfully",
+		Data:    rules,
+	})
+}
+
+// GetKeywordRule returns a single keyword rule
+func (h *KeywordRuleHandler) GetKeywordRule(c *gin.Context) {
+	rule, ok := findClientKeywordRule(c)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, models.SMSResponse{
+		Success: true,
+		Message: "Keyword rule retrieved successfully",
+		Data:    rule,
+	})
+}
+
+// UpdateKeywordRule updates a keyword rule
+func (h *KeywordRuleHandler) UpdateKeywordRule(c *gin.Context) {
+	var req keywordRuleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.SMSResponse{
+			Success: false,
+			Message: "Invalid request payload",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	rule, ok := findClientKeywordRule(c)
+	if !ok {
+		return
+	}
+
+	req.apply(rule)
+	if msg, ok := validateKeywordRule(rule); !ok {
+		c.JSON(http.StatusBadRequest, models.SMSResponse{
+			Success: false,
+			Message: "Invalid keyword rule",
+			Error:   msg,
+		})
+		return
+	}
+
+	if err := database.DB.Save(rule).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, models.SMSResponse{
+			Success: false,
+			Message: "Failed to update keyword rule",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.SMSResponse{
+		Success: true,
+		Message: "Keyword rule updated successfully",
+		Data:    rule,
+	})
+}
+
+// DeleteKeywordRule deletes a keyword rule. Hits already recorded are kept.
+func (h *KeywordRuleHandler) DeleteKeywordRule(c *gin.Context) {
+	rule, ok := findClientKeywordRule(c)
+	if !ok {
+		return
+	}
+
+	if err := database.DB.Delete(rule).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, models.SMSResponse{
+			Success: false,
+			Message: "Failed to delete keyword rule",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.SMSResponse{
+		Success: true,
+		Message: "Keyword rule deleted successfully",
+	})
+}
+
+// TestKeywordRules reports which of the client's rules a message text would match, without running it
+func (h *KeywordRuleHandler) TestKeywordRules(c *gin.Context) {
+	var req struct {
+		Text string `json:"text" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.SMSResponse{
+			Success: false,
+			Message: "Invalid request payload",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	clientID, _ := c.Get("client_id")
+	rule, err := service.MatchKeywordRule(clientID.(uuid.UUID), req.Text)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.SMSResponse{
+			Success: false,
+			Message: "Failed to match keyword rules",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.SMSResponse{
+		Success: true,
+		Message: "Keyword rules tested successfully",
+		Data: map[string]interface{}{
+			"matched": rule != nil,
+			"rule":    rule,
+		},
+	})
+}
@@ -0,0 +1,116 @@
+package handlers
+
+import (
+	"log"
+	"net/http"
+
+	"github.com/Ian-Balijawa/sms-gateway/database"
+	"github.com/Ian-Balijawa/sms-gateway/models"
+	"github.com/Ian-Balijawa/sms-gateway/service"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+type LinkHandler struct{}
+
+func NewLinkHandler() *LinkHandler {
+	return &LinkHandler{}
+}
+
+// RedirectShortLink sends the visitor of a short link on to the original link and records the click.
+// It is public: recipients open it from their messages.
+func (h *LinkHandler) RedirectShortLink(c *gin.Context) {
+	var link models.ShortLink
+	if err := database.DB.Where("code = ?", c.Param("code")).First(&link).Error; err != nil {
+		c.JSON(http.StatusNotFound, models.SMSResponse{
+			Success: false,
+			Message: "Link not found",
+		})
+		return
+	}
+
+	// A failure to record the click must not stop the recipient reaching the link
+	if err := service.RecordLinkClick(&link, c.GetHeader("User-Agent"), c.ClientIP()); err != nil {
+		log.Printf("Error recording click on short link %s: %v", link.Code, err)
+	}
+
+	c.Redirect(http.StatusFound, link.URL)
+}
+
+// ListMessageLinks lists the short links in one of the authenticated client's messages,
+// with every recorded click
+func (h *LinkHandler) ListMessageLinks(c *gin.Context) {
+	clientID, _ := c.Get("client_id")
+
+	var smsLog models.SMSLog
+	if err := database.DB.Where("id = ? AND client_id = ?", c.Param("id"), clientID).First(&smsLog).Error; err != nil {
+		c.JSON(http.StatusNotFound, models.SMSResponse{
+			Success: false,
+			Message: "Message not found",
+		})
+		return
+	}
+
+	var links []models.ShortLink
+	if err := database.DB.Where("sms_log_id = ?", smsLog.ID).Order("created_at").Find(&links).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, models.SMSResponse{
+			Success: false,
+			Message: "Failed to retrieve links",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	var clicks []models.LinkClick
+	if err := database.DB.Where("sms_log_id = ?", smsLog.ID).Order("created_at").Find(&clicks).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, models.SMSResponse{
+			Success: false,
+			Message: "Failed to retrieve link clicks",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	clicksByLink := make(map[uuid.UUID][]models.LinkClick, len(links))
+	for _, click := range clicks {
+		clicksByLink[click.ShortLinkID] = append(clicksByLink[click.ShortLinkID], click)
+	}
+
+	results := make([]map[string]interface{}, len(links))
+	for i, link := range links {
+		linkClicks := clicksByLink[link.ID]
+		if linkClicks == nil {
+			linkClicks = []models.LinkClick{}
+		}
+		results[i] = map[string]interface{}{
+			"id":              link.ID,
+			"code":            link.Code,
+			"short_url":       service.ShortLinkURL(link.Code),
+			"url":             link.URL,
+			"clicks":          link.Clicks,
+			"last_clicked_at": link.LastClickedAt,
+			"click_log":       linkClicks,
+		}
+	}
+
+	c.JSON(http.StatusOK, models.SMSResponse{
+		Success: true,
+		Message: "Links retrieved successfully",
+		Data:    results,
+	})
+}
+
+// checkShortLinks writes a 400 response and returns false if any message asks for
+// shortened links while link shortening is not configured
+func checkShortLinks(c *gin.Context, messages []models.SMSRequest) bool {
+	if err := service.CheckShortLinks(messages); err != nil {
+		c.JSON(http.StatusBadRequest, models.SMSResponse{
+			Success: false,
+			Message: "Link shortening is unavailable",
+			Error:   err.Error(),
+		})
+		return false
+	}
+	return true
+}
@@ -0,0 +1,156 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/Ian-Balijawa/sms-gateway/models"
+	"github.com/Ian-Balijawa/sms-gateway/service"
+	"github.com/Ian-Balijawa/sms-gateway/utils"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// maxValidateNumbers bounds the number of phone numbers checked in one request
+const maxValidateNumbers = 1000
+
+type NumberHandler struct{}
+
+func NewNumberHandler() *NumberHandler {
+	return &NumberHandler{}
+}
+
+// numberValidateRequest accepts a single number, a list of numbers, or both
+type numberValidateRequest struct {
+	Number  string   `json:"number"`
+	Numbers []string `json:"numbers"`
+	Region  string   `json:"region"` // Overrides the client's default region
+}
+
+// numberInfo describes one validated phone number
+type numberInfo struct {
+	Input          string `json:"input"`
+	Valid          bool   `json:"valid"`
+	E164           string `json:"e164,omitempty"`
+	NationalNumber string `json:"national_number,omitempty"`
+	CountryCode    string `json:"country_code,omitempty"`
+	Country        string `json:"country,omitempty"`
+	CallingCode    string `json:"calling_code,omitempty"`
+	Operator       string `json:"operator,omitempty"`
+	LineType       string `json:"line_type,omitempty"`
+	Suppressed     bool   `json:"suppressed"`
+	Reason         string `json:"reason,omitempty"` // One of the utils.PhoneError* reasons
+	Error          string `json:"error,omitempty"`
+}
+
+// ValidateNumbers parses phone numbers the same way the send endpoints do and reports
+// their normalized form, country, network and suppression status. Nothing is sent.
+func (h *NumberHandler) ValidateNumbers(c *gin.Context) {
+	var req numberValidateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.SMSResponse{
+			Success: false,
+			Message: "Invalid request payload",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	inputs := req.Numbers
+	if req.Number != "" {
+		inputs = append([]string{req.Number}, inputs...)
+	}
+	if len(inputs) == 0 {
+		c.JSON(http.StatusBadRequest, models.SMSResponse{
+			Success: false,
+			Message: "Invalid request payload",
+			Error:   "number or numbers is required",
+		})
+		return
+	}
+	if len(inputs) > maxValidateNumbers {
+		c.JSON(http.StatusBadRequest, models.SMSResponse{
+			Success: false,
+			Message: "Too many numbers",
+			Error:   "at most 1000 numbers can be validated per request",
+		})
+		return
+	}
+
+	region := clientRegion(c)
+	if req.Region != "" {
+		normalized, err := normalizeRegion(req.Region)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, models.SMSResponse{
+				Success: false,
+				Message: "Invalid region",
+				Error:   err.Error(),
+			})
+			return
+		}
+		region = normalized
+	}
+
+	results := make([]numberInfo, len(inputs))
+	phones := make([]string, 0, len(inputs))
+	valid := 0
+	for i, input := range inputs {
+		results[i] = describeNumber(input, region)
+		if results[i].Valid {
+			phones = append(phones, results[i].E164)
+			valid++
+		}
+	}
+
+	clientID, _ := c.Get("client_id")
+	suppressed, err := service.SuppressedNumbers(clientID.(uuid.UUID), phones)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.SMSResponse{
+			Success: false,
+			Message: "Failed to check suppression list",
+			Error:   err.Error(),
+		})
+		return
+	}
+	for i := range results {
+		results[i].Suppressed = results[i].Valid && suppressed[results[i].E164]
+	}
+
+	c.JSON(http.StatusOK, models.SMSResponse{
+		Success: true,
+		Message: "Numbers validated successfully",
+		Data: map[string]interface{}{
+			"total":   len(results),
+			"valid":   valid,
+			"invalid": len(results) - valid,
+			"results": results,
+		},
+	})
+}
+
+// describeNumber parses a phone number in the given region and looks up its network
+func describeNumber(input, region string) numberInfo {
+	info := numberInfo{Input: input}
+
+	parsed, err := utils.ParsePhone(input, region)
+	if err != nil {
+		info.Error = err.Error()
+		var phoneErr *utils.PhoneError
+		if errors.As(err, &phoneErr) {
+			info.Reason = phoneErr.Reason
+		}
+		return info
+	}
+
+	info.Valid = true
+	info.E164 = parsed.E164
+	info.NationalNumber = parsed.NationalNumber
+	if parsed.Country != nil {
+		info.CountryCode = parsed.Country.Code
+		info.Country = parsed.Country.Name
+		info.CallingCode = parsed.Country.CallingCode
+	}
+	info.Operator, info.LineType, _ = utils.LookupOperator(parsed.E164)
+	return info
+}
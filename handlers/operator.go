@@ -0,0 +1,47 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/Ian-Balijawa/sms-gateway/config"
+	"github.com/Ian-Balijawa/sms-gateway/models"
+	"github.com/Ian-Balijawa/sms-gateway/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+type OperatorHandler struct{}
+
+func NewOperatorHandler() *OperatorHandler {
+	return &OperatorHandler{}
+}
+
+// ListOperatorPrefixes returns the operator prefix table in use (admin only)
+func (h *OperatorHandler) ListOperatorPrefixes(c *gin.Context) {
+	c.JSON(http.StatusOK, models.SMSResponse{
+		Success: true,
+		Message: "Operator prefixes retrieved successfully",
+		Data:    utils.OperatorPrefixes(),
+	})
+}
+
+// ReloadOperatorPrefixes reloads the operator prefix table from OPERATOR_PREFIXES_FILE,
+// or restores the built-in table when no file is configured (admin only)
+func (h *OperatorHandler) ReloadOperatorPrefixes(c *gin.Context) {
+	if err := utils.LoadOperatorPrefixes(config.AppConfig.OperatorPrefixesFile); err != nil {
+		c.JSON(http.StatusBadRequest, models.SMSResponse{
+			Success: false,
+			Message: "Failed to reload operator prefixes",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.SMSResponse{
+		Success: true,
+		Message: "Operator prefixes reloaded successfully",
+		Data: map[string]interface{}{
+			"prefixes": len(utils.OperatorPrefixes()),
+		},
+	})
+}
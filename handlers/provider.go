@@ -0,0 +1,150 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/Ian-Balijawa/sms-gateway/config"
+	"github.com/Ian-Balijawa/sms-gateway/database"
+	"github.com/Ian-Balijawa/sms-gateway/models"
+	"github.com/Ian-Balijawa/sms-gateway/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+type ProviderHandler struct {
+	monitor *service.BalanceMonitor
+	lanes   *service.LaneScheduler
+}
+
+func NewProviderHandler(monitor *service.BalanceMonitor, lanes *service.LaneScheduler) *ProviderHandler {
+	return &ProviderHandler{monitor: monitor, lanes: lanes}
+}
+
+// GetBalance returns the last known provider balance and the history of balance checks,
+// newest first (admin only)
+func (h *ProviderHandler) GetBalance(c *gin.Context) {
+	limit, offset := paginationParams(c)
+	provider := h.monitor.Provider()
+
+	var latest models.ProviderBalance
+	if err := database.DB.
+		Where("provider = ? AND balance IS NOT NULL", provider).
+		Order("created_at DESC").
+		Limit(1).
+		Find(&latest).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, models.SMSResponse{
+			Success: false,
+			Message: "Failed to retrieve provider balance",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	var history []models.ProviderBalance
+	if err := database.DB.
+		Where("provider = ?", provider).
+		Order("created_at DESC").
+		Limit(limit).
+		Offset(offset).
+		Find(&history).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, models.SMSResponse{
+			Success: false,
+			Message: "Failed to retrieve provider balance history",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	data := gin.H{
+		"provider":              provider,
+		"balance":               nil,
+		"checked_at":            nil,
+		"low":                   false,
+		"low_balance_threshold": config.AppConfig.ProviderBalanceLowThreshold,
+		"history":               history,
+	}
+	if latest.Balance != nil {
+		data["balance"] = *latest.Balance
+		data["checked_at"] = latest.CreatedAt
+		data["low"] = latest.Low
+	}
+
+	c.JSON(http.StatusOK, models.SMSResponse{
+		Success: true,
+		Message: "Provider balance retrieved successfully",
+		Data:    data,
+	})
+}
+
+// CheckBalance queries the provider balance now and stores the reading (admin only)
+func (h *ProviderHandler) CheckBalance(c *gin.Context) {
+	reading, err := h.monitor.Check()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.SMSResponse{
+			Success: false,
+			Message: "Failed to check provider balance",
+			Error:   err.Error(),
+		})
+		return
+	}
+	if reading.Error != "" {
+		c.JSON(http.StatusBadGateway, models.SMSResponse{
+			Success: false,
+			Message: "Provider balance check failed",
+			Error:   reading.Error,
+			Data:    reading,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.SMSResponse{
+		Success: true,
+		Message: "Provider balance checked successfully",
+		Data:    reading,
+	})
+}
+
+// GetLanes returns the limits and current load of each priority lane, highest priority first,
+// with the number of queued and deferred messages waiting to be sent through it (admin only)
+func (h *ProviderHandler) GetLanes(c *gin.Context) {
+	var backlog []struct {
+		Lane  string
+		Count int64
+	}
+	if err := database.DB.Model(&models.SMSLog{}).
+		Select("lane, COUNT(*) AS count").
+		Where("status IN ?", []string{models.SMSStatusQueued, models.SMSStatusDeferred}).
+		Group("lane").
+		Scan(&backlog).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, models.SMSResponse{
+			Success: false,
+			Message: "Failed to retrieve lane backlog",
+			Error:   err.Error(),
+		})
+		return
+	}
+	waiting := make(map[string]int64, len(backlog))
+	for _, row := range backlog {
+		waiting[row.Lane] += row.Count
+	}
+
+	stats := h.lanes.Stats()
+	lanes := make([]gin.H, len(stats))
+	for i, lane := range stats {
+		lanes[i] = gin.H{
+			"name":        lane.Name,
+			"concurrency": lane.Concurrency,
+			"rate":        lane.Rate,
+			"in_flight":   lane.InFlight,
+			"waiting":     lane.Waiting,
+			"sent":        lane.Sent,
+			"backlog":     waiting[lane.Name],
+		}
+	}
+
+	c.JSON(http.StatusOK, models.SMSResponse{
+		Success: true,
+		Message: "Priority lanes retrieved successfully",
+		Data:    lanes,
+	})
+}
@@ -0,0 +1,494 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/Ian-Balijawa/sms-gateway/database"
+	"github.com/Ian-Balijawa/sms-gateway/models"
+	"github.com/Ian-Balijawa/sms-gateway/service"
+	"github.com/Ian-Balijawa/sms-gateway/utils"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+type RecurringJobHandler struct{}
+
+func NewRecurringJobHandler() *RecurringJobHandler {
+	return &RecurringJobHandler{}
+}
+
+// recurringJobRequest is the payload for creating and updating recurring jobs
+type recurringJobRequest struct {
+	Name           *string   `json:"name"`
+	Message        *string   `json:"message"`
+	SenderID       *string   `json:"senderid"`
+	Priority       *string   `json:"priority"`
+	GroupID        *string   `json:"group_id"`
+	Recipients     *[]string `json:"recipients"`
+	ScheduleType   *string   `json:"schedule_type"`
+	CronExpression *string   `json:"cron_expression"`
+	TimeOfDay      *string   `json:"time_of_day"`
+	Weekdays       *[]string `json:"weekdays"`
+	Timezone       *string   `json:"timezone"`
+}
+
+// apply copies the fields present in the request onto the job. An empty group_id clears the group.
+func (req *recurringJobRequest) apply(job *models.RecurringJob) error {
+	if req.Name != nil {
+		job.Name = *req.Name
+	}
+	if req.Message != nil {
+		job.Message = *req.Message
+	}
+	if req.SenderID != nil {
+		job.SenderID = *req.SenderID
+	}
+	if req.Priority != nil {
+		job.Priority = *req.Priority
+	}
+	if req.GroupID != nil {
+		job.GroupID = nil
+		if *req.GroupID != "" {
+			groupID, err := uuid.Parse(*req.GroupID)
+			if err != nil {
+				return fmt.Errorf("invalid group_id")
+			}
+			job.GroupID = &groupID
+		}
+	}
+	if req.Recipients != nil {
+		job.Recipients = *req.Recipients
+	}
+	if req.ScheduleType != nil {
+		job.ScheduleType = *req.ScheduleType
+	}
+	if req.CronExpression != nil {
+		job.CronExpression = *req.CronExpression
+	}
+	if req.TimeOfDay != nil {
+		job.TimeOfDay = *req.TimeOfDay
+	}
+	if req.Weekdays != nil {
+		job.Weekdays = *req.Weekdays
+	}
+	if req.Timezone != nil {
+		job.Timezone = *req.Timezone
+	}
+	return nil
+}
+
+// updates returns the columns to save for the fields present in the request, read from the
+// validated job. The run state (run_count, last_run_at) is left to the scheduler.
+func (req *recurringJobRequest) updates(job *models.RecurringJob) map[string]interface{} {
+	updates := make(map[string]interface{})
+	if req.Name != nil {
+		updates["name"] = job.Name
+	}
+	if req.Message != nil {
+		updates["message"] = job.Message
+	}
+	if req.SenderID != nil {
+		updates["sender_id"] = job.SenderID
+	}
+	if req.Priority != nil {
+		updates["priority"] = job.Priority
+	}
+	if req.GroupID != nil {
+		updates["group_id"] = job.GroupID
+	}
+	if req.Recipients != nil {
+		updates["recipients"] = job.Recipients
+	}
+	if req.ScheduleType != nil || req.CronExpression != nil || req.TimeOfDay != nil || req.Weekdays != nil || req.Timezone != nil {
+		updates["schedule_type"] = job.ScheduleType
+		updates["cron_expression"] = job.CronExpression
+		updates["time_of_day"] = job.TimeOfDay
+		updates["weekdays"] = job.Weekdays
+		updates["timezone"] = job.Timezone
+		if job.Status == models.RecurringJobActive {
+			updates["next_run_at"] = job.NextRunAt
+		}
+	}
+	return updates
+}
+
+// validateRecurringJob checks the job, normalizes its recipients (reading numbers without
+// a country code in the given region) and computes its next run time
+func validateRecurringJob(job *models.RecurringJob, region string) (string, bool) {
+	if job.Name == "" {
+		return "name is required", false
+	}
+	if job.Message == "" {
+		return "message is required", false
+	}
+	if job.GroupID == nil && len(job.Recipients) == 0 {
+		return "group_id or at least one recipient is required", false
+	}
+	if job.GroupID != nil {
+		var group models.Group
+		if err := database.DB.Where("id = ? AND client_id = ?", job.GroupID, job.ClientID).First(&group).Error; err != nil {
+			return "group not found", false
+		}
+	}
+	for i, recipient := range job.Recipients {
+		parsed, err := utils.ParsePhone(recipient, region)
+		if err != nil {
+			return err.Error(), false
+		}
+		job.Recipients[i] = parsed.E164
+	}
+	if err := service.CheckSenderID(job.ClientID, job.SenderID); err != nil {
+		return err.Error(), false
+	}
+	if job.Timezone == "" {
+		job.Timezone = "UTC"
+	}
+
+	next, err := service.NextRecurringRun(job, time.Now())
+	if err != nil {
+		return err.Error(), false
+	}
+	if job.Status == models.RecurringJobActive {
+		job.NextRunAt = &next
+	}
+	return "", true
+}
+
+// findClientJob loads a recurring job owned by the authenticated client
+func findClientJob(c *gin.Context) (*models.RecurringJob, bool) {
+	clientID, _ := c.Get("client_id")
+
+	var job models.RecurringJob
+	if err := database.DB.Where("id = ? AND client_id = ?", c.Param("id"), clientID).First(&job).Error; err != nil {
+		c.JSON(http.StatusNotFound, models.SMSResponse{
+			Success: false,
+			Message: "Recurring job not found",
+		})
+		return nil, false
+	}
+	return &job, true
+}
+
+// reloadRecurringJob reads the job back after an update
+func reloadRecurringJob(c *gin.Context, job *models.RecurringJob) bool {
+	if err := database.DB.Where("id = ?", job.ID).First(job).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, models.SMSResponse{
+			Success: false,
+			Message: "Failed to reload recurring job",
+			Error:   err.Error(),
+		})
+		return false
+	}
+	return true
+}
+
+// CreateRecurringJob creates a recurring job for the authenticated client
+func (h *RecurringJobHandler) CreateRecurringJob(c *gin.Context) {
+	var req recurringJobRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.SMSResponse{
+			Success: false,
+			Message: "Invalid request payload",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	clientID, exists := c.Get("client_id")
+	if !exists {
+		c.JSON(http.StatusInternalServerError, models.SMSResponse{
+			Success: false,
+			Message: "Client ID not found",
+		})
+		return
+	}
+
+	job := models.RecurringJob{
+		ClientID: clientID.(uuid.UUID),
+		Status:   models.RecurringJobActive,
+	}
+	if err := req.apply(&job); err != nil {
+		c.JSON(http.StatusBadRequest, models.SMSResponse{
+			Success: false,
+			Message: "Invalid recurring job",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	if msg, ok := validateRecurringJob(&job, clientRegion(c)); !ok {
+		c.JSON(http.StatusBadRequest, models.SMSResponse{
+			Success: false,
+			Message: "Invalid recurring job",
+			Error:   msg,
+		})
+		return
+	}
+
+	if err := database.DB.Create(&job).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, models.SMSResponse{
+			Success: false,
+			Message: "Failed to create recurring job",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, models.SMSResponse{
+		Success: true,
+		Message: "Recurring job created successfully",
+		Data:    job,
+	})
+}
+
+// ListRecurringJobs lists the authenticated client's recurring jobs
+func (h *RecurringJobHandler) ListRecurringJobs(c *gin.Context) {
+	clientID, _ := c.Get("client_id")
+
+	query := database.DB.Where("client_id = ?", clientID).Order("created_at DESC")
+	if status := c.Query("status"); status != "" {
+		query = query.Where("status = ?", status)
+	}
+
+	var jobs []models.RecurringJob
+	if err := query.Find(&jobs).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, models.SMSResponse{
+			Success: false,
+			Message: "Failed to retrieve recurring jobs",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.SMSResponse{
+		Success: true,
+		Message: "Recurring jobs retrieved successfully",
+		Data:    jobs,
+	})
+}
+
+// GetRecurringJob returns a single recurring job
+func (h *RecurringJobHandler) GetRecurringJob(c *gin.Context) {
+	job, ok := findClientJob(c)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, models.SMSResponse{
+		Success: true,
+		Message: "Recurring job retrieved successfully",
+		Data:    job,
+	})
+}
+
+// UpdateRecurringJob updates a recurring job's message, recipients or schedule
+func (h *RecurringJobHandler) UpdateRecurringJob(c *gin.Context) {
+	var req recurringJobRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.SMSResponse{
+			Success: false,
+			Message: "Invalid request payload",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	job, ok := findClientJob(c)
+	if !ok {
+		return
+	}
+
+	if err := req.apply(job); err != nil {
+		c.JSON(http.StatusBadRequest, models.SMSResponse{
+			Success: false,
+			Message: "Invalid recurring job",
+			Error:   err.Error(),
+		})
+		return
+	}
+	if msg, ok := validateRecurringJob(job, clientRegion(c)); !ok {
+		c.JSON(http.StatusBadRequest, models.SMSResponse{
+			Success: false,
+			Message: "Invalid recurring job",
+			Error:   msg,
+		})
+		return
+	}
+
+	// run_count changes whenever the scheduler claims a run, so an update racing a run is
+	// refused rather than overwriting the claim
+	if updates := req.updates(job); len(updates) > 0 {
+		result := database.DB.Model(&models.RecurringJob{}).
+			Where("id = ? AND run_count = ?", job.ID, job.RunCount).
+			Updates(updates)
+		if result.Error != nil {
+			c.JSON(http.StatusInternalServerError, models.SMSResponse{
+				Success: false,
+				Message: "Failed to update recurring job",
+				Error:   result.Error.Error(),
+			})
+			return
+		}
+		if result.RowsAffected == 0 {
+			c.JSON(http.StatusConflict, models.SMSResponse{
+				Success: false,
+				Message: "Recurring job is running, retry the update",
+			})
+			return
+		}
+	}
+	if !reloadRecurringJob(c, job) {
+		return
+	}
+
+	c.JSON(http.StatusOK, models.SMSResponse{
+		Success: true,
+		Message: "Recurring job updated successfully",
+		Data:    job,
+	})
+}
+
+// DeleteRecurringJob deletes a recurring job. Its run history is kept.
+func (h *RecurringJobHandler) DeleteRecurringJob(c *gin.Context) {
+	job, ok := findClientJob(c)
+	if !ok {
+		return
+	}
+
+	if err := database.DB.Delete(job).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, models.SMSResponse{
+			Success: false,
+			Message: "Failed to delete recurring job",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.SMSResponse{
+		Success: true,
+		Message: "Recurring job deleted successfully",
+	})
+}
+
+// PauseRecurringJob stops a recurring job from running until it is resumed
+func (h *RecurringJobHandler) PauseRecurringJob(c *gin.Context) {
+	job, ok := findClientJob(c)
+	if !ok {
+		return
+	}
+
+	result := database.DB.Model(&models.RecurringJob{}).
+		Where("id = ? AND status = ?", job.ID, models.RecurringJobActive).
+		Updates(map[string]interface{}{
+			"status":      models.RecurringJobPaused,
+			"next_run_at": nil,
+		})
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, models.SMSResponse{
+			Success: false,
+			Message: "Failed to pause recurring job",
+			Error:   result.Error.Error(),
+		})
+		return
+	}
+	if result.RowsAffected == 0 {
+		c.JSON(http.StatusConflict, models.SMSResponse{
+			Success: false,
+			Message: "Recurring job is not active",
+		})
+		return
+	}
+	if !reloadRecurringJob(c, job) {
+		return
+	}
+
+	c.JSON(http.StatusOK, models.SMSResponse{
+		Success: true,
+		Message: "Recurring job paused successfully",
+		Data:    job,
+	})
+}
+
+// ResumeRecurringJob reactivates a paused job from its next scheduled time.
+// Runs missed while the job was paused are not sent.
+func (h *RecurringJobHandler) ResumeRecurringJob(c *gin.Context) {
+	job, ok := findClientJob(c)
+	if !ok {
+		return
+	}
+
+	job.Status = models.RecurringJobActive
+	if msg, ok := validateRecurringJob(job, clientRegion(c)); !ok {
+		c.JSON(http.StatusBadRequest, models.SMSResponse{
+			Success: false,
+			Message: "Recurring job cannot be resumed",
+			Error:   msg,
+		})
+		return
+	}
+
+	result := database.DB.Model(&models.RecurringJob{}).
+		Where("id = ? AND status = ?", job.ID, models.RecurringJobPaused).
+		Updates(map[string]interface{}{
+			"status":      models.RecurringJobActive,
+			"next_run_at": job.NextRunAt,
+			"timezone":    job.Timezone,
+			"last_error":  "",
+		})
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, models.SMSResponse{
+			Success: false,
+			Message: "Failed to resume recurring job",
+			Error:   result.Error.Error(),
+		})
+		return
+	}
+	if result.RowsAffected == 0 {
+		c.JSON(http.StatusConflict, models.SMSResponse{
+			Success: false,
+			Message: "Recurring job is not paused",
+		})
+		return
+	}
+	if !reloadRecurringJob(c, job) {
+		return
+	}
+
+	c.JSON(http.StatusOK, models.SMSResponse{
+		Success: true,
+		Message: "Recurring job resumed successfully",
+		Data:    job,
+	})
+}
+
+// GetRecurringJobRuns returns the run history of a recurring job
+func (h *RecurringJobHandler) GetRecurringJobRuns(c *gin.Context) {
+	job, ok := findClientJob(c)
+	if !ok {
+		return
+	}
+
+	limit, offset := paginationParams(c)
+
+	var runs []models.RecurringJobRun
+	if err := database.DB.Where("job_id = ?", job.ID).
+		Order("started_at DESC").
+		Limit(limit).Offset(offset).
+		Find(&runs).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, models.SMSResponse{
+			Success: false,
+			Message: "Failed to retrieve runs",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.SMSResponse{
+		Success: true,
+		Message: "Runs retrieved successfully",
+		Data:    runs,
+	})
+}
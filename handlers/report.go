@@ -0,0 +1,130 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/Ian-Balijawa/sms-gateway/database"
+	"github.com/Ian-Balijawa/sms-gateway/models"
+	"github.com/Ian-Balijawa/sms-gateway/service"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+type ReportHandler struct{}
+
+func NewReportHandler() *ReportHandler {
+	return &ReportHandler{}
+}
+
+// Reconciliation compares what clients were charged with what the provider charged, per
+// client, provider and day (admin only). Filter with ?from= and ?to= (YYYY-MM-DD, UTC,
+// inclusive; default the current month) and ?client_id=.
+func (h *ReportHandler) Reconciliation(c *gin.Context) {
+	from, to, ok := reportRange(c)
+	if !ok {
+		return
+	}
+
+	var clientID *uuid.UUID
+	if raw := c.Query("client_id"); raw != "" {
+		id, err := uuid.Parse(raw)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, models.SMSResponse{
+				Success: false,
+				Message: "Invalid client ID",
+				Error:   err.Error(),
+			})
+			return
+		}
+		clientID = &id
+	}
+
+	report, err := service.BuildReconciliationReport(from, to, clientID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.SMSResponse{
+			Success: false,
+			Message: "Failed to build reconciliation report",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.SMSResponse{
+		Success: true,
+		Message: "Reconciliation report generated successfully",
+		Data:    report,
+	})
+}
+
+// ListLossMessages lists messages the provider charged more for than the client paid,
+// newest first (admin only). Takes the same filters as the reconciliation report.
+func (h *ReportHandler) ListLossMessages(c *gin.Context) {
+	from, to, ok := reportRange(c)
+	if !ok {
+		return
+	}
+	limit, offset := paginationParams(c)
+
+	query := database.DB.
+		Where("provider_cost > price AND status IN ? AND created_at >= ? AND created_at < ?",
+			service.BillableStatuses, from, to).
+		Order("created_at DESC")
+	if clientID := c.Query("client_id"); clientID != "" {
+		query = query.Where("client_id = ?", clientID)
+	}
+
+	var logs []models.SMSLog
+	if err := query.Limit(limit).Offset(offset).Find(&logs).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, models.SMSResponse{
+			Success: false,
+			Message: "Failed to retrieve messages",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.SMSResponse{
+		Success: true,
+		Message: "Loss-making messages retrieved successfully",
+		Data:    logs,
+	})
+}
+
+// reportRange reads the ?from= and ?to= dates (inclusive) as a half-open UTC time range.
+// It writes the error response and returns false if either date is invalid.
+func reportRange(c *gin.Context) (time.Time, time.Time, bool) {
+	now := time.Now().UTC()
+	from := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+
+	for param, value := range map[string]*time.Time{"from": &from, "to": &to} {
+		raw := c.Query(param)
+		if raw == "" {
+			continue
+		}
+		date, err := time.ParseInLocation("2006-01-02", raw, time.UTC)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, models.SMSResponse{
+				Success: false,
+				Message: fmt.Sprintf("Invalid %s date", param),
+				Error:   "dates must be in YYYY-MM-DD format",
+			})
+			return time.Time{}, time.Time{}, false
+		}
+		*value = date
+	}
+
+	to = to.AddDate(0, 0, 1)
+	if !from.Before(to) {
+		c.JSON(http.StatusBadRequest, models.SMSResponse{
+			Success: false,
+			Message: "Invalid date range",
+			Error:   "from must not be after to",
+		})
+		return time.Time{}, time.Time{}, false
+	}
+	return from, to, true
+}
@@ -0,0 +1,330 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/Ian-Balijawa/sms-gateway/database"
+	"github.com/Ian-Balijawa/sms-gateway/models"
+	"github.com/Ian-Balijawa/sms-gateway/service"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type SenderIDHandler struct{}
+
+func NewSenderIDHandler() *SenderIDHandler {
+	return &SenderIDHandler{}
+}
+
+// RequestSenderID submits a sender name for approval
+func (h *SenderIDHandler) RequestSenderID(c *gin.Context) {
+	var req struct {
+		Name    string `json:"name" binding:"required"`
+		Purpose string `json:"purpose"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.SMSResponse{
+			Success: false,
+			Message: "Invalid request payload",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	name, err := service.NormalizeSenderIDName(req.Name)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.SMSResponse{
+			Success: false,
+			Message: "Invalid sender ID",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	clientID, _ := c.Get("client_id")
+	var existing models.SenderID
+	if err := database.DB.Where("client_id = ? AND name = ?", clientID, name).First(&existing).Error; err == nil {
+		c.JSON(http.StatusConflict, models.SMSResponse{
+			Success: false,
+			Message: fmt.Sprintf("Sender ID has already been requested and is %s", existing.Status),
+			Data:    existing,
+		})
+		return
+	}
+
+	senderID := models.SenderID{
+		ClientID: clientID.(uuid.UUID),
+		Name:     name,
+		Purpose:  req.Purpose,
+		Status:   models.SenderIDPending,
+	}
+	if err := database.DB.Create(&senderID).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, models.SMSResponse{
+			Success: false,
+			Message: "Failed to request sender ID",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, models.SMSResponse{
+		Success: true,
+		Message: "Sender ID submitted for approval",
+		Data:    senderID,
+	})
+}
+
+// ListSenderIDs lists the authenticated client's sender IDs. Filter with ?status=.
+func (h *SenderIDHandler) ListSenderIDs(c *gin.Context) {
+	clientID, _ := c.Get("client_id")
+	listSenderIDs(c, database.DB.Where("client_id = ?", clientID))
+}
+
+// GetSenderID returns one of the authenticated client's sender IDs
+func (h *SenderIDHandler) GetSenderID(c *gin.Context) {
+	senderID, ok := findClientSenderID(c)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, models.SMSResponse{
+		Success: true,
+		Message: "Sender ID retrieved successfully",
+		Data:    senderID,
+	})
+}
+
+// DeleteSenderID withdraws a sender ID request, or stops using an approved sender ID
+func (h *SenderIDHandler) DeleteSenderID(c *gin.Context) {
+	senderID, ok := findClientSenderID(c)
+	if !ok {
+		return
+	}
+
+	if err := database.DB.Delete(senderID).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, models.SMSResponse{
+			Success: false,
+			Message: "Failed to delete sender ID",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.SMSResponse{
+		Success: true,
+		Message: "Sender ID deleted successfully",
+	})
+}
+
+// SetDefaultSenderID makes an approved sender ID the default for messages that do not set one
+func (h *SenderIDHandler) SetDefaultSenderID(c *gin.Context) {
+	senderID, ok := findClientSenderID(c)
+	if !ok {
+		return
+	}
+	if senderID.Status != models.SenderIDApproved {
+		c.JSON(http.StatusConflict, models.SMSResponse{
+			Success: false,
+			Message: fmt.Sprintf("Sender ID is %s, only approved sender IDs can be the default", senderID.Status),
+		})
+		return
+	}
+
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.SenderID{}).
+			Where("client_id = ? AND id <> ?", senderID.ClientID, senderID.ID).
+			Update("is_default", false).Error; err != nil {
+			return err
+		}
+		return tx.Model(senderID).Update("is_default", true).Error
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.SMSResponse{
+			Success: false,
+			Message: "Failed to set default sender ID",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.SMSResponse{
+		Success: true,
+		Message: "Default sender ID updated successfully",
+		Data:    senderID,
+	})
+}
+
+// AdminListSenderIDs lists sender IDs across clients, oldest first so that pending requests
+// are reviewed in order (admin only). Filter with ?status= and ?client_id=.
+func (h *SenderIDHandler) AdminListSenderIDs(c *gin.Context) {
+	query := database.DB
+	if clientID := c.Query("client_id"); clientID != "" {
+		query = query.Where("client_id = ?", clientID)
+	}
+	listSenderIDs(c, query)
+}
+
+// ApproveSenderID allows a client to send with a sender ID (admin only).
+// The client's first approved sender ID becomes its default.
+func (h *SenderIDHandler) ApproveSenderID(c *gin.Context) {
+	var req struct {
+		Note string `json:"note"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+		c.JSON(http.StatusBadRequest, models.SMSResponse{
+			Success: false,
+			Message: "Invalid request payload",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	reviewSenderID(c, models.SenderIDApproved, req.Note)
+}
+
+// RejectSenderID refuses a sender ID request, or revokes an approved sender ID (admin only).
+// A reason is required and shown to the client.
+func (h *SenderIDHandler) RejectSenderID(c *gin.Context) {
+	var req struct {
+		Reason string `json:"reason" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.SMSResponse{
+			Success: false,
+			Message: "Invalid request payload",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	reviewSenderID(c, models.SenderIDRejected, req.Reason)
+}
+
+// reviewSenderID moves the sender ID in the path to the given status
+func reviewSenderID(c *gin.Context, status, note string) {
+	var senderID models.SenderID
+	if err := database.DB.Where("id = ?", c.Param("id")).First(&senderID).Error; err != nil {
+		c.JSON(http.StatusNotFound, models.SMSResponse{
+			Success: false,
+			Message: "Sender ID not found",
+		})
+		return
+	}
+	if senderID.Status == status {
+		c.JSON(http.StatusConflict, models.SMSResponse{
+			Success: false,
+			Message: fmt.Sprintf("Sender ID is already %s", status),
+			Data:    senderID,
+		})
+		return
+	}
+
+	now := time.Now().UTC()
+	updates := map[string]interface{}{
+		"status":      status,
+		"review_note": note,
+		"reviewed_at": now,
+		"is_default":  false,
+	}
+	if status == models.SenderIDApproved {
+		var defaults int64
+		if err := database.DB.Model(&models.SenderID{}).
+			Where("client_id = ? AND status = ? AND is_default = ?", senderID.ClientID, models.SenderIDApproved, true).
+			Count(&defaults).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, models.SMSResponse{
+				Success: false,
+				Message: "Failed to review sender ID",
+				Error:   err.Error(),
+			})
+			return
+		}
+		updates["is_default"] = defaults == 0
+	}
+
+	if err := database.DB.Model(&senderID).Updates(updates).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, models.SMSResponse{
+			Success: false,
+			Message: "Failed to review sender ID",
+			Error:   err.Error(),
+		})
+		return
+	}
+	database.DB.Where("id = ?", senderID.ID).First(&senderID)
+
+	c.JSON(http.StatusOK, models.SMSResponse{
+		Success: true,
+		Message: fmt.Sprintf("Sender ID %s", status),
+		Data:    senderID,
+	})
+}
+
+// listSenderIDs writes a page of the sender IDs matched by the query, filtered by ?status=
+func listSenderIDs(c *gin.Context, query *gorm.DB) {
+	limit, offset := paginationParams(c)
+	if status := c.Query("status"); status != "" {
+		query = query.Where("status = ?", status)
+	}
+
+	var senderIDs []models.SenderID
+	if err := query.Order("created_at").Limit(limit).Offset(offset).Find(&senderIDs).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, models.SMSResponse{
+			Success: false,
+			Message: "Failed to retrieve sender IDs",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.SMSResponse{
+		Success: true,
+		Message: "Sender IDs retrieved successfully",
+		Data:    senderIDs,
+	})
+}
+
+// findClientSenderID loads a sender ID owned by the authenticated client
+func findClientSenderID(c *gin.Context) (*models.SenderID, bool) {
+	clientID, _ := c.Get("client_id")
+
+	var senderID models.SenderID
+	if err := database.DB.Where("id = ? AND client_id = ?", c.Param("id"), clientID).First(&senderID).Error; err != nil {
+		c.JSON(http.StatusNotFound, models.SMSResponse{
+			Success: false,
+			Message: "Sender ID not found",
+		})
+		return nil, false
+	}
+	return &senderID, true
+}
+
+// checkSenderIDs writes a 403 response and returns false if any message uses a sender ID
+// the client may not send with
+func checkSenderIDs(c *gin.Context, clientID uuid.UUID, messages []models.SMSRequest) bool {
+	ids, err := service.LoadSenderIDs(clientID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.SMSResponse{
+			Success: false,
+			Message: "Failed to check sender IDs",
+			Error:   err.Error(),
+		})
+		return false
+	}
+
+	for _, msg := range messages {
+		if _, err := ids.Resolve(msg.SenderID); err != nil {
+			c.JSON(http.StatusForbidden, models.SMSResponse{
+				Success: false,
+				Message: "Sender ID not approved",
+				Error:   err.Error(),
+			})
+			return false
+		}
+	}
+	return true
+}
@@ -1,9 +1,12 @@
 package handlers
 
 import (
+	"fmt"
 	"net/http"
 	"strconv"
+	"time"
 
+	"github.com/Ian-Balijawa/sms-gateway/config"
 	"github.com/Ian-Balijawa/sms-gateway/database"
 	"github.com/Ian-Balijawa/sms-gateway/models"
 	"github.com/Ian-Balijawa/sms-gateway/service"
@@ -13,12 +16,12 @@ import (
 )
 
 type SMSHandler struct {
-	smsProvider *service.SMSProvider
+	dispatcher *service.Dispatcher
 }
 
-func NewSMSHandler() *SMSHandler {
+func NewSMSHandler(dispatcher *service.Dispatcher) *SMSHandler {
 	return &SMSHandler{
-		smsProvider: service.NewSMSProvider(),
+		dispatcher: dispatcher,
 	}
 }
 
@@ -35,11 +38,20 @@ func (h *SMSHandler) SendSingleSMS(c *gin.Context) {
 	}
 
 	// Validate phone number
-	if !utils.ValidatePhone(req.Number) {
+	if _, err := utils.ParsePhone(req.Number, clientRegion(c)); err != nil {
 		c.JSON(http.StatusBadRequest, models.SMSResponse{
 			Success: false,
 			Message: "Invalid phone number",
-			Error:   "Phone number format is invalid",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	if err := validateQuietHours(req.QuietHours); err != nil {
+		c.JSON(http.StatusBadRequest, models.SMSResponse{
+			Success: false,
+			Message: "Invalid quiet hours",
+			Error:   err.Error(),
 		})
 		return
 	}
@@ -54,26 +66,28 @@ func (h *SMSHandler) SendSingleSMS(c *gin.Context) {
 		return
 	}
 	apiClient := client.(models.APIClient)
-	clientID := apiClient.ID
+
+	if !checkSenderIDs(c, apiClient.ID, []models.SMSRequest{req}) {
+		return
+	}
+	if !checkContentPolicy(c, &apiClient, []models.SMSRequest{req}) {
+		return
+	}
+	if !checkShortLinks(c, []models.SMSRequest{req}) {
+		return
+	}
+	if !checkWalletBalance(c, &apiClient, []models.SMSRequest{req}) {
+		return
+	}
 
 	// Send SMS via provider
-	responses, err := h.smsProvider.SendSMS([]models.SMSRequest{req}, apiClient.Name)
+	result, err := h.dispatcher.Dispatch(service.DispatchRequest{
+		Client:    &apiClient,
+		Messages:  []models.SMSRequest{req},
+		IPAddress: c.ClientIP(),
+		UserAgent: c.GetHeader("User-Agent"),
+	})
 	if err != nil {
-		// Log error
-		smsLog := models.SMSLog{
-			ClientID:       clientID,
-			Recipient:      req.Number,
-			Message:        req.Message,
-			SenderID:       req.SenderID,
-			Priority:       req.Priority,
-			Status:         "failed",
-			ProviderStatus: "error",
-			Error:          err.Error(),
-			IPAddress:      c.ClientIP(),
-			UserAgent:      c.GetHeader("User-Agent"),
-		}
-		database.DB.Create(&smsLog)
-
 		c.JSON(http.StatusInternalServerError, models.SMSResponse{
 			Success: false,
 			Message: "Failed to send SMS",
@@ -81,60 +95,81 @@ func (h *SMSHandler) SendSingleSMS(c *gin.Context) {
 		})
 		return
 	}
-
-	// Determine status from provider response
-	status := "sent"
-	providerStatus := "Success"
-	providerMessage := "SMS sent successfully"
-	errorMsg := ""
-
-	if len(responses) > 0 {
-		resp := responses[0]
-		if resp.Status != "Success" && resp.Status != "success" {
-			status = "failed"
-			providerStatus = resp.Status
-			providerMessage = resp.Message
-			errorMsg = resp.Message
-		} else {
-			providerMessage = resp.Message
-		}
-	}
-
-	// Log SMS
-	smsLog := models.SMSLog{
-		ClientID:       clientID,
-		Recipient:      utils.FormatPhone(req.Number),
-		Message:        req.Message,
-		SenderID:       req.SenderID,
-		Priority:       req.Priority,
-		Status:         status,
-		ProviderStatus: providerStatus,
-		ProviderMessage: providerMessage,
-		Error:          errorMsg,
-		IPAddress:      c.ClientIP(),
-		UserAgent:      c.GetHeader("User-Agent"),
-	}
-	database.DB.Create(&smsLog)
-
-	// Update client usage
-	if status == "sent" {
-		apiClient.DailyUsage++
-		apiClient.MonthlyUsage++
-		database.DB.Save(&apiClient)
-	}
+	smsLog := result.Logs[0]
 
 	// Return response
-	if status == "sent" {
+	if smsLog.Status == models.SMSStatusSuppressed {
+		c.JSON(http.StatusOK, models.SMSResponse{
+			Success: false,
+			Message: "Recipient has opted out of messages",
+			Error:   smsLog.Error,
+			Data: map[string]interface{}{
+				"log_id":    smsLog.ID,
+				"recipient": smsLog.Recipient,
+				"status":    smsLog.Status,
+			},
+		})
+	} else if smsLog.Status == models.SMSStatusBlocked {
+		c.JSON(http.StatusForbidden, models.SMSResponse{
+			Success: false,
+			Message: "SMS blocked by fraud protection",
+			Error:   smsLog.Error,
+			Data: map[string]interface{}{
+				"log_id":      smsLog.ID,
+				"recipient":   smsLog.Recipient,
+				"status":      smsLog.Status,
+				"fraud_score": smsLog.FraudScore,
+			},
+		})
+	} else if service.HeldForFraudReview(smsLog) {
+		c.JSON(http.StatusAccepted, models.SMSResponse{
+			Success: true,
+			Message: "SMS held for fraud review",
+			Data: map[string]interface{}{
+				"log_id":       smsLog.ID,
+				"recipient":    smsLog.Recipient,
+				"status":       smsLog.Status,
+				"scheduled_at": smsLog.ScheduledAt,
+				"fraud_score":  smsLog.FraudScore,
+			},
+		})
+	} else if smsLog.Status == models.SMSStatusDeferred {
+		c.JSON(http.StatusAccepted, models.SMSResponse{
+			Success: true,
+			Message: "SMS deferred until the recipient's quiet hours end",
+			Data: map[string]interface{}{
+				"log_id":       smsLog.ID,
+				"recipient":    smsLog.Recipient,
+				"status":       smsLog.Status,
+				"scheduled_at": smsLog.ScheduledAt,
+			},
+		})
+	} else if smsLog.Status == models.SMSStatusSent {
 		c.JSON(http.StatusOK, models.SMSResponse{
 			Success: true,
 			Message: "SMS sent successfully",
 			Data: map[string]interface{}{
 				"log_id":    smsLog.ID,
 				"recipient": smsLog.Recipient,
-				"status":    status,
+				"status":    smsLog.Status,
 				"provider_response": map[string]string{
-					"status":  providerStatus,
-					"message": providerMessage,
+					"status":     smsLog.ProviderStatus,
+					"message":    smsLog.ProviderMessage,
+					"message_id": smsLog.ProviderMessageID,
+				},
+			},
+		})
+	} else if smsLog.Status == models.SMSStatusUnknown {
+		c.JSON(http.StatusAccepted, models.SMSResponse{
+			Success: true,
+			Message: "SMS submitted, the provider did not report its outcome",
+			Data: map[string]interface{}{
+				"log_id":    smsLog.ID,
+				"recipient": smsLog.Recipient,
+				"status":    smsLog.Status,
+				"provider_response": map[string]string{
+					"status":  smsLog.ProviderStatus,
+					"message": smsLog.ProviderMessage,
 				},
 			},
 		})
@@ -142,11 +177,11 @@ func (h *SMSHandler) SendSingleSMS(c *gin.Context) {
 		c.JSON(http.StatusOK, models.SMSResponse{
 			Success: false,
 			Message: "SMS failed to send",
-			Error:   errorMsg,
+			Error:   smsLog.Error,
 			Data: map[string]interface{}{
 				"log_id":    smsLog.ID,
 				"recipient": smsLog.Recipient,
-				"status":    status,
+				"status":    smsLog.Status,
 			},
 		})
 	}
@@ -164,16 +199,48 @@ func (h *SMSHandler) SendBulkSMS(c *gin.Context) {
 		return
 	}
 
-	// Validate all phone numbers
+	if maxMessages := config.AppConfig.MaxBulkMessages; maxMessages > 0 && len(req.Messages) > maxMessages {
+		c.JSON(http.StatusRequestEntityTooLarge, models.SMSResponse{
+			Success: false,
+			Message: "Bulk request is too large",
+			Error:   fmt.Sprintf("A bulk request may contain at most %d messages, got %d", maxMessages, len(req.Messages)),
+		})
+		return
+	}
+
+	// Validate all phone numbers. In partial mode invalid numbers are logged as rejected instead.
+	region := clientRegion(c)
 	for _, msg := range req.Messages {
-		if !utils.ValidatePhone(msg.Number) {
+		if _, err := utils.ParsePhone(msg.Number, region); err != nil && !req.Partial {
 			c.JSON(http.StatusBadRequest, models.SMSResponse{
 				Success: false,
 				Message: "Invalid phone number in messages",
-				Error:   "Phone number " + msg.Number + " is invalid",
+				Error:   err.Error(),
 			})
 			return
 		}
+		if err := validateQuietHours(msg.QuietHours); err != nil {
+			c.JSON(http.StatusBadRequest, models.SMSResponse{
+				Success: false,
+				Message: "Invalid quiet hours in messages",
+				Error:   err.Error(),
+			})
+			return
+		}
+	}
+
+	// Detect repeated recipients, and drop them when collapsing
+	duplicateOf := findDuplicateRecipients(req.Messages, region)
+	messages := make([]models.SMSRequest, 0, len(req.Messages))
+	duplicates := 0
+	for i, msg := range req.Messages {
+		if duplicateOf[i] >= 0 {
+			duplicates++
+			if req.CollapseDuplicates {
+				continue
+			}
+		}
+		messages = append(messages, msg)
 	}
 
 	// Get client from context
@@ -186,10 +253,9 @@ func (h *SMSHandler) SendBulkSMS(c *gin.Context) {
 		return
 	}
 	apiClient := client.(models.APIClient)
-	clientID := apiClient.ID
 
 	// Check if bulk request exceeds limits
-	if apiClient.DailyUsage+len(req.Messages) > apiClient.DailyLimit {
+	if apiClient.DailyUsage+len(messages) > apiClient.DailyLimit {
 		c.JSON(http.StatusTooManyRequests, models.SMSResponse{
 			Success: false,
 			Message: "Bulk request would exceed daily limit",
@@ -198,8 +264,26 @@ func (h *SMSHandler) SendBulkSMS(c *gin.Context) {
 		return
 	}
 
+	// In partial mode messages with a sender ID the client may not use, or breaking the content
+	// policy, are logged as rejected instead
+	if !req.Partial && (!checkSenderIDs(c, apiClient.ID, messages) || !checkContentPolicy(c, &apiClient, messages)) {
+		return
+	}
+	if !checkShortLinks(c, messages) {
+		return
+	}
+	if !checkWalletBalance(c, &apiClient, messages) {
+		return
+	}
+
 	// Send SMS via provider
-	responses, err := h.smsProvider.SendSMS(req.Messages, apiClient.Name)
+	result, err := h.dispatcher.Dispatch(service.DispatchRequest{
+		Client:    &apiClient,
+		Messages:  messages,
+		Bulk:      true,
+		IPAddress: c.ClientIP(),
+		UserAgent: c.GetHeader("User-Agent"),
+	})
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, models.SMSResponse{
 			Success: false,
@@ -209,71 +293,42 @@ func (h *SMSHandler) SendBulkSMS(c *gin.Context) {
 		return
 	}
 
-	// Log all SMS messages and track results
-	results := make([]map[string]interface{}, 0)
-	successCount := 0
-	failedCount := 0
-
-	for i, msg := range req.Messages {
-		var resp service.SMSProviderResponse
-		if i < len(responses) {
-			resp = responses[i]
-		} else if len(responses) > 0 {
-			// Use first response if not enough responses
-			resp = responses[0]
-		}
-
-		status := "sent"
-		providerStatus := "Success"
-		providerMessage := "SMS sent successfully"
-		errorMsg := ""
-
-		if resp.Status != "Success" && resp.Status != "success" {
-			status = "failed"
-			providerStatus = resp.Status
-			providerMessage = resp.Message
-			errorMsg = resp.Message
-			failedCount++
-		} else {
-			successCount++
+	// Report every requested message in request order, including collapsed duplicates
+	sent := logResults(result.Logs)
+	results := make([]map[string]interface{}, 0, len(req.Messages))
+	for i := range req.Messages {
+		first := duplicateOf[i]
+		if first >= 0 && req.CollapseDuplicates {
+			results = append(results, map[string]interface{}{
+				"recipient":    results[first]["recipient"],
+				"status":       "duplicate",
+				"duplicate_of": first,
+			})
+			continue
 		}
 
-		// Log SMS
-		smsLog := models.SMSLog{
-			ClientID:       clientID,
-			Recipient:      utils.FormatPhone(msg.Number),
-			Message:        msg.Message,
-			SenderID:       msg.SenderID,
-			Priority:       msg.Priority,
-			Status:         status,
-			ProviderStatus: providerStatus,
-			ProviderMessage: providerMessage,
-			Error:          errorMsg,
-			IPAddress:      c.ClientIP(),
-			UserAgent:      c.GetHeader("User-Agent"),
+		entry := sent[0]
+		sent = sent[1:]
+		if first >= 0 {
+			entry["duplicate_of"] = first
 		}
-		database.DB.Create(&smsLog)
-
-		results = append(results, map[string]interface{}{
-			"log_id":    smsLog.ID,
-			"recipient": smsLog.Recipient,
-			"status":    status,
-		})
+		results = append(results, entry)
 	}
 
-	// Update client usage
-	apiClient.DailyUsage += successCount
-	apiClient.MonthlyUsage += successCount
-	database.DB.Save(&apiClient)
-
 	c.JSON(http.StatusOK, models.SMSResponse{
 		Success: true,
 		Message: "Bulk SMS processing completed",
 		Data: map[string]interface{}{
-			"total":        len(req.Messages),
-			"successful":   successCount,
-			"failed":       failedCount,
-			"results":      results,
+			"total":      len(req.Messages),
+			"successful": result.Successful,
+			"failed":     result.Failed,
+			"suppressed": result.Suppressed,
+			"deferred":   result.Deferred,
+			"rejected":   result.Rejected,
+			"unknown":    result.Unknown,
+			"blocked":    result.Blocked,
+			"duplicates": duplicates,
+			"results":    results,
 		},
 	})
 }
@@ -293,16 +348,7 @@ func (h *SMSHandler) GetSMSLogs(c *gin.Context) {
 	query := database.DB.Where("client_id = ?", clientID).Order("created_at DESC")
 
 	// Pagination
-	limitStr := c.DefaultQuery("limit", "50")
-	offsetStr := c.DefaultQuery("offset", "0")
-	limit, err1 := strconv.Atoi(limitStr)
-	offset, err2 := strconv.Atoi(offsetStr)
-	if err1 != nil {
-		limit = 50
-	}
-	if err2 != nil {
-		offset = 0
-	}
+	limit, offset := paginationParams(c)
 	query = query.Limit(limit).Offset(offset)
 
 	// Status filter
@@ -310,6 +356,26 @@ func (h *SMSHandler) GetSMSLogs(c *gin.Context) {
 		query = query.Where("status = ?", status)
 	}
 
+	// Network filter
+	if operator := c.Query("operator"); operator != "" {
+		query = query.Where("operator = ?", operator)
+	}
+
+	// Campaign filter
+	if campaignID := c.Query("campaign_id"); campaignID != "" {
+		query = query.Where("campaign_id = ?", campaignID)
+	}
+
+	// Priority lane filter
+	if lane := c.Query("lane"); lane != "" {
+		query = query.Where("lane = ?", lane)
+	}
+
+	// Messages sent despite breaking a content rule
+	if c.Query("policy_flagged") == "true" {
+		query = query.Where("policy_flagged = ?", true)
+	}
+
 	if err := query.Find(&logs).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, models.SMSResponse{
 			Success: false,
@@ -345,6 +411,32 @@ func (h *SMSHandler) GetStats(c *gin.Context) {
 		DailyLimit:   apiClient.DailyLimit,
 		MonthlyLimit: apiClient.MonthlyLimit,
 		IsActive:     apiClient.IsActive,
+		Networks:     []models.NetworkStats{},
+	}
+
+	// Month-to-date breakdown per destination network
+	now := time.Now()
+	monthStart := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, now.Location())
+	if err := database.DB.Model(&models.SMSLog{}).
+		Select("operator, COUNT(*) AS total, "+
+			"SUM(CASE WHEN status = ? THEN 1 ELSE 0 END) AS sent, "+
+			"SUM(CASE WHEN status = ? THEN 1 ELSE 0 END) AS failed",
+			models.SMSStatusSent, models.SMSStatusFailed).
+		Where("client_id = ? AND created_at >= ?", apiClient.ID, monthStart).
+		Group("operator").
+		Order("total DESC").
+		Scan(&stats.Networks).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, models.SMSResponse{
+			Success: false,
+			Message: "Failed to retrieve statistics",
+			Error:   err.Error(),
+		})
+		return
+	}
+	for i := range stats.Networks {
+		if stats.Networks[i].Operator == "" {
+			stats.Networks[i].Operator = "unknown"
+		}
 	}
 
 	c.JSON(http.StatusOK, models.SMSResponse{
@@ -354,3 +446,98 @@ func (h *SMSHandler) GetStats(c *gin.Context) {
 	})
 }
 
+// logResults summarizes dispatched messages for a bulk response
+func logResults(logs []models.SMSLog) []map[string]interface{} {
+	results := make([]map[string]interface{}, 0, len(logs))
+	for _, smsLog := range logs {
+		entry := map[string]interface{}{
+			"log_id":    smsLog.ID,
+			"recipient": smsLog.Recipient,
+			"status":    smsLog.Status,
+		}
+		if smsLog.ScheduledAt != nil {
+			entry["scheduled_at"] = smsLog.ScheduledAt
+		}
+		if smsLog.ProviderMessageID != "" {
+			entry["provider_message_id"] = smsLog.ProviderMessageID
+		}
+		if smsLog.Status == models.SMSStatusRejected || smsLog.Status == models.SMSStatusFailed || smsLog.Status == models.SMSStatusBlocked {
+			entry["error"] = smsLog.Error
+		}
+		if smsLog.FraudFlagged {
+			entry["fraud_score"] = smsLog.FraudScore
+		}
+		results = append(results, entry)
+	}
+	return results
+}
+
+// findDuplicateRecipients returns, for each message, the index of the first earlier message
+// to the same normalized recipient, or -1. Invalid numbers are compared as given.
+func findDuplicateRecipients(messages []models.SMSRequest, region string) []int {
+	duplicateOf := make([]int, len(messages))
+	first := make(map[string]int, len(messages))
+	for i, msg := range messages {
+		recipient := utils.FormatPhoneForRegion(msg.Number, region)
+		if j, ok := first[recipient]; ok {
+			duplicateOf[i] = j
+			continue
+		}
+		first[recipient] = i
+		duplicateOf[i] = -1
+	}
+	return duplicateOf
+}
+
+// clientRegion returns the default phone region of the authenticated client
+func clientRegion(c *gin.Context) string {
+	client, exists := c.Get("client")
+	if !exists {
+		return ""
+	}
+	return client.(models.APIClient).DefaultRegion
+}
+
+// validateQuietHours checks an optional quiet hours window
+func validateQuietHours(quietHours *models.QuietHours) error {
+	if quietHours == nil {
+		return nil
+	}
+	return validateQuietHoursWindow(quietHours.Start, quietHours.End)
+}
+
+// validateQuietHoursWindow checks that start and end are both empty or both valid "HH:MM" times
+func validateQuietHoursWindow(start, end string) error {
+	if start == "" && end == "" {
+		return nil
+	}
+	if _, err := utils.ParseClock(start); err != nil {
+		return err
+	}
+	if _, err := utils.ParseClock(end); err != nil {
+		return err
+	}
+	return nil
+}
+
+// Page sizes accepted by paginationParams
+const (
+	defaultPageLimit = 50
+	maxPageLimit     = 500
+)
+
+// paginationParams reads the limit and offset query parameters. The limit is kept between
+// 1 and maxPageLimit and negative offsets are read as 0.
+func paginationParams(c *gin.Context) (int, int) {
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", strconv.Itoa(defaultPageLimit)))
+	if err != nil || limit < 1 {
+		limit = defaultPageLimit
+	}
+	limit = min(limit, maxPageLimit)
+
+	offset, err := strconv.Atoi(c.DefaultQuery("offset", "0"))
+	if err != nil || offset < 0 {
+		offset = 0
+	}
+	return limit, offset
+}
@@ -0,0 +1,188 @@
+package handlers
+
+import (
+	"encoding/csv"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/Ian-Balijawa/sms-gateway/database"
+	"github.com/Ian-Balijawa/sms-gateway/models"
+	"github.com/Ian-Balijawa/sms-gateway/service"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type StatementHandler struct{}
+
+func NewStatementHandler() *StatementHandler {
+	return &StatementHandler{}
+}
+
+// ListStatements lists the authenticated client's finalized statements, newest first
+func (h *StatementHandler) ListStatements(c *gin.Context) {
+	clientID, _ := c.Get("client_id")
+	listStatements(c, database.DB.Where("client_id = ?", clientID))
+}
+
+// GetStatement returns the authenticated client's statement for a month (YYYY-MM), as JSON
+// or, with ?format=csv, as a CSV download. Months that are not finalized are returned as drafts.
+func (h *StatementHandler) GetStatement(c *gin.Context) {
+	clientID, _ := c.Get("client_id")
+	respondStatement(c, clientID.(uuid.UUID))
+}
+
+// AdminListStatements lists finalized statements across clients (admin only).
+// Filter with ?client_id= and ?period=.
+func (h *StatementHandler) AdminListStatements(c *gin.Context) {
+	query := database.DB
+	if clientID := c.Query("client_id"); clientID != "" {
+		query = query.Where("client_id = ?", clientID)
+	}
+	if period := c.Query("period"); period != "" {
+		query = query.Where("period = ?", period)
+	}
+	listStatements(c, query)
+}
+
+// AdminGetStatement returns a client's statement for a month, as JSON or CSV (admin only)
+func (h *StatementHandler) AdminGetStatement(c *gin.Context) {
+	client, ok := findClientByParam(c)
+	if !ok {
+		return
+	}
+	respondStatement(c, client.ID)
+}
+
+// FinalizeStatement stores a client's statement for a month that has ended, after which it
+// never changes (admin only)
+func (h *StatementHandler) FinalizeStatement(c *gin.Context) {
+	client, ok := findClientByParam(c)
+	if !ok {
+		return
+	}
+
+	period := c.Param("period")
+	_, end, err := service.StatementPeriod(period)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.SMSResponse{
+			Success: false,
+			Message: "Invalid period",
+			Error:   err.Error(),
+		})
+		return
+	}
+	if end.After(time.Now()) {
+		c.JSON(http.StatusBadRequest, models.SMSResponse{
+			Success: false,
+			Message: "Statement period has not ended",
+			Error:   fmt.Sprintf("%s can be finalized from %s", period, end.Format(time.RFC3339)),
+		})
+		return
+	}
+
+	statement, created, err := service.FinalizeStatement(client.ID, period)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.SMSResponse{
+			Success: false,
+			Message: "Failed to finalize statement",
+			Error:   err.Error(),
+		})
+		return
+	}
+	if !created {
+		c.JSON(http.StatusConflict, models.SMSResponse{
+			Success: false,
+			Message: "Statement is already finalized",
+			Data:    statement,
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, models.SMSResponse{
+		Success: true,
+		Message: "Statement finalized successfully",
+		Data:    statement,
+	})
+}
+
+// listStatements writes a page of the finalized statements matched by the query
+func listStatements(c *gin.Context, query *gorm.DB) {
+	limit, offset := paginationParams(c)
+
+	var statements []models.Statement
+	if err := query.Order("period DESC").Limit(limit).Offset(offset).Find(&statements).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, models.SMSResponse{
+			Success: false,
+			Message: "Failed to retrieve statements",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.SMSResponse{
+		Success: true,
+		Message: "Statements retrieved successfully",
+		Data:    statements,
+	})
+}
+
+// respondStatement writes the client's statement for the :period in the path, as JSON or CSV
+func respondStatement(c *gin.Context, clientID uuid.UUID) {
+	period := c.Param("period")
+	if _, _, err := service.StatementPeriod(period); err != nil {
+		c.JSON(http.StatusBadRequest, models.SMSResponse{
+			Success: false,
+			Message: "Invalid period",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	statement, err := service.GetStatement(clientID, period)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.SMSResponse{
+			Success: false,
+			Message: "Failed to build statement",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	if c.Query("format") != "csv" {
+		c.JSON(http.StatusOK, models.SMSResponse{
+			Success: true,
+			Message: "Statement retrieved successfully",
+			Data:    statement,
+		})
+		return
+	}
+
+	c.Header("Content-Type", "text/csv")
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=\"statement-%s-%s-%s.csv\"", clientID, period, statement.Status))
+
+	writer := csv.NewWriter(c.Writer)
+	writer.Write([]string{"destination", "sender_id", "messages", "segments", "cost", "currency"})
+	for _, line := range statement.Lines {
+		writer.Write([]string{
+			line.Destination,
+			line.SenderID,
+			strconv.FormatInt(line.Messages, 10),
+			strconv.FormatInt(line.Segments, 10),
+			strconv.FormatInt(line.Cost, 10),
+			statement.Currency,
+		})
+	}
+	writer.Write([]string{
+		"total",
+		"",
+		strconv.FormatInt(statement.Messages, 10),
+		strconv.FormatInt(statement.Segments, 10),
+		strconv.FormatInt(statement.Cost, 10),
+		statement.Currency,
+	})
+	writer.Flush()
+}
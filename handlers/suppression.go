@@ -0,0 +1,234 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/Ian-Balijawa/sms-gateway/database"
+	"github.com/Ian-Balijawa/sms-gateway/models"
+	"github.com/Ian-Balijawa/sms-gateway/service"
+	"github.com/Ian-Balijawa/sms-gateway/utils"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+type SuppressionHandler struct{}
+
+func NewSuppressionHandler() *SuppressionHandler {
+	return &SuppressionHandler{}
+}
+
+// suppressionRequest is the payload for adding numbers to a suppression list
+type suppressionRequest struct {
+	Phones   []string   `json:"phones" binding:"required,min=1"`
+	Reason   string     `json:"reason"`
+	ClientID *uuid.UUID `json:"client_id"` // Admin only, omit for the global list
+}
+
+// addSuppressions adds the request's phone numbers to a suppression list and writes the response.
+// Numbers without a country code are read in the given region.
+func addSuppressions(c *gin.Context, clientID *uuid.UUID, region string, req suppressionRequest, source string) {
+	added := make([]models.Suppression, 0, len(req.Phones))
+	existing := make([]string, 0)
+	invalid := make([]string, 0)
+
+	for _, raw := range req.Phones {
+		phone, err := normalizeContactPhone(raw, region)
+		if err != nil {
+			invalid = append(invalid, raw)
+			continue
+		}
+
+		suppression, created, err := service.AddSuppression(clientID, phone, req.Reason, source)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, models.SMSResponse{
+				Success: false,
+				Message: "Failed to add suppression",
+				Error:   err.Error(),
+			})
+			return
+		}
+		if created {
+			added = append(added, *suppression)
+		} else {
+			existing = append(existing, suppression.Phone)
+		}
+	}
+
+	c.JSON(http.StatusOK, models.SMSResponse{
+		Success: true,
+		Message: "Suppressions added successfully",
+		Data: map[string]interface{}{
+			"added":          added,
+			"already_listed": existing,
+			"invalid_phones": invalid,
+		},
+	})
+}
+
+// ListSuppressions lists the authenticated client's suppressed numbers.
+// Global suppressions are included with ?include_global=true.
+func (h *SuppressionHandler) ListSuppressions(c *gin.Context) {
+	clientID, _ := c.Get("client_id")
+	limit, offset := paginationParams(c)
+
+	query := database.DB.Order("created_at DESC").Limit(limit).Offset(offset)
+	if c.Query("include_global") == "true" {
+		query = query.Where("client_id = ? OR client_id IS NULL", clientID)
+	} else {
+		query = query.Where("client_id = ?", clientID)
+	}
+
+	var suppressions []models.Suppression
+	if err := query.Find(&suppressions).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, models.SMSResponse{
+			Success: false,
+			Message: "Failed to retrieve suppressions",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.SMSResponse{
+		Success: true,
+		Message: "Suppressions retrieved successfully",
+		Data:    suppressions,
+	})
+}
+
+// AddSuppressions adds phone numbers to the authenticated client's suppression list
+func (h *SuppressionHandler) AddSuppressions(c *gin.Context) {
+	var req suppressionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.SMSResponse{
+			Success: false,
+			Message: "Invalid request payload",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	clientID, _ := c.Get("client_id")
+	id := clientID.(uuid.UUID)
+
+	addSuppressions(c, &id, clientRegion(c), req, models.SuppressionSourceClient)
+}
+
+// RemoveSuppression removes a phone number from the authenticated client's suppression list.
+// Global suppressions can only be removed by an admin.
+func (h *SuppressionHandler) RemoveSuppression(c *gin.Context) {
+	clientID, _ := c.Get("client_id")
+	id := clientID.(uuid.UUID)
+
+	removed, err := service.RemoveSuppression(&id, utils.FormatPhoneForRegion(c.Param("phone"), clientRegion(c)))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.SMSResponse{
+			Success: false,
+			Message: "Failed to remove suppression",
+			Error:   err.Error(),
+		})
+		return
+	}
+	if !removed {
+		c.JSON(http.StatusNotFound, models.SMSResponse{
+			Success: false,
+			Message: "Phone number is not on your suppression list",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.SMSResponse{
+		Success: true,
+		Message: "Suppression removed successfully",
+	})
+}
+
+// AdminListSuppressions lists suppressions across clients (admin only).
+// Filter with ?client_id= or ?scope=global.
+func (h *SuppressionHandler) AdminListSuppressions(c *gin.Context) {
+	limit, offset := paginationParams(c)
+
+	query := database.DB.Order("created_at DESC").Limit(limit).Offset(offset)
+	if c.Query("scope") == "global" {
+		query = query.Where("client_id IS NULL")
+	} else if clientID := c.Query("client_id"); clientID != "" {
+		query = query.Where("client_id = ?", clientID)
+	}
+	if phone := c.Query("phone"); phone != "" {
+		if normalized, err := normalizeContactPhone(phone, ""); err == nil {
+			phone = normalized
+		}
+		query = query.Where("phone = ?", phone)
+	}
+
+	var suppressions []models.Suppression
+	if err := query.Find(&suppressions).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, models.SMSResponse{
+			Success: false,
+			Message: "Failed to retrieve suppressions",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.SMSResponse{
+		Success: true,
+		Message: "Suppressions retrieved successfully",
+		Data:    suppressions,
+	})
+}
+
+// AdminAddSuppressions adds phone numbers to a client's suppression list, or to the
+// global list when no client_id is given (admin only)
+func (h *SuppressionHandler) AdminAddSuppressions(c *gin.Context) {
+	var req suppressionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.SMSResponse{
+			Success: false,
+			Message: "Invalid request payload",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	// Global entries use the gateway's default region
+	region := ""
+	if req.ClientID != nil {
+		var client models.APIClient
+		if err := database.DB.Where("id = ?", req.ClientID).First(&client).Error; err != nil {
+			c.JSON(http.StatusNotFound, models.SMSResponse{
+				Success: false,
+				Message: "Client not found",
+			})
+			return
+		}
+		region = client.DefaultRegion
+	}
+
+	addSuppressions(c, req.ClientID, region, req, models.SuppressionSourceAdmin)
+}
+
+// AdminRemoveSuppression removes any suppression entry by ID (admin only)
+func (h *SuppressionHandler) AdminRemoveSuppression(c *gin.Context) {
+	result := database.DB.Where("id = ?", c.Param("id")).Delete(&models.Suppression{})
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, models.SMSResponse{
+			Success: false,
+			Message: "Failed to remove suppression",
+			Error:   result.Error.Error(),
+		})
+		return
+	}
+	if result.RowsAffected == 0 {
+		c.JSON(http.StatusNotFound, models.SMSResponse{
+			Success: false,
+			Message: "Suppression not found",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.SMSResponse{
+		Success: true,
+		Message: "Suppression removed successfully",
+	})
+}
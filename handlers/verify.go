@@ -0,0 +1,361 @@
+package handlers
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"time"
+
+	"github.com/Ian-Balijawa/sms-gateway/config"
+	"github.com/Ian-Balijawa/sms-gateway/database"
+	"github.com/Ian-Balijawa/sms-gateway/models"
+	"github.com/Ian-Balijawa/sms-gateway/service"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+type VerifyHandler struct {
+	dispatcher *service.Dispatcher
+}
+
+func NewVerifyHandler(dispatcher *service.Dispatcher) *VerifyHandler {
+	return &VerifyHandler{
+		dispatcher: dispatcher,
+	}
+}
+
+// verificationData is the public view of a verification returned by the verify endpoints
+func verificationData(v *models.Verification) map[string]interface{} {
+	return map[string]interface{}{
+		"verification_id":    v.ID,
+		"phone":              v.Phone,
+		"status":             v.Status,
+		"expires_at":         v.ExpiresAt,
+		"attempts_remaining": max(v.MaxAttempts-v.Attempts, 0),
+		"send_count":         v.SendCount,
+	}
+}
+
+// retryAfterSeconds returns the whole seconds until t, at least 1
+func retryAfterSeconds(t time.Time) int {
+	return max(int(math.Ceil(time.Until(t).Seconds())), 1)
+}
+
+// StartVerification sends a one-time code to a phone number. Starting again for a number with
+// a pending verification sends a new code for that verification, subject to the resend cooldown.
+func (h *VerifyHandler) StartVerification(c *gin.Context) {
+	var req struct {
+		Phone    string `json:"phone" binding:"required"`
+		SenderID string `json:"senderid"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.SMSResponse{
+			Success: false,
+			Message: "Invalid request payload",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	phone, err := normalizeContactPhone(req.Phone, clientRegion(c))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.SMSResponse{
+			Success: false,
+			Message: "Invalid phone number",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	client, exists := c.Get("client")
+	if !exists {
+		c.JSON(http.StatusInternalServerError, models.SMSResponse{
+			Success: false,
+			Message: "Client not found in context",
+		})
+		return
+	}
+	apiClient := client.(models.APIClient)
+	now := time.Now().UTC()
+
+	// Limit the codes sent to one number to stop SMS pumping through the verify endpoint
+	var sentLastHour int64
+	if err := database.DB.Model(&models.Verification{}).
+		Select("COALESCE(SUM(send_count), 0)").
+		Where("client_id = ? AND phone = ? AND last_sent_at >= ?", apiClient.ID, phone, now.Add(-time.Hour)).
+		Scan(&sentLastHour).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, models.SMSResponse{
+			Success: false,
+			Message: "Failed to check verification history",
+			Error:   err.Error(),
+		})
+		return
+	}
+	if sentLastHour >= int64(config.AppConfig.OTPMaxSendsPerNumberPerHour) {
+		c.Header("Retry-After", "3600")
+		c.JSON(http.StatusTooManyRequests, models.SMSResponse{
+			Success: false,
+			Message: "Too many verification codes sent to this number",
+			Error:   "try again later",
+		})
+		return
+	}
+
+	var verification models.Verification
+	err = database.DB.
+		Where("client_id = ? AND phone = ? AND status = ? AND expires_at > ?", apiClient.ID, phone, models.VerificationPending, now).
+		Order("created_at DESC").
+		First(&verification).Error
+	if err == nil {
+		cooldownEnds := verification.LastSentAt.Add(time.Duration(config.AppConfig.OTPResendCooldownSeconds) * time.Second)
+		if cooldownEnds.After(now) {
+			retryAfter := retryAfterSeconds(cooldownEnds)
+			c.Header("Retry-After", fmt.Sprint(retryAfter))
+			c.JSON(http.StatusTooManyRequests, models.SMSResponse{
+				Success: false,
+				Message: "A code was sent recently, wait before resending",
+				Error:   fmt.Sprintf("retry after %d seconds", retryAfter),
+				Data:    verificationData(&verification),
+			})
+			return
+		}
+	} else {
+		verification = models.Verification{
+			ID:          uuid.New(),
+			ClientID:    apiClient.ID,
+			Phone:       phone,
+			Status:      models.VerificationPending,
+			MaxAttempts: max(config.AppConfig.OTPMaxAttempts, 1),
+		}
+	}
+
+	code, err := service.GenerateOTP()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.SMSResponse{
+			Success: false,
+			Message: "Failed to generate verification code",
+			Error:   err.Error(),
+		})
+		return
+	}
+	verification.CodeHash = service.HashOTP(&verification, code)
+	verification.ExpiresAt = now.Add(time.Duration(config.AppConfig.OTPTTLSeconds) * time.Second)
+	verification.SendCount++
+	verification.LastSentAt = &now
+
+	message := models.SMSRequest{
+		Number:   phone,
+		Message:  service.OTPMessage(&apiClient, code),
+		SenderID: req.SenderID,
+		Priority: models.PriorityTransactional,
+		OTPCode:  code,
+	}
+	if !checkSenderIDs(c, apiClient.ID, []models.SMSRequest{message}) {
+		return
+	}
+	if !checkWalletBalance(c, &apiClient, []models.SMSRequest{message}) {
+		return
+	}
+
+	result, sendErr := h.dispatcher.Dispatch(service.DispatchRequest{
+		Client:    &apiClient,
+		Messages:  []models.SMSRequest{message},
+		IPAddress: c.ClientIP(),
+		UserAgent: c.GetHeader("User-Agent"),
+	})
+	var smsLog *models.SMSLog
+	if result != nil && len(result.Logs) > 0 {
+		smsLog = &result.Logs[0]
+		verification.SMSLogID = &smsLog.ID
+	}
+
+	// Saved even if sending failed, so that failed sends count towards the limits
+	if err := database.DB.Save(&verification).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, models.SMSResponse{
+			Success: false,
+			Message: "Failed to save verification",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	if sendErr != nil || smsLog == nil || (smsLog.Status != models.SMSStatusSent && smsLog.Status != models.SMSStatusUnknown) {
+		errMsg := ""
+		if sendErr != nil {
+			errMsg = sendErr.Error()
+		} else if smsLog != nil {
+			errMsg = smsLog.Error
+		}
+		data := verificationData(&verification)
+		if smsLog != nil {
+			data["sms_status"] = smsLog.Status
+		}
+		c.JSON(http.StatusBadGateway, models.SMSResponse{
+			Success: false,
+			Message: "Failed to send verification code",
+			Error:   errMsg,
+			Data:    data,
+		})
+		return
+	}
+
+	data := verificationData(&verification)
+	data["sms_status"] = smsLog.Status
+	data["resend_after"] = now.Add(time.Duration(config.AppConfig.OTPResendCooldownSeconds) * time.Second)
+	c.JSON(http.StatusOK, models.SMSResponse{
+		Success: true,
+		Message: "Verification code sent",
+		Data:    data,
+	})
+}
+
+// CheckVerification checks a code against a verification, given by verification_id or by
+// the phone number's latest verification. Codes are compared in constant time and
+// the verification is locked after too many wrong codes.
+func (h *VerifyHandler) CheckVerification(c *gin.Context) {
+	var req struct {
+		VerificationID string `json:"verification_id"`
+		Phone          string `json:"phone"`
+		Code           string `json:"code" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.SMSResponse{
+			Success: false,
+			Message: "Invalid request payload",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	clientID, _ := c.Get("client_id")
+	query := database.DB.Where("client_id = ?", clientID)
+	switch {
+	case req.VerificationID != "":
+		query = query.Where("id = ?", req.VerificationID)
+	case req.Phone != "":
+		phone, err := normalizeContactPhone(req.Phone, clientRegion(c))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, models.SMSResponse{
+				Success: false,
+				Message: "Invalid phone number",
+				Error:   err.Error(),
+			})
+			return
+		}
+		query = query.Where("phone = ?", phone).Order("created_at DESC")
+	default:
+		c.JSON(http.StatusBadRequest, models.SMSResponse{
+			Success: false,
+			Message: "verification_id or phone is required",
+		})
+		return
+	}
+
+	var verification models.Verification
+	if err := query.First(&verification).Error; err != nil {
+		c.JSON(http.StatusNotFound, models.SMSResponse{
+			Success: false,
+			Message: "Verification not found",
+		})
+		return
+	}
+
+	now := time.Now()
+	if verification.Status == models.VerificationPending && !verification.ExpiresAt.After(now) {
+		verification.Status = models.VerificationExpired
+		database.DB.Model(&verification).Update("status", verification.Status)
+	}
+
+	switch verification.Status {
+	case models.VerificationApproved:
+		c.JSON(http.StatusConflict, models.SMSResponse{
+			Success: false,
+			Message: "Verification already approved",
+			Data:    verificationData(&verification),
+		})
+		return
+	case models.VerificationExpired:
+		c.JSON(http.StatusGone, models.SMSResponse{
+			Success: false,
+			Message: "Verification expired",
+			Data:    verificationData(&verification),
+		})
+		return
+	case models.VerificationFailed:
+		c.JSON(http.StatusTooManyRequests, models.SMSResponse{
+			Success: false,
+			Message: "Too many incorrect codes, start a new verification",
+			Data:    verificationData(&verification),
+		})
+		return
+	}
+
+	// Count the attempt before comparing. attempts acts as a version so that concurrent
+	// checks cannot get more guesses than allowed.
+	claim := database.DB.Model(&models.Verification{}).
+		Where("id = ? AND status = ? AND attempts = ?", verification.ID, models.VerificationPending, verification.Attempts).
+		Update("attempts", verification.Attempts+1)
+	if claim.Error != nil || claim.RowsAffected == 0 {
+		c.JSON(http.StatusConflict, models.SMSResponse{
+			Success: false,
+			Message: "Verification is being checked, try again",
+		})
+		return
+	}
+	verification.Attempts++
+
+	if service.CheckOTP(&verification, req.Code) {
+		verification.Status = models.VerificationApproved
+		verification.VerifiedAt = &now
+		database.DB.Model(&verification).Updates(map[string]interface{}{
+			"status":      verification.Status,
+			"verified_at": now,
+		})
+
+		data := verificationData(&verification)
+		data["valid"] = true
+		c.JSON(http.StatusOK, models.SMSResponse{
+			Success: true,
+			Message: "Code verified",
+			Data:    data,
+		})
+		return
+	}
+
+	if verification.Attempts >= verification.MaxAttempts {
+		verification.Status = models.VerificationFailed
+		database.DB.Model(&verification).Update("status", verification.Status)
+	}
+
+	data := verificationData(&verification)
+	data["valid"] = false
+	c.JSON(http.StatusBadRequest, models.SMSResponse{
+		Success: false,
+		Message: "Invalid code",
+		Data:    data,
+	})
+}
+
+// GetVerification returns the status of a verification
+func (h *VerifyHandler) GetVerification(c *gin.Context) {
+	clientID, _ := c.Get("client_id")
+
+	var verification models.Verification
+	if err := database.DB.Where("id = ? AND client_id = ?", c.Param("id"), clientID).First(&verification).Error; err != nil {
+		c.JSON(http.StatusNotFound, models.SMSResponse{
+			Success: false,
+			Message: "Verification not found",
+		})
+		return
+	}
+	if verification.Status == models.VerificationPending && !verification.ExpiresAt.After(time.Now()) {
+		verification.Status = models.VerificationExpired
+	}
+
+	c.JSON(http.StatusOK, models.SMSResponse{
+		Success: true,
+		Message: "Verification retrieved successfully",
+		Data:    verificationData(&verification),
+	})
+}
@@ -0,0 +1,409 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/Ian-Balijawa/sms-gateway/config"
+	"github.com/Ian-Balijawa/sms-gateway/database"
+	"github.com/Ian-Balijawa/sms-gateway/models"
+	"github.com/Ian-Balijawa/sms-gateway/service"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+type WalletHandler struct{}
+
+func NewWalletHandler() *WalletHandler {
+	return &WalletHandler{}
+}
+
+// GetWallet returns the authenticated client's wallet balance
+func (h *WalletHandler) GetWallet(c *gin.Context) {
+	clientID, _ := c.Get("client_id")
+	respondWallet(c, clientID.(uuid.UUID))
+}
+
+// ListTransactions lists the authenticated client's wallet ledger, newest first.
+// Filter with ?type= (topup, adjustment, debit, refund).
+func (h *WalletHandler) ListTransactions(c *gin.Context) {
+	clientID, _ := c.Get("client_id")
+	respondTransactions(c, clientID.(uuid.UUID))
+}
+
+// ListPrices lists the price per segment for each destination prefix
+func (h *WalletHandler) ListPrices(c *gin.Context) {
+	var rules []models.PriceRule
+	if err := database.DB.Order("prefix").Find(&rules).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, models.SMSResponse{
+			Success: false,
+			Message: "Failed to retrieve prices",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.SMSResponse{
+		Success: true,
+		Message: "Prices retrieved successfully",
+		Data: map[string]interface{}{
+			"currency": config.AppConfig.WalletCurrency,
+			"prices":   rules,
+		},
+	})
+}
+
+// AdminGetWallet returns a client's wallet balance (admin only)
+func (h *WalletHandler) AdminGetWallet(c *gin.Context) {
+	client, ok := findClientByParam(c)
+	if !ok {
+		return
+	}
+	respondWallet(c, client.ID)
+}
+
+// AdminListTransactions lists a client's wallet ledger, newest first (admin only)
+func (h *WalletHandler) AdminListTransactions(c *gin.Context) {
+	client, ok := findClientByParam(c)
+	if !ok {
+		return
+	}
+	respondTransactions(c, client.ID)
+}
+
+// walletCreditRequest is the payload for top-ups and adjustments
+type walletCreditRequest struct {
+	Amount    int64  `json:"amount" binding:"required"`
+	Reference string `json:"reference"`
+	Note      string `json:"note"`
+}
+
+// TopUpWallet adds credit to a client's wallet (admin only)
+func (h *WalletHandler) TopUpWallet(c *gin.Context) {
+	var req walletCreditRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.SMSResponse{
+			Success: false,
+			Message: "Invalid request payload",
+			Error:   err.Error(),
+		})
+		return
+	}
+	if req.Amount <= 0 {
+		c.JSON(http.StatusBadRequest, models.SMSResponse{
+			Success: false,
+			Message: "Invalid amount",
+			Error:   "amount must be positive",
+		})
+		return
+	}
+
+	creditWallet(c, models.WalletTxTopUp, req)
+}
+
+// AdjustWallet corrects a client's balance by a positive or negative amount (admin only).
+// A note explaining the adjustment is required.
+func (h *WalletHandler) AdjustWallet(c *gin.Context) {
+	var req walletCreditRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.SMSResponse{
+			Success: false,
+			Message: "Invalid request payload",
+			Error:   err.Error(),
+		})
+		return
+	}
+	if strings.TrimSpace(req.Note) == "" {
+		c.JSON(http.StatusBadRequest, models.SMSResponse{
+			Success: false,
+			Message: "Invalid adjustment",
+			Error:   "note is required",
+		})
+		return
+	}
+
+	creditWallet(c, models.WalletTxAdjustment, req)
+}
+
+// creditWallet records a top-up or adjustment for the client in the path and writes the response
+func creditWallet(c *gin.Context, txType string, req walletCreditRequest) {
+	client, ok := findClientByParam(c)
+	if !ok {
+		return
+	}
+
+	entry, applied, err := service.CreditWallet(client.ID, txType, req.Amount, req.Reference, req.Note)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.SMSResponse{
+			Success: false,
+			Message: "Failed to update wallet",
+			Error:   err.Error(),
+		})
+		return
+	}
+	if !applied {
+		c.JSON(http.StatusConflict, models.SMSResponse{
+			Success: false,
+			Message: "Adjustment would make the balance negative",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.SMSResponse{
+		Success: true,
+		Message: "Wallet updated successfully",
+		Data:    entry,
+	})
+}
+
+// priceRuleRequest is the payload for creating and updating price rules
+type priceRuleRequest struct {
+	Prefix          string `json:"prefix" binding:"required"`
+	PricePerSegment int64  `json:"price_per_segment"`
+	Description     string `json:"description"`
+}
+
+// CreatePriceRule adds a destination prefix to the price list (admin only)
+func (h *WalletHandler) CreatePriceRule(c *gin.Context) {
+	var req priceRuleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.SMSResponse{
+			Success: false,
+			Message: "Invalid request payload",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	rule := models.PriceRule{Description: req.Description}
+	if !applyPriceRule(c, &rule, req) {
+		return
+	}
+
+	var existing models.PriceRule
+	if err := database.DB.Where("prefix = ?", rule.Prefix).First(&existing).Error; err == nil {
+		c.JSON(http.StatusConflict, models.SMSResponse{
+			Success: false,
+			Message: "A price for this prefix already exists",
+			Data:    existing,
+		})
+		return
+	}
+
+	if err := database.DB.Create(&rule).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, models.SMSResponse{
+			Success: false,
+			Message: "Failed to create price",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, models.SMSResponse{
+		Success: true,
+		Message: "Price created successfully",
+		Data:    rule,
+	})
+}
+
+// UpdatePriceRule changes a price rule (admin only). Messages already sent keep their price.
+func (h *WalletHandler) UpdatePriceRule(c *gin.Context) {
+	var rule models.PriceRule
+	if err := database.DB.Where("id = ?", c.Param("id")).First(&rule).Error; err != nil {
+		c.JSON(http.StatusNotFound, models.SMSResponse{
+			Success: false,
+			Message: "Price not found",
+		})
+		return
+	}
+
+	req := priceRuleRequest{
+		Prefix:          rule.Prefix,
+		PricePerSegment: rule.PricePerSegment,
+		Description:     rule.Description,
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.SMSResponse{
+			Success: false,
+			Message: "Invalid request payload",
+			Error:   err.Error(),
+		})
+		return
+	}
+	rule.Description = req.Description
+	if !applyPriceRule(c, &rule, req) {
+		return
+	}
+
+	if err := database.DB.Save(&rule).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, models.SMSResponse{
+			Success: false,
+			Message: "Failed to update price",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.SMSResponse{
+		Success: true,
+		Message: "Price updated successfully",
+		Data:    rule,
+	})
+}
+
+// DeletePriceRule removes a destination prefix from the price list (admin only)
+func (h *WalletHandler) DeletePriceRule(c *gin.Context) {
+	result := database.DB.Where("id = ?", c.Param("id")).Delete(&models.PriceRule{})
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, models.SMSResponse{
+			Success: false,
+			Message: "Failed to delete price",
+			Error:   result.Error.Error(),
+		})
+		return
+	}
+	if result.RowsAffected == 0 {
+		c.JSON(http.StatusNotFound, models.SMSResponse{
+			Success: false,
+			Message: "Price not found",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.SMSResponse{
+		Success: true,
+		Message: "Price deleted successfully",
+	})
+}
+
+// applyPriceRule validates the request and copies its prefix and price onto the rule.
+// It writes the error response and returns false if the request is invalid.
+func applyPriceRule(c *gin.Context, rule *models.PriceRule, req priceRuleRequest) bool {
+	digits := strings.TrimPrefix(strings.TrimSpace(req.Prefix), "+")
+	if strings.Trim(digits, "0123456789") != "" {
+		c.JSON(http.StatusBadRequest, models.SMSResponse{
+			Success: false,
+			Message: "Invalid prefix",
+			Error:   "prefix must be an international number prefix, e.g. +25677, or + for every destination",
+		})
+		return false
+	}
+	if req.PricePerSegment < 0 {
+		c.JSON(http.StatusBadRequest, models.SMSResponse{
+			Success: false,
+			Message: "Invalid price",
+			Error:   "price_per_segment must not be negative",
+		})
+		return false
+	}
+
+	rule.Prefix = "+" + digits
+	rule.PricePerSegment = req.PricePerSegment
+	return true
+}
+
+// checkWalletBalance refuses the request with 402 if the client's wallet cannot pay for the messages.
+// It returns true if the messages can be sent.
+func checkWalletBalance(c *gin.Context, client *models.APIClient, messages []models.SMSRequest) bool {
+	if !config.AppConfig.WalletEnabled {
+		return true
+	}
+
+	cost, err := service.QuoteMessages(client, messages)
+	if err == nil && cost == 0 {
+		return true
+	}
+	var wallet *models.Wallet
+	if err == nil {
+		wallet, err = service.GetWallet(client.ID)
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.SMSResponse{
+			Success: false,
+			Message: "Failed to check wallet balance",
+			Error:   err.Error(),
+		})
+		return false
+	}
+
+	if wallet.Balance < cost {
+		c.JSON(http.StatusPaymentRequired, models.SMSResponse{
+			Success: false,
+			Message: "Insufficient wallet balance",
+			Error:   fmt.Sprintf("Sending costs %d %s, the balance is %d", cost, config.AppConfig.WalletCurrency, wallet.Balance),
+			Data: map[string]interface{}{
+				"cost":     cost,
+				"balance":  wallet.Balance,
+				"currency": config.AppConfig.WalletCurrency,
+			},
+		})
+		return false
+	}
+	return true
+}
+
+// respondWallet writes a client's wallet balance
+func respondWallet(c *gin.Context, clientID uuid.UUID) {
+	wallet, err := service.GetWallet(clientID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.SMSResponse{
+			Success: false,
+			Message: "Failed to retrieve wallet",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.SMSResponse{
+		Success: true,
+		Message: "Wallet retrieved successfully",
+		Data: map[string]interface{}{
+			"client_id":  wallet.ClientID,
+			"balance":    wallet.Balance,
+			"currency":   config.AppConfig.WalletCurrency,
+			"enabled":    config.AppConfig.WalletEnabled,
+			"updated_at": wallet.UpdatedAt,
+		},
+	})
+}
+
+// respondTransactions writes a page of a client's wallet ledger
+func respondTransactions(c *gin.Context, clientID uuid.UUID) {
+	limit, offset := paginationParams(c)
+
+	query := database.DB.Where("client_id = ?", clientID).Order("created_at DESC")
+	if txType := c.Query("type"); txType != "" {
+		query = query.Where("type = ?", txType)
+	}
+
+	var entries []models.WalletTransaction
+	if err := query.Limit(limit).Offset(offset).Find(&entries).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, models.SMSResponse{
+			Success: false,
+			Message: "Failed to retrieve transactions",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.SMSResponse{
+		Success: true,
+		Message: "Transactions retrieved successfully",
+		Data:    entries,
+	})
+}
+
+// findClientByParam loads the client in the :id path parameter, writing a 404 if it does not exist
+func findClientByParam(c *gin.Context) (*models.APIClient, bool) {
+	var client models.APIClient
+	if err := database.DB.Where("id = ?", c.Param("id")).First(&client).Error; err != nil {
+		c.JSON(http.StatusNotFound, models.SMSResponse{
+			Success: false,
+			Message: "Client not found",
+		})
+		return nil, false
+	}
+	return &client, true
+}
@@ -8,6 +8,7 @@ import (
 	"github.com/Ian-Balijawa/sms-gateway/database"
 	"github.com/Ian-Balijawa/sms-gateway/handlers"
 	"github.com/Ian-Balijawa/sms-gateway/middleware"
+	"github.com/Ian-Balijawa/sms-gateway/service"
 	"github.com/Ian-Balijawa/sms-gateway/utils"
 	"syscall"
 	"time"
@@ -27,9 +28,37 @@ func main() {
 		log.Fatalf("Failed to initialize database: %v", err)
 	}
 
+	// Load the operator prefix table, falling back to the built-in one
+	if err := utils.LoadOperatorPrefixes(config.AppConfig.OperatorPrefixesFile); err != nil {
+		log.Printf("Failed to load operator prefixes, using built-in table: %v", err)
+	}
+
 	// Start usage reset scheduler
 	utils.StartUsageResetScheduler()
 
+	// All outbound messages go through a single dispatcher
+	provider := service.NewProvider()
+	dispatcher := service.NewDispatcher(provider)
+
+	// Start sending messages deferred by quiet hours
+	dispatcher.StartDeferredSender()
+
+	// Fail contact imports abandoned by a server that stopped
+	service.StartContactImportRecovery()
+
+	// Retry forwarding inbound messages to client webhooks
+	service.StartInboundForwarder()
+
+	// Start recurring job scheduler
+	service.NewRecurringScheduler(dispatcher).Start()
+
+	// Start sending running campaigns
+	service.NewCampaignRunner(dispatcher).Start()
+
+	// Start checking the provider account balance
+	balanceMonitor := service.NewBalanceMonitor(provider)
+	balanceMonitor.Start()
+
 	// Set Gin mode
 	if os.Getenv("GIN_MODE") == "" {
 		gin.SetMode(gin.ReleaseMode)
@@ -53,8 +82,27 @@ func main() {
 	}))
 
 	// Initialize handlers
-	smsHandler := handlers.NewSMSHandler()
+	smsHandler := handlers.NewSMSHandler(dispatcher)
 	clientHandler := handlers.NewClientHandler()
+	recurringJobHandler := handlers.NewRecurringJobHandler()
+	contactHandler := handlers.NewContactHandler()
+	groupHandler := handlers.NewGroupHandler(dispatcher)
+	suppressionHandler := handlers.NewSuppressionHandler()
+	operatorHandler := handlers.NewOperatorHandler()
+	numberHandler := handlers.NewNumberHandler()
+	campaignHandler := handlers.NewCampaignHandler()
+	inboundHandler := handlers.NewInboundHandler(dispatcher)
+	keywordRuleHandler := handlers.NewKeywordRuleHandler()
+	conversationHandler := handlers.NewConversationHandler()
+	verifyHandler := handlers.NewVerifyHandler(dispatcher)
+	fraudHandler := handlers.NewFraudHandler()
+	walletHandler := handlers.NewWalletHandler()
+	statementHandler := handlers.NewStatementHandler()
+	reportHandler := handlers.NewReportHandler()
+	providerHandler := handlers.NewProviderHandler(balanceMonitor, dispatcher.Lanes())
+	senderIDHandler := handlers.NewSenderIDHandler()
+	contentRuleHandler := handlers.NewContentRuleHandler()
+	linkHandler := handlers.NewLinkHandler()
 
 	// Health check endpoint
 	router.GET("/health", func(c *gin.Context) {
@@ -64,6 +112,9 @@ func main() {
 		})
 	})
 
+	// Short links in messages (public, opened by recipients)
+	router.GET("/l/:code", linkHandler.RedirectShortLink)
+
 	// API v1 routes
 	v1 := router.Group("/api/v1")
 	{
@@ -74,7 +125,155 @@ func main() {
 			sms.POST("/send", smsHandler.SendSingleSMS)
 			sms.POST("/send/bulk", smsHandler.SendBulkSMS)
 			sms.GET("/logs", smsHandler.GetSMSLogs)
+			sms.GET("/logs/:id/links", linkHandler.ListMessageLinks)
 			sms.GET("/stats", smsHandler.GetStats)
+			sms.POST("/check", contentRuleHandler.CheckMessage)
+		}
+
+		// Verification (OTP) endpoints (require API key authentication)
+		verify := v1.Group("/verify")
+		verify.Use(middleware.APIKeyAuth())
+		{
+			verify.POST("/start", verifyHandler.StartVerification)
+			verify.POST("/check", verifyHandler.CheckVerification)
+			verify.GET("/:id", verifyHandler.GetVerification)
+		}
+
+		// Wallet endpoints (require API key authentication)
+		wallet := v1.Group("/wallet")
+		wallet.Use(middleware.APIKeyAuth())
+		{
+			wallet.GET("", walletHandler.GetWallet)
+			wallet.GET("/transactions", walletHandler.ListTransactions)
+			wallet.GET("/prices", walletHandler.ListPrices)
+		}
+
+		// Statement endpoints (require API key authentication)
+		statements := v1.Group("/statements")
+		statements.Use(middleware.APIKeyAuth())
+		{
+			statements.GET("", statementHandler.ListStatements)
+			statements.GET("/:period", statementHandler.GetStatement)
+		}
+
+		// Sender ID endpoints (require API key authentication)
+		senderIDs := v1.Group("/sender-ids")
+		senderIDs.Use(middleware.APIKeyAuth())
+		{
+			senderIDs.POST("", senderIDHandler.RequestSenderID)
+			senderIDs.GET("", senderIDHandler.ListSenderIDs)
+			senderIDs.GET("/:id", senderIDHandler.GetSenderID)
+			senderIDs.DELETE("/:id", senderIDHandler.DeleteSenderID)
+			senderIDs.POST("/:id/default", senderIDHandler.SetDefaultSenderID)
+		}
+
+		// Recurring job endpoints (require API key authentication)
+		recurring := v1.Group("/recurring-jobs")
+		recurring.Use(middleware.APIKeyAuth())
+		{
+			recurring.POST("", recurringJobHandler.CreateRecurringJob)
+			recurring.GET("", recurringJobHandler.ListRecurringJobs)
+			recurring.GET("/:id", recurringJobHandler.GetRecurringJob)
+			recurring.PUT("/:id", recurringJobHandler.UpdateRecurringJob)
+			recurring.DELETE("/:id", recurringJobHandler.DeleteRecurringJob)
+			recurring.POST("/:id/pause", recurringJobHandler.PauseRecurringJob)
+			recurring.POST("/:id/resume", recurringJobHandler.ResumeRecurringJob)
+			recurring.GET("/:id/runs", recurringJobHandler.GetRecurringJobRuns)
+		}
+
+		// Contact endpoints (require API key authentication)
+		contacts := v1.Group("/contacts")
+		contacts.Use(middleware.APIKeyAuth())
+		{
+			contacts.POST("", contactHandler.CreateContact)
+			contacts.GET("", contactHandler.ListContacts)
+			contacts.POST("/import", contactHandler.ImportContacts)
+			contacts.GET("/imports", contactHandler.ListContactImports)
+			contacts.GET("/imports/:id", contactHandler.GetContactImport)
+			contacts.GET("/imports/:id/errors", contactHandler.GetContactImportErrors)
+			contacts.GET("/:id", contactHandler.GetContact)
+			contacts.PUT("/:id", contactHandler.UpdateContact)
+			contacts.DELETE("/:id", contactHandler.DeleteContact)
+		}
+
+		// Contact group endpoints (require API key authentication)
+		groups := v1.Group("/groups")
+		groups.Use(middleware.APIKeyAuth())
+		{
+			groups.POST("", groupHandler.CreateGroup)
+			groups.GET("", groupHandler.ListGroups)
+			groups.GET("/:id", groupHandler.GetGroup)
+			groups.PUT("/:id", groupHandler.UpdateGroup)
+			groups.DELETE("/:id", groupHandler.DeleteGroup)
+			groups.GET("/:id/contacts", groupHandler.ListGroupContacts)
+			groups.POST("/:id/contacts", groupHandler.AddGroupContacts)
+			groups.DELETE("/:id/contacts", groupHandler.RemoveGroupContacts)
+			groups.POST("/:id/send", groupHandler.SendToGroup)
+			groups.GET("/:id/export", groupHandler.ExportGroupContacts)
+		}
+
+		// Suppression list endpoints (require API key authentication)
+		suppressions := v1.Group("/suppressions")
+		suppressions.Use(middleware.APIKeyAuth())
+		{
+			suppressions.GET("", suppressionHandler.ListSuppressions)
+			suppressions.POST("", suppressionHandler.AddSuppressions)
+			suppressions.DELETE("/:phone", suppressionHandler.RemoveSuppression)
+		}
+
+		// Campaign endpoints (require API key authentication)
+		campaigns := v1.Group("/campaigns")
+		campaigns.Use(middleware.APIKeyAuth())
+		{
+			campaigns.POST("", campaignHandler.CreateCampaign)
+			campaigns.GET("", campaignHandler.ListCampaigns)
+			campaigns.GET("/:id", campaignHandler.GetCampaign)
+			campaigns.PUT("/:id", campaignHandler.UpdateCampaign)
+			campaigns.DELETE("/:id", campaignHandler.DeleteCampaign)
+			campaigns.POST("/:id/schedule", campaignHandler.ScheduleCampaign)
+			campaigns.POST("/:id/pause", campaignHandler.PauseCampaign)
+			campaigns.POST("/:id/resume", campaignHandler.ResumeCampaign)
+			campaigns.POST("/:id/cancel", campaignHandler.CancelCampaign)
+			campaigns.GET("/:id/stats", campaignHandler.GetCampaignStats)
+		}
+
+		// Inbound message endpoints (require API key authentication)
+		inbound := v1.Group("/inbound-messages")
+		inbound.Use(middleware.APIKeyAuth())
+		{
+			inbound.GET("", inboundHandler.ListInboundMessages)
+			inbound.GET("/:id", inboundHandler.GetInboundMessage)
+		}
+
+		// Conversation endpoints (require API key authentication)
+		conversations := v1.Group("/conversations")
+		conversations.Use(middleware.APIKeyAuth())
+		{
+			conversations.GET("", conversationHandler.ListConversations)
+			conversations.GET("/:phone", conversationHandler.GetConversation)
+			conversations.POST("/:phone/read", conversationHandler.MarkConversationRead)
+		}
+
+		// Keyword rule endpoints (require API key authentication)
+		keywordRules := v1.Group("/keyword-rules")
+		keywordRules.Use(middleware.APIKeyAuth())
+		{
+			keywordRules.POST("", keywordRuleHandler.CreateKeywordRule)
+			keywordRules.GET("", keywordRuleHandler.ListKeywordRules)
+			keywordRules.POST("/test", keywordRuleHandler.TestKeywordRules)
+			keywordRules.GET("/:id", keywordRuleHandler.GetKeywordRule)
+			keywordRules.PUT("/:id", keywordRuleHandler.UpdateKeywordRule)
+			keywordRules.DELETE("/:id", keywordRuleHandler.DeleteKeywordRule)
+		}
+
+		// Provider webhooks (authenticated with INBOUND_WEBHOOK_TOKEN)
+		v1.POST("/webhooks/inbound/:provider", inboundHandler.ReceiveInbound)
+
+		// Number lookup endpoints (require API key authentication)
+		numbers := v1.Group("/numbers")
+		numbers.Use(middleware.APIKeyAuth())
+		{
+			numbers.POST("/validate", numberHandler.ValidateNumbers)
 		}
 
 		// Admin endpoints (require Basic Auth)
@@ -85,6 +284,46 @@ func main() {
 			admin.GET("/clients", clientHandler.ListClients)
 			admin.PUT("/clients/:id", clientHandler.UpdateClient)
 			admin.POST("/clients/:id/reset", clientHandler.ResetClientUsage)
+			admin.GET("/clients/:id/wallet", walletHandler.AdminGetWallet)
+			admin.GET("/clients/:id/wallet/transactions", walletHandler.AdminListTransactions)
+			admin.POST("/clients/:id/wallet/topup", walletHandler.TopUpWallet)
+			admin.POST("/clients/:id/wallet/adjust", walletHandler.AdjustWallet)
+			admin.GET("/clients/:id/statements/:period", statementHandler.AdminGetStatement)
+			admin.POST("/clients/:id/statements/:period/finalize", statementHandler.FinalizeStatement)
+			admin.GET("/statements", statementHandler.AdminListStatements)
+			admin.GET("/reports/reconciliation", reportHandler.Reconciliation)
+			admin.GET("/reports/reconciliation/losses", reportHandler.ListLossMessages)
+			admin.GET("/sender-ids", senderIDHandler.AdminListSenderIDs)
+			admin.POST("/sender-ids/:id/approve", senderIDHandler.ApproveSenderID)
+			admin.POST("/sender-ids/:id/reject", senderIDHandler.RejectSenderID)
+			admin.GET("/content/rules", contentRuleHandler.ListContentRules)
+			admin.POST("/content/rules", contentRuleHandler.CreateContentRule)
+			admin.PUT("/content/rules/:id", contentRuleHandler.UpdateContentRule)
+			admin.DELETE("/content/rules/:id", contentRuleHandler.DeleteContentRule)
+			admin.GET("/content/messages", contentRuleHandler.ListPolicyMessages)
+			admin.GET("/provider/balance", providerHandler.GetBalance)
+			admin.POST("/provider/balance/check", providerHandler.CheckBalance)
+			admin.GET("/provider/lanes", providerHandler.GetLanes)
+			admin.GET("/prices", walletHandler.ListPrices)
+			admin.POST("/prices", walletHandler.CreatePriceRule)
+			admin.PUT("/prices/:id", walletHandler.UpdatePriceRule)
+			admin.DELETE("/prices/:id", walletHandler.DeletePriceRule)
+			admin.GET("/suppressions", suppressionHandler.AdminListSuppressions)
+			admin.POST("/suppressions", suppressionHandler.AdminAddSuppressions)
+			admin.DELETE("/suppressions/:id", suppressionHandler.AdminRemoveSuppression)
+			admin.GET("/operators", operatorHandler.ListOperatorPrefixes)
+			admin.POST("/operators/reload", operatorHandler.ReloadOperatorPrefixes)
+			admin.GET("/inbound-routes", inboundHandler.ListInboundRoutes)
+			admin.POST("/inbound-routes", inboundHandler.CreateInboundRoute)
+			admin.DELETE("/inbound-routes/:id", inboundHandler.DeleteInboundRoute)
+			admin.GET("/inbound-messages", inboundHandler.AdminListInboundMessages)
+			admin.POST("/inbound/simulate", inboundHandler.SimulateInbound)
+			admin.GET("/fraud/messages", fraudHandler.ListFlaggedMessages)
+			admin.POST("/fraud/messages/:id/release", fraudHandler.ReleaseFlaggedMessage)
+			admin.POST("/fraud/messages/:id/block", fraudHandler.BlockFlaggedMessage)
+			admin.GET("/fraud/whitelist", fraudHandler.ListWhitelist)
+			admin.POST("/fraud/whitelist", fraudHandler.AddWhitelistEntry)
+			admin.DELETE("/fraud/whitelist/:id", fraudHandler.RemoveWhitelistEntry)
 		}
 	}
 
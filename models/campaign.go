@@ -0,0 +1,79 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Campaign statuses. A campaign moves draft -> scheduled -> running -> completed,
+// and can be paused while scheduled or running, or cancelled before it completes.
+const (
+	CampaignDraft     = "draft"
+	CampaignScheduled = "scheduled"
+	CampaignRunning   = "running"
+	CampaignPaused    = "paused"
+	CampaignCompleted = "completed"
+	CampaignCancelled = "cancelled"
+)
+
+// Campaign is a one-off send to a contact group and/or an uploaded recipient list.
+// When it starts, one SMSLog per recipient is queued and then sent at Rate messages per second.
+type Campaign struct {
+	ID        uuid.UUID      `gorm:"type:uuid;primary_key" json:"id"`
+	CreatedAt time.Time      `json:"created_at"`
+	UpdatedAt time.Time      `json:"updated_at"`
+	DeletedAt gorm.DeletedAt `gorm:"index" json:"-"`
+
+	ClientID uuid.UUID `gorm:"type:uuid;not null;index" json:"client_id"`
+	Name     string    `gorm:"not null" json:"name"`
+
+	// Message details. Message is a template, see utils.RenderTemplate
+	Message  string `gorm:"not null" json:"message"`
+	SenderID string `json:"sender_id"`
+	Priority string `json:"priority"`
+
+	// Replace links in the message with short links that record clicks
+	ShortenLinks bool `json:"shorten_links"`
+
+	// Target: a contact group and/or a list of recipients, expanded when the campaign starts
+	GroupID    *uuid.UUID `gorm:"type:uuid;index" json:"group_id,omitempty"`
+	Recipients StringList `gorm:"type:text" json:"recipients"`
+
+	// Schedule. The campaign starts at ScheduledAt, or as soon as it is scheduled if unset.
+	ScheduledAt *time.Time `gorm:"index" json:"scheduled_at,omitempty"`
+	Rate        int        `json:"rate"` // Messages per second, 0 for the gateway default
+
+	// State
+	Status      string     `gorm:"not null;index" json:"status"` // "draft", "scheduled", "running", "paused", "completed", "cancelled"
+	StartedAt   *time.Time `json:"started_at,omitempty"`
+	CompletedAt *time.Time `json:"completed_at,omitempty"`
+	Total       int        `gorm:"default:0" json:"total"` // Messages created when the campaign started
+	LastError   string     `json:"last_error,omitempty"`
+}
+
+// BeforeCreate hook to generate UUID before creating
+func (campaign *Campaign) BeforeCreate(tx *gorm.DB) error {
+	if campaign.ID == uuid.Nil {
+		campaign.ID = uuid.New()
+	}
+	return nil
+}
+
+// CampaignStats summarizes the messages of a campaign by status
+type CampaignStats struct {
+	CampaignID uuid.UUID `json:"campaign_id"`
+	Status     string    `json:"status"`
+	Total      int64     `json:"total"`
+	Queued     int64     `json:"queued"`
+	Deferred   int64     `json:"deferred"` // Held by quiet hours or for fraud review, sent later
+	Sent       int64     `json:"sent"`
+	Failed     int64     `json:"failed"`
+	Unknown    int64     `json:"unknown"`
+	Suppressed int64     `json:"suppressed"`
+	Rejected   int64     `json:"rejected"`
+	Blocked    int64     `json:"blocked"` // Blocked by the fraud guard
+	Cancelled  int64     `json:"cancelled"`
+	Progress   float64   `json:"progress"` // Percentage of messages no longer queued or deferred
+}
@@ -0,0 +1,58 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Contact is a recipient saved by a client. Phone numbers are stored normalized
+// and are unique per client.
+type Contact struct {
+	ID        uuid.UUID `gorm:"type:uuid;primary_key" json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+
+	ClientID   uuid.UUID `gorm:"type:uuid;not null;uniqueIndex:idx_contacts_client_phone" json:"client_id"`
+	Phone      string    `gorm:"not null;uniqueIndex:idx_contacts_client_phone" json:"phone"`
+	Name       string    `json:"name"`
+	Attributes StringMap `gorm:"type:text" json:"attributes"`
+}
+
+// BeforeCreate hook to generate UUID before creating
+func (contact *Contact) BeforeCreate(tx *gorm.DB) error {
+	if contact.ID == uuid.Nil {
+		contact.ID = uuid.New()
+	}
+	return nil
+}
+
+// Group is a named list of contacts belonging to a client
+type Group struct {
+	ID        uuid.UUID `gorm:"type:uuid;primary_key" json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+
+	ClientID    uuid.UUID `gorm:"type:uuid;not null;index" json:"client_id"`
+	Name        string    `gorm:"not null" json:"name"`
+	Description string    `json:"description"`
+
+	// Number of contacts in the group, filled in by the handlers
+	ContactCount int64 `gorm:"-" json:"contact_count"`
+}
+
+// BeforeCreate hook to generate UUID before creating
+func (group *Group) BeforeCreate(tx *gorm.DB) error {
+	if group.ID == uuid.Nil {
+		group.ID = uuid.New()
+	}
+	return nil
+}
+
+// GroupMember links a contact to a group
+type GroupMember struct {
+	GroupID   uuid.UUID `gorm:"type:uuid;primaryKey" json:"group_id"`
+	ContactID uuid.UUID `gorm:"type:uuid;primaryKey;index" json:"contact_id"`
+	CreatedAt time.Time `json:"created_at"`
+}
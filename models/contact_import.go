@@ -0,0 +1,66 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Contact import statuses
+const (
+	ImportQueued     = "queued"
+	ImportProcessing = "processing"
+	ImportCompleted  = "completed"
+	ImportFailed     = "failed"
+)
+
+// ContactImport tracks a CSV contact import processed in the background
+type ContactImport struct {
+	ID        uuid.UUID `gorm:"type:uuid;primary_key" json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+
+	ClientID uuid.UUID  `gorm:"type:uuid;not null;index" json:"client_id"`
+	FileName string     `json:"file_name"`
+	GroupID  *uuid.UUID `gorm:"type:uuid" json:"group_id,omitempty"` // Imported contacts are added to this group
+
+	// Column mapping (CSV header names)
+	PhoneColumn      string     `json:"phone_column"`
+	NameColumn       string     `json:"name_column"`
+	AttributeColumns StringList `gorm:"type:text" json:"attribute_columns"`
+	UpdateExisting   bool       `json:"update_existing"` // Overwrite name/attributes of existing contacts
+
+	// Progress
+	Status        string     `gorm:"not null;index" json:"status"` // "queued", "processing", "completed", "failed"
+	TotalRows     int        `json:"total_rows"`
+	ProcessedRows int        `json:"processed_rows"`
+	Imported      int        `json:"imported"`   // New contacts created
+	Updated       int        `json:"updated"`    // Existing contacts updated
+	Duplicates    int        `json:"duplicates"` // Rows matching an existing contact or an earlier row
+	Failed        int        `json:"failed"`     // Rows rejected, see ContactImportError
+	Error         string     `json:"error,omitempty"`
+	StartedAt     *time.Time `json:"started_at"`
+	FinishedAt    *time.Time `json:"finished_at"`
+
+	// Refreshed with every batch the import writes. An import whose heartbeat stops, because the
+	// server running it went away, is failed by any running server.
+	HeartbeatAt *time.Time `gorm:"index" json:"-"`
+}
+
+// BeforeCreate hook to generate UUID before creating
+func (imp *ContactImport) BeforeCreate(tx *gorm.DB) error {
+	if imp.ID == uuid.Nil {
+		imp.ID = uuid.New()
+	}
+	return nil
+}
+
+// ContactImportError records a CSV row rejected during an import
+type ContactImportError struct {
+	ID       uint      `gorm:"primaryKey" json:"-"`
+	ImportID uuid.UUID `gorm:"type:uuid;not null;index" json:"import_id"`
+	Row      int       `json:"row"` // 1-based line number in the file, including the header
+	Phone    string    `json:"phone"`
+	Reason   string    `json:"reason"`
+}
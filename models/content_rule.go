@@ -0,0 +1,45 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Content rule types
+const (
+	ContentRuleBannedWords  = "banned_words"   // Message contains one of Values (words or phrases, ignoring case)
+	ContentRuleDomainAllow  = "domain_allow"   // Message links to a domain outside Values
+	ContentRuleDomainDeny   = "domain_deny"    // Message links to one of Values, or a subdomain of one
+	ContentRuleMaxLength    = "max_length"     // Message is longer than MaxLength characters
+	ContentRuleOptOutFooter = "opt_out_footer" // Marketing message contains none of Values (e.g. "Reply STOP")
+)
+
+// What happens to messages breaking a content rule, set per client
+const (
+	ContentPolicyReject = "reject" // The request is refused, or the message logged as rejected
+	ContentPolicyFlag   = "flag"   // The message is sent and its log entry flagged
+)
+
+// ContentRule is a check applied to outbound message text before it is sent.
+// Rules without a ClientID apply to every client.
+type ContentRule struct {
+	ID        uuid.UUID `gorm:"type:uuid;primary_key" json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+
+	ClientID  *uuid.UUID `gorm:"type:uuid;index" json:"client_id"` // nil for global rules
+	Name      string     `gorm:"not null" json:"name"`
+	Type      string     `gorm:"not null" json:"type"`
+	Values    StringList `gorm:"type:text" json:"values,omitempty"`
+	MaxLength int        `json:"max_length,omitempty"`
+}
+
+// BeforeCreate hook to generate UUID before creating
+func (r *ContentRule) BeforeCreate(tx *gorm.DB) error {
+	if r.ID == uuid.Nil {
+		r.ID = uuid.New()
+	}
+	return nil
+}
@@ -0,0 +1,38 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Conversation message directions
+const (
+	DirectionOutbound = "outbound"
+	DirectionInbound  = "inbound"
+)
+
+// Conversation summarizes the exchange between a client and one counterpart number,
+// combining sent messages (SMSLog) and received messages (InboundMessage)
+type Conversation struct {
+	Phone         string `json:"phone"`
+	ContactName   string `gorm:"-" json:"contact_name,omitempty"`
+	MessageCount  int64  `json:"message_count"`
+	OutboundCount int64  `json:"outbound_count"`
+	InboundCount  int64  `json:"inbound_count"`
+	UnreadCount   int64  `json:"unread_count"` // Inbound messages not yet marked read
+
+	LastMessage *ConversationMessage `gorm:"-" json:"last_message,omitempty"`
+}
+
+// ConversationMessage is one message in a conversation, sent or received
+type ConversationMessage struct {
+	ID        uuid.UUID  `json:"id"`
+	Direction string     `json:"direction"` // "outbound" or "inbound"
+	Phone     string     `json:"phone"`     // Counterpart number
+	Text      string     `json:"text"`
+	Status    string     `json:"status"` // SMSLog status for outbound, InboundMessage status for inbound
+	SenderID  string     `json:"sender_id,omitempty"`
+	At        time.Time  `json:"at"` // Sent (logged) or received time
+	ReadAt    *time.Time `json:"read_at,omitempty"`
+}
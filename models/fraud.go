@@ -0,0 +1,27 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// FraudWhitelist exempts recipients from the fraud guard. Prefix is an E.164 prefix
+// (e.g. "+25677") or a full number. Entries without a ClientID apply to every client.
+type FraudWhitelist struct {
+	ID        uuid.UUID `gorm:"type:uuid;primary_key" json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+
+	ClientID *uuid.UUID `gorm:"type:uuid;index" json:"client_id"` // nil for global entries
+	Prefix   string     `gorm:"not null" json:"prefix"`
+	Reason   string     `json:"reason"`
+}
+
+// BeforeCreate hook to generate UUID before creating
+func (w *FraudWhitelist) BeforeCreate(tx *gorm.DB) error {
+	if w.ID == uuid.Nil {
+		w.ID = uuid.New()
+	}
+	return nil
+}
@@ -0,0 +1,82 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Inbound message statuses
+const (
+	InboundStatusReceived      = "received"       // Routed to a client without a webhook
+	InboundStatusPending       = "pending"        // Waiting to be forwarded to the client's webhook
+	InboundStatusForwarded     = "forwarded"      // Delivered to the client's webhook
+	InboundStatusForwardFailed = "forward_failed" // The client's webhook kept failing and delivery was abandoned
+	InboundStatusUnrouted      = "unrouted"       // No client owns the destination number and keyword
+)
+
+// InboundRoute assigns a shortcode or long number to a client. Routes with a keyword only
+// match messages whose first word is that keyword, so a shared shortcode can be split
+// between clients; a route without a keyword matches everything else sent to the number.
+type InboundRoute struct {
+	ID        uuid.UUID `gorm:"type:uuid;primary_key" json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+
+	ClientID uuid.UUID `gorm:"type:uuid;not null;index" json:"client_id"`
+	Number   string    `gorm:"not null;uniqueIndex:idx_inbound_routes_number_keyword" json:"number"` // Shortcode digits or E.164 long number
+	Keyword  string    `gorm:"uniqueIndex:idx_inbound_routes_number_keyword" json:"keyword"`         // Upper case, empty for the number's default route
+}
+
+// BeforeCreate hook to generate UUID before creating
+func (route *InboundRoute) BeforeCreate(tx *gorm.DB) error {
+	if route.ID == uuid.Nil {
+		route.ID = uuid.New()
+	}
+	return nil
+}
+
+// InboundMessage is a message received from a handset through a provider webhook
+type InboundMessage struct {
+	ID        uuid.UUID `gorm:"type:uuid;primary_key" json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+
+	// Owning client and the route that matched, nil when the message could not be routed
+	ClientID *uuid.UUID `gorm:"type:uuid;index" json:"client_id,omitempty"`
+	RouteID  *uuid.UUID `gorm:"type:uuid" json:"route_id,omitempty"`
+
+	// Message details
+	From       string    `gorm:"not null;index" json:"from"` // Sender's number, normalized when possible
+	To         string    `gorm:"not null;index" json:"to"`   // Shortcode or long number the message was sent to
+	Text       string    `gorm:"type:text" json:"text"`
+	Keyword    string    `json:"keyword"` // First word of the text, upper case
+	ReceivedAt time.Time `gorm:"index" json:"received_at"`
+
+	// Provider details. A provider message ID is stored once per provider, so redeliveries
+	// of the same message are detected even when they arrive at the same time.
+	Provider          string `gorm:"not null;uniqueIndex:idx_inbound_messages_provider_message,where:provider_message_id <> ''" json:"provider"`
+	ProviderMessageID string `gorm:"uniqueIndex:idx_inbound_messages_provider_message,where:provider_message_id <> ''" json:"provider_message_id,omitempty"`
+
+	// Forwarding to the client's webhook
+	Status          string     `gorm:"not null;index" json:"status"` // "received", "pending", "forwarded", "forward_failed", "unrouted"
+	ForwardAttempts int        `gorm:"default:0" json:"forward_attempts"`
+	NextAttemptAt   *time.Time `gorm:"index" json:"-"`
+	ForwardedAt     *time.Time `json:"forwarded_at,omitempty"`
+	ForwardError    string     `json:"forward_error,omitempty"`
+
+	// Set when the client marks the conversation with the sender as read
+	ReadAt *time.Time `json:"read_at,omitempty"`
+
+	// Actions run by the client's keyword rules, loaded on request
+	RuleHits []InboundRuleHit `gorm:"foreignKey:InboundMessageID" json:"rule_hits,omitempty"`
+}
+
+// BeforeCreate hook to generate UUID before creating
+func (msg *InboundMessage) BeforeCreate(tx *gorm.DB) error {
+	if msg.ID == uuid.Nil {
+		msg.ID = uuid.New()
+	}
+	return nil
+}
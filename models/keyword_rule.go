@@ -0,0 +1,123 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Keyword rule match types
+const (
+	KeywordMatchExact  = "exact"  // The whole text equals the pattern, ignoring case and surrounding space
+	KeywordMatchPrefix = "prefix" // The text starts with the pattern, ignoring case
+	KeywordMatchRegex  = "regex"  // The text matches the regular expression
+)
+
+// Keyword rule actions
+const (
+	RuleActionReply           = "reply"             // Send Message to the sender
+	RuleActionAddToGroup      = "add_to_group"      // Add the sender to GroupID, creating a contact if needed
+	RuleActionRemoveFromGroup = "remove_from_group" // Remove the sender from GroupID
+	RuleActionSuppress        = "suppress"          // Add the sender to the client's suppression list
+	RuleActionForward         = "forward"           // POST the message to URL, or to the client's webhook
+)
+
+// Rule hit statuses
+const (
+	RuleHitSucceeded = "succeeded"
+	RuleHitFailed    = "failed"
+)
+
+// RuleAction is one step run when a keyword rule matches
+type RuleAction struct {
+	Type     string     `json:"type"`
+	Message  string     `json:"message,omitempty"` // Reply template, see utils.RenderTemplate
+	SenderID string     `json:"senderid,omitempty"`
+	GroupID  *uuid.UUID `json:"group_id,omitempty"`
+	URL      string     `json:"url,omitempty"`
+}
+
+// RuleActions is a list of rule actions stored as a JSON array in a text column
+type RuleActions []RuleAction
+
+// Value implements driver.Valuer
+func (a RuleActions) Value() (driver.Value, error) {
+	if a == nil {
+		return "[]", nil
+	}
+	data, err := json.Marshal(a)
+	if err != nil {
+		return nil, err
+	}
+	return string(data), nil
+}
+
+// Scan implements sql.Scanner
+func (a *RuleActions) Scan(value interface{}) error {
+	data, err := jsonColumnBytes(value)
+	if err != nil {
+		return err
+	}
+	if len(data) == 0 {
+		*a = nil
+		return nil
+	}
+	return json.Unmarshal(data, a)
+}
+
+// KeywordRule runs actions for inbound messages whose text matches its pattern. A client's
+// active rules are tried in Position order and only the first matching rule runs.
+type KeywordRule struct {
+	ID        uuid.UUID      `gorm:"type:uuid;primary_key" json:"id"`
+	CreatedAt time.Time      `json:"created_at"`
+	UpdatedAt time.Time      `json:"updated_at"`
+	DeletedAt gorm.DeletedAt `gorm:"index" json:"-"`
+
+	ClientID uuid.UUID `gorm:"type:uuid;not null;index" json:"client_id"`
+	Name     string    `gorm:"not null" json:"name"`
+	IsActive bool      `gorm:"not null" json:"is_active"`
+	Position int       `gorm:"default:0" json:"position"` // Lower positions are tried first
+
+	// Matching
+	MatchType string `gorm:"not null" json:"match_type"` // "exact", "prefix", "regex"
+	Pattern   string `gorm:"not null" json:"pattern"`
+
+	Actions RuleActions `gorm:"type:text" json:"actions"`
+
+	// Usage
+	HitCount  int        `gorm:"default:0" json:"hit_count"`
+	LastHitAt *time.Time `json:"last_hit_at,omitempty"`
+}
+
+// BeforeCreate hook to generate UUID before creating
+func (rule *KeywordRule) BeforeCreate(tx *gorm.DB) error {
+	if rule.ID == uuid.Nil {
+		rule.ID = uuid.New()
+	}
+	return nil
+}
+
+// InboundRuleHit records an action run by a keyword rule for an inbound message
+type InboundRuleHit struct {
+	ID        uuid.UUID `gorm:"type:uuid;primary_key" json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+
+	InboundMessageID uuid.UUID `gorm:"type:uuid;not null;index" json:"inbound_message_id"`
+	RuleID           uuid.UUID `gorm:"type:uuid;not null;index" json:"rule_id"`
+	RuleName         string    `json:"rule_name"`
+	Action           string    `gorm:"not null" json:"action"`
+	Status           string    `gorm:"not null" json:"status"` // "succeeded", "failed"
+	Result           string    `json:"result,omitempty"`       // What the action did, e.g. the reply's log ID
+	Error            string    `json:"error,omitempty"`
+}
+
+// BeforeCreate hook to generate UUID before creating
+func (hit *InboundRuleHit) BeforeCreate(tx *gorm.DB) error {
+	if hit.ID == uuid.Nil {
+		hit.ID = uuid.New()
+	}
+	return nil
+}
@@ -31,6 +31,26 @@ type APIClient struct {
 	DailyUsage   int       `gorm:"default:0" json:"daily_usage"`
 	MonthlyUsage int       `gorm:"default:0" json:"monthly_usage"`
 	LastReset    time.Time `json:"last_reset"`
+
+	// Quiet hours ("HH:MM", recipient's local time). Non-transactional messages to
+	// recipients currently in quiet hours are deferred. Empty disables the rule.
+	QuietHoursStart string `json:"quiet_hours_start"`
+	QuietHoursEnd   string `json:"quiet_hours_end"`
+
+	// Region (ISO 3166-1 alpha-2) used to read phone numbers without a country code.
+	// Empty uses the gateway's DEFAULT_PHONE_REGION.
+	DefaultRegion string `json:"default_region"`
+
+	// Inbound messages routed to the client are POSTed to WebhookURL, signed with
+	// WebhookSecret when set. Empty disables forwarding.
+	WebhookURL    string `json:"webhook_url"`
+	WebhookSecret string `json:"-"`
+
+	// Message template for verification codes, must contain {{code}}. Empty uses the default.
+	OTPTemplate string `json:"otp_template"`
+
+	// What happens to messages breaking a content rule: "reject" (the default) or "flag"
+	ContentPolicyMode string `json:"content_policy_mode"`
 }
 
 // BeforeCreate hook to generate UUID before creating
@@ -44,6 +64,34 @@ func (client *APIClient) BeforeCreate(tx *gorm.DB) error {
 	return nil
 }
 
+// SMS log statuses
+const (
+	SMSStatusPending    = "pending"
+	SMSStatusSent       = "sent"
+	SMSStatusFailed     = "failed"
+	SMSStatusSuppressed = "suppressed" // Recipient is on a suppression list, nothing was sent
+	SMSStatusDeferred   = "deferred"   // Held until ScheduledAt, e.g. because of quiet hours
+	SMSStatusRejected   = "rejected"   // Invalid recipient, nothing was sent
+	SMSStatusUnknown    = "unknown"    // Accepted by the provider, but its outcome was not reported
+	SMSStatusQueued     = "queued"     // Waiting to be sent by a running campaign
+	SMSStatusCancelled  = "cancelled"  // Queued or deferred by a campaign that was cancelled, nothing was sent
+	SMSStatusBlocked    = "blocked"    // Stopped by the fraud guard, nothing was sent
+)
+
+// Message priorities, passed through to the provider
+const (
+	PriorityTransactional = "0" // Transactional traffic (e.g. OTPs), bypasses quiet hours
+	PriorityDefault       = "1"
+)
+
+// Priority lanes, in the order they are drained. Transactional messages (priority "0") use the
+// transactional lane, campaigns and bulk sends the bulk lane, and everything else the standard lane.
+const (
+	LaneTransactional = "transactional"
+	LaneStandard      = "standard"
+	LaneBulk          = "bulk"
+)
+
 // SMSLog represents a log entry for each SMS sent
 type SMSLog struct {
 	ID        uuid.UUID `gorm:"type:uuid;primary_key" json:"id"`
@@ -54,17 +102,54 @@ type SMSLog struct {
 	Client   APIClient `gorm:"foreignKey:ClientID" json:"client,omitempty"`
 
 	// SMS details
-	Recipient  string `gorm:"not null" json:"recipient"`
+	Recipient  string `gorm:"not null;index" json:"recipient"`
 	Message    string `gorm:"not null" json:"message"`
 	SenderID   string `json:"sender_id"`
 	Priority   string `gorm:"default:1" json:"priority"`
+	Lane       string `gorm:"index" json:"lane,omitempty"` // Priority lane the message is sent through
+
+	// Billing. Price is the amount charged to the client's wallet, 0 when billing is off.
+	Segments int   `gorm:"default:1" json:"segments"`
+	Price    int64 `gorm:"default:0" json:"price"`
+
+	// Destination network, detected from the recipient's number prefix
+	Operator string `gorm:"index" json:"operator,omitempty"`
+	LineType string `json:"line_type,omitempty"` // "mobile" or "fixed"
 
 	// Status
-	Status     string `gorm:"not null" json:"status"` // "pending", "sent", "failed"
+	Status     string `gorm:"not null;index" json:"status"` // "pending", "sent", "failed", "suppressed", "deferred", "rejected", "unknown", "queued", "cancelled", "blocked"
 	ProviderStatus string `json:"provider_status"`    // Status from SMS provider
 	ProviderMessage string `json:"provider_message"`  // Message from SMS provider
+	ProviderMessageID string `gorm:"index" json:"provider_message_id,omitempty"` // Provider reference for the message
+	Provider     string   `gorm:"index" json:"provider,omitempty"`      // Provider the message was handed to
+	ProviderCost *float64 `json:"provider_cost,omitempty"`              // What the provider charged, when it reports it, in the same unit as Price
 	Error      string `json:"error,omitempty"`
 
+	// Fraud guard result. Messages scoring high enough are blocked or deferred for review,
+	// lower scores are sent and flagged.
+	FraudScore   int    `json:"fraud_score,omitempty"`
+	FraudReason  string `json:"fraud_reason,omitempty"`
+	FraudFlagged bool   `gorm:"index" json:"fraud_flagged,omitempty"`
+
+	// Content rules the message broke (rule IDs). Flagged messages were sent anyway
+	// because the client's content policy mode is "flag".
+	PolicyViolations StringList `gorm:"type:text" json:"policy_violations,omitempty"`
+	PolicyFlagged    bool       `gorm:"index" json:"policy_flagged,omitempty"`
+
+	// Deferred messages are sent once ScheduledAt has passed
+	ScheduledAt *time.Time `gorm:"index" json:"scheduled_at,omitempty"`
+
+	// When the message was handed to the provider (status "sent" or "unknown")
+	SentAt *time.Time `gorm:"index" json:"sent_at,omitempty"`
+
+	// Text sent to the provider for a one-time code, never stored: Message holds it with the code masked
+	OTPText string `gorm:"-" json:"-"`
+
+	// Origin (set when the message was sent by a recurring job or campaign)
+	RecurringJobID *uuid.UUID `gorm:"type:uuid;index" json:"recurring_job_id,omitempty"`
+	RecurringRunID *uuid.UUID `gorm:"type:uuid;index" json:"recurring_run_id,omitempty"`
+	CampaignID     *uuid.UUID `gorm:"type:uuid;index" json:"campaign_id,omitempty"`
+
 	// Metadata
 	IPAddress  string `json:"ip_address"`
 	UserAgent  string `json:"user_agent"`
@@ -84,11 +169,39 @@ type SMSRequest struct {
 	Message  string `json:"message" binding:"required"`
 	SenderID string `json:"senderid,omitempty"`
 	Priority string `json:"priority,omitempty"`
+
+	// Overrides the client's quiet hours for this message
+	QuietHours *QuietHours `json:"quiet_hours,omitempty"`
+
+	// Marketing messages must carry an opt-out footer when a content rule requires one.
+	// Campaign messages are always marketing.
+	Marketing bool `json:"marketing,omitempty"`
+
+	// Replace links in the message with gateway short links that record clicks
+	ShortenLinks bool `json:"shorten_links,omitempty"`
+
+	// One-time code contained in Message, set by verifications. The code is masked in the
+	// message log and the message is never held back for later.
+	OTPCode string `json:"-"`
+}
+
+// QuietHours is a daily window ("HH:MM") in the recipient's local time during which
+// non-transactional messages are deferred. Equal start and end disable the window.
+type QuietHours struct {
+	Start string `json:"start"`
+	End   string `json:"end"`
 }
 
 // BulkSMSRequest represents multiple SMS requests
 type BulkSMSRequest struct {
 	Messages []SMSRequest `json:"messages" binding:"required,min=1,dive"`
+
+	// Partial sends the valid messages and logs invalid recipients as rejected
+	// instead of failing the whole request
+	Partial bool `json:"partial"`
+
+	// CollapseDuplicates sends only the first message to each recipient in the batch
+	CollapseDuplicates bool `json:"collapse_duplicates"`
 }
 
 // SMSResponse represents the API response
@@ -107,5 +220,16 @@ type ClientStats struct {
 	DailyLimit    int       `json:"daily_limit"`
 	MonthlyLimit  int       `json:"monthly_limit"`
 	IsActive      bool      `json:"is_active"`
+
+	// Month-to-date traffic per destination network
+	Networks []NetworkStats `json:"networks"`
+}
+
+// NetworkStats counts a client's messages to one network ("unknown" if the prefix is not recognized)
+type NetworkStats struct {
+	Operator string `json:"operator"`
+	Total    int64  `json:"total"`
+	Sent     int64  `json:"sent"`
+	Failed   int64  `json:"failed"`
 }
 
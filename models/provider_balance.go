@@ -0,0 +1,29 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// ProviderBalance is one check of the credit left on the SMS provider account.
+// Failed checks are kept too, with the error instead of a balance.
+type ProviderBalance struct {
+	ID        uuid.UUID `gorm:"type:uuid;primary_key" json:"id"`
+	CreatedAt time.Time `gorm:"index" json:"created_at"`
+
+	Provider  string   `gorm:"not null;index" json:"provider"`
+	Balance   *float64 `json:"balance"` // nil if the check failed
+	Error     string   `json:"error,omitempty"`
+	Low       bool     `json:"low"`        // Balance is below PROVIDER_BALANCE_LOW_THRESHOLD
+	AlertSent bool     `json:"alert_sent"` // A low-balance alert was delivered since the balance went low
+}
+
+// BeforeCreate hook to generate UUID before creating
+func (b *ProviderBalance) BeforeCreate(tx *gorm.DB) error {
+	if b.ID == uuid.Nil {
+		b.ID = uuid.New()
+	}
+	return nil
+}
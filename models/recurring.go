@@ -0,0 +1,95 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Recurring job schedule types
+const (
+	ScheduleCron   = "cron"
+	ScheduleDaily  = "daily"
+	ScheduleWeekly = "weekly"
+)
+
+// Recurring job statuses
+const (
+	RecurringJobActive = "active"
+	RecurringJobPaused = "paused"
+)
+
+// Recurring job run statuses
+const (
+	RecurringRunRunning   = "running"
+	RecurringRunCompleted = "completed"
+	RecurringRunFailed    = "failed"
+)
+
+// RecurringJob sends a message template to a contact group or recipient list on a repeating schedule
+type RecurringJob struct {
+	ID        uuid.UUID      `gorm:"type:uuid;primary_key" json:"id"`
+	CreatedAt time.Time      `json:"created_at"`
+	UpdatedAt time.Time      `json:"updated_at"`
+	DeletedAt gorm.DeletedAt `gorm:"index" json:"-"`
+
+	ClientID uuid.UUID `gorm:"type:uuid;not null;index" json:"client_id"`
+	Name     string    `gorm:"not null" json:"name"`
+
+	// Message details. Message is a template, see utils.RenderTemplate
+	Message  string `gorm:"not null" json:"message"`
+	SenderID string `json:"sender_id"`
+	Priority string `json:"priority"`
+
+	// Target: a contact group (expanded at run time) and/or a fixed list of recipients
+	GroupID    *uuid.UUID `gorm:"type:uuid;index" json:"group_id,omitempty"`
+	Recipients StringList `gorm:"type:text" json:"recipients"`
+
+	// Schedule. Cron jobs use CronExpression, daily and weekly jobs use TimeOfDay ("HH:MM")
+	// and, for weekly jobs, Weekdays ("mon".."sun"). All times are evaluated in Timezone.
+	ScheduleType   string     `gorm:"not null" json:"schedule_type"`
+	CronExpression string     `json:"cron_expression,omitempty"`
+	TimeOfDay      string     `json:"time_of_day,omitempty"`
+	Weekdays       StringList `gorm:"type:text" json:"weekdays,omitempty"`
+	Timezone       string     `gorm:"default:UTC" json:"timezone"`
+
+	// State
+	Status    string     `gorm:"not null;index" json:"status"` // "active", "paused"
+	NextRunAt *time.Time `gorm:"index" json:"next_run_at"`
+	LastRunAt *time.Time `json:"last_run_at"`
+	RunCount  int        `gorm:"default:0" json:"run_count"`
+	LastError string     `json:"last_error,omitempty"`
+}
+
+// BeforeCreate hook to generate UUID before creating
+func (job *RecurringJob) BeforeCreate(tx *gorm.DB) error {
+	if job.ID == uuid.Nil {
+		job.ID = uuid.New()
+	}
+	return nil
+}
+
+// RecurringJobRun records a single execution of a RecurringJob
+type RecurringJobRun struct {
+	ID        uuid.UUID `gorm:"type:uuid;primary_key" json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+
+	JobID uuid.UUID `gorm:"type:uuid;not null;index" json:"job_id"`
+
+	StartedAt  time.Time  `json:"started_at"`
+	FinishedAt *time.Time `json:"finished_at"`
+	Status     string     `gorm:"not null" json:"status"` // "running", "completed", "failed"
+	Total      int        `json:"total"`
+	Sent       int        `json:"sent"`
+	Failed     int        `json:"failed"`
+	Error      string     `json:"error,omitempty"`
+}
+
+// BeforeCreate hook to generate UUID before creating
+func (run *RecurringJobRun) BeforeCreate(tx *gorm.DB) error {
+	if run.ID == uuid.Nil {
+		run.ID = uuid.New()
+	}
+	return nil
+}
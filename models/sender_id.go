@@ -0,0 +1,39 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Sender ID statuses
+const (
+	SenderIDPending  = "pending"  // Requested by the client, waiting for review
+	SenderIDApproved = "approved" // May be used for sending
+	SenderIDRejected = "rejected" // Refused or revoked by an admin
+)
+
+// SenderID is a sender name requested by a client. Only approved sender IDs may be used
+// for sending; the client's default is used for messages that do not set one.
+type SenderID struct {
+	ID        uuid.UUID `gorm:"type:uuid;primary_key" json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+
+	ClientID   uuid.UUID  `gorm:"type:uuid;not null;uniqueIndex:idx_sender_ids_client_name" json:"client_id"`
+	Name       string     `gorm:"not null;uniqueIndex:idx_sender_ids_client_name" json:"name"`
+	Purpose    string     `json:"purpose"` // What the client will send with it, for the reviewer
+	Status     string     `gorm:"not null;index" json:"status"`
+	IsDefault  bool       `gorm:"default:false" json:"is_default"`
+	ReviewNote string     `json:"review_note,omitempty"`
+	ReviewedAt *time.Time `json:"reviewed_at,omitempty"`
+}
+
+// BeforeCreate hook to generate UUID before creating
+func (s *SenderID) BeforeCreate(tx *gorm.DB) error {
+	if s.ID == uuid.Nil {
+		s.ID = uuid.New()
+	}
+	return nil
+}
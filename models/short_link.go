@@ -0,0 +1,50 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// ShortLink is a link in an outbound message replaced by a gateway short link.
+// The gateway redirects /l/{Code} to URL and records each click.
+type ShortLink struct {
+	ID        uuid.UUID `gorm:"type:uuid;primary_key" json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+
+	ClientID uuid.UUID `gorm:"type:uuid;not null;index" json:"client_id"`
+	SMSLogID uuid.UUID `gorm:"type:uuid;not null;index" json:"sms_log_id"` // Message the link was sent in
+	Code     string    `gorm:"not null;uniqueIndex" json:"code"`
+	URL      string    `gorm:"not null" json:"url"` // Original link
+	Clicks   int       `gorm:"default:0" json:"clicks"`
+
+	LastClickedAt *time.Time `json:"last_clicked_at,omitempty"`
+}
+
+// BeforeCreate hook to generate UUID before creating
+func (l *ShortLink) BeforeCreate(tx *gorm.DB) error {
+	if l.ID == uuid.Nil {
+		l.ID = uuid.New()
+	}
+	return nil
+}
+
+// LinkClick records one visit to a short link
+type LinkClick struct {
+	ID        uuid.UUID `gorm:"type:uuid;primary_key" json:"id"`
+	CreatedAt time.Time `gorm:"index" json:"created_at"` // When the link was clicked
+
+	ShortLinkID uuid.UUID `gorm:"type:uuid;not null;index" json:"short_link_id"`
+	SMSLogID    uuid.UUID `gorm:"type:uuid;not null;index" json:"sms_log_id"`
+	UserAgent   string    `json:"user_agent"`
+	IPAddress   string    `json:"ip_address"`
+}
+
+// BeforeCreate hook to generate UUID before creating
+func (c *LinkClick) BeforeCreate(tx *gorm.DB) error {
+	if c.ID == uuid.Nil {
+		c.ID = uuid.New()
+	}
+	return nil
+}
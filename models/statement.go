@@ -0,0 +1,92 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Statement statuses
+const (
+	StatementDraft     = "draft"     // Computed from the message history on request, not stored
+	StatementFinalized = "finalized" // Stored and never recomputed
+)
+
+// StatementLine totals a client's billable messages to one destination country from one sender ID
+type StatementLine struct {
+	Destination string `json:"destination"` // ISO 3166-1 alpha-2 code, or "unknown"
+	SenderID    string `json:"sender_id"`
+	Messages    int64  `json:"messages"`
+	Segments    int64  `json:"segments"`
+	Cost        int64  `json:"cost"`
+}
+
+// StatementLines is a list of statement lines stored as a JSON array in a text column
+type StatementLines []StatementLine
+
+// Value implements driver.Valuer
+func (l StatementLines) Value() (driver.Value, error) {
+	if l == nil {
+		return "[]", nil
+	}
+	data, err := json.Marshal(l)
+	if err != nil {
+		return nil, err
+	}
+	return string(data), nil
+}
+
+// Scan implements sql.Scanner
+func (l *StatementLines) Scan(value interface{}) error {
+	data, err := jsonColumnBytes(value)
+	if err != nil {
+		return err
+	}
+	if len(data) == 0 {
+		*l = nil
+		return nil
+	}
+	return json.Unmarshal(data, l)
+}
+
+// Statement is a client's monthly usage statement. Billable messages are those handed to
+// the provider (sent or with an unknown outcome); months run from midnight UTC on the 1st.
+type Statement struct {
+	ID        uuid.UUID `gorm:"type:uuid;primary_key" json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+
+	ClientID    uuid.UUID  `gorm:"type:uuid;not null;uniqueIndex:idx_statements_client_period" json:"client_id"`
+	Period      string     `gorm:"not null;uniqueIndex:idx_statements_client_period" json:"period"` // "YYYY-MM"
+	PeriodStart time.Time  `json:"period_start"`
+	PeriodEnd   time.Time  `json:"period_end"`
+	Status      string     `gorm:"not null" json:"status"` // "draft", "finalized"
+	FinalizedAt *time.Time `json:"finalized_at,omitempty"`
+
+	Currency string         `json:"currency"`
+	Messages int64          `json:"messages"`
+	Segments int64          `json:"segments"`
+	Cost     int64          `json:"cost"`
+	Lines    StatementLines `gorm:"type:text" json:"lines"`
+}
+
+// BeforeCreate hook to generate UUID before creating
+func (s *Statement) BeforeCreate(tx *gorm.DB) error {
+	if s.ID == uuid.Nil {
+		s.ID = uuid.New()
+	}
+	return nil
+}
+
+// BeforeUpdate keeps finalized statements unchanged
+func (s *Statement) BeforeUpdate(tx *gorm.DB) error {
+	return errors.New("finalized statements cannot be changed")
+}
+
+// BeforeDelete keeps finalized statements unchanged
+func (s *Statement) BeforeDelete(tx *gorm.DB) error {
+	return errors.New("finalized statements cannot be changed")
+}
@@ -0,0 +1,40 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Suppression sources
+const (
+	SuppressionSourceClient  = "client"
+	SuppressionSourceAdmin   = "admin"
+	SuppressionSourceInbound = "inbound" // Recipient replied with an opt-out keyword
+)
+
+// Suppression is a phone number that must not receive messages.
+// Entries without a ClientID apply to every client.
+type Suppression struct {
+	ID        uuid.UUID `gorm:"type:uuid;primary_key" json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+
+	ClientID *uuid.UUID `gorm:"type:uuid;index:idx_suppressions_client_phone" json:"client_id"` // nil for global suppressions
+	Phone    string     `gorm:"not null;index:idx_suppressions_client_phone" json:"phone"`      // Normalized phone number
+	Reason   string     `json:"reason"`
+	Source   string     `gorm:"not null" json:"source"` // "client", "admin", "inbound"
+}
+
+// BeforeCreate hook to generate UUID before creating
+func (s *Suppression) BeforeCreate(tx *gorm.DB) error {
+	if s.ID == uuid.Nil {
+		s.ID = uuid.New()
+	}
+	return nil
+}
+
+// IsGlobal reports whether the suppression applies to every client
+func (s *Suppression) IsGlobal() bool {
+	return s.ClientID == nil
+}
@@ -0,0 +1,48 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Verification statuses
+const (
+	VerificationPending  = "pending"
+	VerificationApproved = "approved"
+	VerificationExpired  = "expired"
+	VerificationFailed   = "failed" // Too many wrong codes, the verification is locked
+)
+
+// Verification is a one-time code sent to a phone number. Only an HMAC of the code is stored.
+type Verification struct {
+	ID        uuid.UUID `gorm:"type:uuid;primary_key" json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+
+	ClientID uuid.UUID `gorm:"type:uuid;not null;index:idx_verifications_client_phone" json:"client_id"`
+	Phone    string    `gorm:"not null;index:idx_verifications_client_phone" json:"phone"`
+
+	CodeHash  string    `gorm:"not null" json:"-"`
+	ExpiresAt time.Time `json:"expires_at"`
+	Status    string    `gorm:"not null;index" json:"status"` // "pending", "approved", "expired", "failed"
+
+	// Checking
+	Attempts    int        `gorm:"default:0" json:"attempts"`
+	MaxAttempts int        `json:"max_attempts"`
+	VerifiedAt  *time.Time `json:"verified_at,omitempty"`
+
+	// Sending
+	SendCount  int        `gorm:"default:0" json:"send_count"`
+	LastSentAt *time.Time `gorm:"index" json:"last_sent_at,omitempty"`
+	SMSLogID   *uuid.UUID `gorm:"type:uuid" json:"sms_log_id,omitempty"` // Log of the most recent send
+}
+
+// BeforeCreate hook to generate UUID before creating
+func (v *Verification) BeforeCreate(tx *gorm.DB) error {
+	if v.ID == uuid.Nil {
+		v.ID = uuid.New()
+	}
+	return nil
+}
@@ -0,0 +1,90 @@
+package models
+
+import (
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Wallet holds a client's prepaid credit. Amounts are in the smallest unit of the
+// gateway's currency (WALLET_CURRENCY).
+type Wallet struct {
+	ID        uuid.UUID `gorm:"type:uuid;primary_key" json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+
+	ClientID uuid.UUID `gorm:"type:uuid;uniqueIndex;not null" json:"client_id"`
+	Balance  int64     `gorm:"not null;default:0" json:"balance"`
+}
+
+// BeforeCreate hook to generate UUID before creating
+func (w *Wallet) BeforeCreate(tx *gorm.DB) error {
+	if w.ID == uuid.Nil {
+		w.ID = uuid.New()
+	}
+	return nil
+}
+
+// Wallet transaction types
+const (
+	WalletTxTopUp      = "topup"      // Credit bought by the client
+	WalletTxAdjustment = "adjustment" // Manual correction by an admin, positive or negative
+	WalletTxDebit      = "debit"      // Messages handed to the provider
+	WalletTxRefund     = "refund"     // Message the provider failed to send
+)
+
+// WalletTransaction is an entry in a client's wallet ledger. Entries are never changed:
+// corrections are recorded as new adjustment entries.
+type WalletTransaction struct {
+	ID        uuid.UUID `gorm:"type:uuid;primary_key" json:"id"`
+	CreatedAt time.Time `gorm:"index" json:"created_at"`
+
+	ClientID     uuid.UUID  `gorm:"type:uuid;not null;index" json:"client_id"`
+	Type         string     `gorm:"not null;index" json:"type"` // "topup", "adjustment", "debit", "refund"
+	Amount       int64      `gorm:"not null" json:"amount"`     // Positive for credits, negative for debits
+	BalanceAfter int64      `gorm:"not null" json:"balance_after"`
+	Messages     int        `json:"messages,omitempty"`                          // Messages covered by a debit
+	SMSLogID     *uuid.UUID `gorm:"type:uuid;index" json:"sms_log_id,omitempty"` // Message a refund is for
+	Reference    string     `json:"reference,omitempty"`                         // External payment reference
+	Note         string     `json:"note,omitempty"`
+}
+
+// BeforeCreate hook to generate UUID before creating
+func (t *WalletTransaction) BeforeCreate(tx *gorm.DB) error {
+	if t.ID == uuid.Nil {
+		t.ID = uuid.New()
+	}
+	return nil
+}
+
+// BeforeUpdate keeps the ledger append-only
+func (t *WalletTransaction) BeforeUpdate(tx *gorm.DB) error {
+	return errors.New("wallet transactions cannot be changed")
+}
+
+// BeforeDelete keeps the ledger append-only
+func (t *WalletTransaction) BeforeDelete(tx *gorm.DB) error {
+	return errors.New("wallet transactions cannot be changed")
+}
+
+// PriceRule is the price of one message segment to destinations starting with Prefix
+// (E.164, e.g. "+25677"). The longest matching prefix applies; "+" matches every number.
+type PriceRule struct {
+	ID        uuid.UUID `gorm:"type:uuid;primary_key" json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+
+	Prefix          string `gorm:"uniqueIndex;not null" json:"prefix"`
+	PricePerSegment int64  `gorm:"not null" json:"price_per_segment"`
+	Description     string `json:"description"`
+}
+
+// BeforeCreate hook to generate UUID before creating
+func (p *PriceRule) BeforeCreate(tx *gorm.DB) error {
+	if p.ID == uuid.Nil {
+		p.ID = uuid.New()
+	}
+	return nil
+}
@@ -0,0 +1,128 @@
+package service
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/Ian-Balijawa/sms-gateway/config"
+	"github.com/Ian-Balijawa/sms-gateway/database"
+	"github.com/Ian-Balijawa/sms-gateway/models"
+)
+
+// BalanceChecker is implemented by providers that can report the credit left on their account
+type BalanceChecker interface {
+	Balance() (float64, error)
+}
+
+// BalanceMonitor records the provider account balance and alerts when it runs low
+type BalanceMonitor struct {
+	provider Provider
+
+	// Checks are serialized so that one low-balance period raises a single alert
+	mu sync.Mutex
+}
+
+func NewBalanceMonitor(provider Provider) *BalanceMonitor {
+	return &BalanceMonitor{provider: provider}
+}
+
+// Provider returns the name of the monitored provider
+func (m *BalanceMonitor) Provider() string {
+	return m.provider.Name()
+}
+
+// Start checks the balance every PROVIDER_BALANCE_CHECK_MINUTES in a background goroutine
+func (m *BalanceMonitor) Start() {
+	interval := config.AppConfig.ProviderBalanceCheckMinutes
+	if interval <= 0 {
+		log.Println("Provider balance monitoring is disabled")
+		return
+	}
+	if _, ok := m.provider.(BalanceChecker); !ok {
+		log.Printf("Provider %s does not report its balance, balance monitoring is disabled", m.provider.Name())
+		return
+	}
+
+	go func() {
+		for {
+			if _, err := m.Check(); err != nil {
+				log.Printf("Error checking provider balance: %v", err)
+			}
+			time.Sleep(time.Duration(interval) * time.Minute)
+		}
+	}()
+
+	log.Println("Provider balance monitor started")
+}
+
+// Check queries the provider balance and stores the reading. When the balance is below
+// PROVIDER_BALANCE_LOW_THRESHOLD an alert is sent, once per low-balance period; an alert
+// that could not be delivered is retried on the next check. A failed query is stored
+// with its error and does not return one.
+func (m *BalanceMonitor) Check() (*models.ProviderBalance, error) {
+	checker, ok := m.provider.(BalanceChecker)
+	if !ok {
+		return nil, fmt.Errorf("provider %s does not report its balance", m.provider.Name())
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	reading := models.ProviderBalance{Provider: m.provider.Name()}
+	balance, err := checker.Balance()
+	if err != nil {
+		reading.Error = err.Error()
+		log.Printf("Provider balance check failed: %v", err)
+		return &reading, database.DB.Create(&reading).Error
+	}
+	reading.Balance = &balance
+
+	threshold := config.AppConfig.ProviderBalanceLowThreshold
+	reading.Low = threshold > 0 && balance < float64(threshold)
+	if reading.Low {
+		var previous models.ProviderBalance
+		if err := database.DB.
+			Where("provider = ? AND balance IS NOT NULL", reading.Provider).
+			Order("created_at DESC").
+			Limit(1).
+			Find(&previous).Error; err != nil {
+			return nil, err
+		}
+
+		reading.AlertSent = previous.Low && previous.AlertSent
+		if !reading.AlertSent {
+			log.Printf("Provider %s balance %.2f is below %d", reading.Provider, balance, threshold)
+			if err := sendLowBalanceAlert(&reading, threshold); err != nil {
+				log.Printf("Error sending low balance alert: %v", err)
+			} else {
+				reading.AlertSent = true
+			}
+		}
+	}
+
+	return &reading, database.DB.Create(&reading).Error
+}
+
+// sendLowBalanceAlert POSTs a low-balance event to PROVIDER_BALANCE_ALERT_URL, signed
+// with PROVIDER_BALANCE_ALERT_SECRET like client webhooks
+func sendLowBalanceAlert(reading *models.ProviderBalance, threshold int) error {
+	url := config.AppConfig.ProviderBalanceAlertURL
+	if url == "" {
+		return fmt.Errorf("PROVIDER_BALANCE_ALERT_URL is not set")
+	}
+
+	body, err := json.Marshal(map[string]interface{}{
+		"event":      "provider_balance_low",
+		"provider":   reading.Provider,
+		"balance":    *reading.Balance,
+		"threshold":  threshold,
+		"checked_at": time.Now().UTC(),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to marshal payload: %w", err)
+	}
+	return postWebhook(url, config.AppConfig.ProviderBalanceAlertSecret, body)
+}
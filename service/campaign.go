@@ -0,0 +1,277 @@
+package service
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/Ian-Balijawa/sms-gateway/config"
+	"github.com/Ian-Balijawa/sms-gateway/database"
+	"github.com/Ian-Balijawa/sms-gateway/models"
+
+	"github.com/google/uuid"
+)
+
+// campaignPollInterval is how often running campaigns send their next batch.
+// Each batch holds at most the campaign's rate, so campaigns send at most rate messages per second.
+const campaignPollInterval = time.Second
+
+// CampaignRate returns the send rate of a campaign in messages per second.
+// Campaigns without a rate, or above the gateway's CAMPAIGN_RATE, use CAMPAIGN_RATE.
+func CampaignRate(campaign *models.Campaign) int {
+	limit := max(config.AppConfig.CampaignRate, 1)
+	if campaign.Rate <= 0 || campaign.Rate > limit {
+		return limit
+	}
+	return campaign.Rate
+}
+
+// campaignOutstandingStatuses are the statuses of campaign messages still to be sent
+var campaignOutstandingStatuses = []string{models.SMSStatusQueued, models.SMSStatusPending, models.SMSStatusDeferred}
+
+// CancelCampaignMessages marks the campaign's queued and deferred messages as cancelled
+func CancelCampaignMessages(campaignID uuid.UUID) error {
+	return database.DB.Model(&models.SMSLog{}).
+		Where("campaign_id = ? AND status IN ?", campaignID, []string{models.SMSStatusQueued, models.SMSStatusDeferred}).
+		Updates(map[string]interface{}{
+			"status": models.SMSStatusCancelled,
+			"error":  "campaign cancelled",
+		}).Error
+}
+
+// GetCampaignStats counts the campaign's messages by status
+func GetCampaignStats(campaign *models.Campaign) (*models.CampaignStats, error) {
+	var counts []struct {
+		Status string
+		Count  int64
+	}
+	if err := database.DB.Model(&models.SMSLog{}).
+		Select("status, COUNT(*) AS count").
+		Where("campaign_id = ?", campaign.ID).
+		Group("status").
+		Scan(&counts).Error; err != nil {
+		return nil, err
+	}
+
+	stats := &models.CampaignStats{
+		CampaignID: campaign.ID,
+		Status:     campaign.Status,
+	}
+	for _, count := range counts {
+		stats.Total += count.Count
+		switch count.Status {
+		case models.SMSStatusQueued, models.SMSStatusPending:
+			stats.Queued += count.Count
+		case models.SMSStatusDeferred:
+			stats.Deferred += count.Count
+		case models.SMSStatusSent:
+			stats.Sent += count.Count
+		case models.SMSStatusUnknown:
+			stats.Unknown += count.Count
+		case models.SMSStatusSuppressed:
+			stats.Suppressed += count.Count
+		case models.SMSStatusRejected:
+			stats.Rejected += count.Count
+		case models.SMSStatusBlocked:
+			stats.Blocked += count.Count
+		case models.SMSStatusCancelled:
+			stats.Cancelled += count.Count
+		default:
+			stats.Failed += count.Count
+		}
+	}
+	if stats.Total > 0 {
+		stats.Progress = float64(stats.Total-stats.Queued-stats.Deferred) * 100 / float64(stats.Total)
+	}
+	return stats, nil
+}
+
+// CampaignRunner starts scheduled campaigns and sends the queued messages of running ones
+type CampaignRunner struct {
+	dispatcher *Dispatcher
+}
+
+func NewCampaignRunner(dispatcher *Dispatcher) *CampaignRunner {
+	return &CampaignRunner{
+		dispatcher: dispatcher,
+	}
+}
+
+// Start runs campaigns in a background goroutine
+func (r *CampaignRunner) Start() {
+	go func() {
+		for {
+			r.tick(time.Now())
+			time.Sleep(campaignPollInterval)
+		}
+	}()
+
+	log.Println("Campaign runner started")
+}
+
+func (r *CampaignRunner) tick(now time.Time) {
+	var due []models.Campaign
+	if err := database.DB.
+		Where("status = ? AND (scheduled_at IS NULL OR scheduled_at <= ?)", models.CampaignScheduled, now.UTC()).
+		Order("created_at").
+		Find(&due).Error; err != nil {
+		log.Printf("Error loading scheduled campaigns: %v", err)
+		return
+	}
+	for i := range due {
+		r.startCampaign(&due[i], now)
+	}
+
+	var running []models.Campaign
+	if err := database.DB.Where("status = ?", models.CampaignRunning).Order("started_at").Find(&running).Error; err != nil {
+		log.Printf("Error loading running campaigns: %v", err)
+		return
+	}
+	for i := range running {
+		if err := r.sendNextBatch(&running[i], now); err != nil {
+			r.pause(&running[i], err)
+		}
+	}
+}
+
+// startCampaign expands the campaign's audience and queues one message per recipient
+func (r *CampaignRunner) startCampaign(campaign *models.Campaign, now time.Time) {
+	// Claim the campaign so that it is only started once
+	claim := database.DB.Model(&models.Campaign{}).
+		Where("id = ? AND status = ?", campaign.ID, models.CampaignScheduled).
+		Updates(map[string]interface{}{
+			"status":     models.CampaignRunning,
+			"started_at": now,
+			"last_error": "",
+		})
+	if claim.Error != nil || claim.RowsAffected == 0 {
+		return
+	}
+	campaign.Status = models.CampaignRunning
+	campaign.StartedAt = &now
+
+	total, err := r.queueMessages(campaign)
+	if err != nil {
+		log.Printf("Campaign %s failed to start: %v", campaign.ID, err)
+		// Remove what was queued so the campaign can start again from scratch when resumed
+		database.DB.Where("campaign_id = ?", campaign.ID).Delete(&models.SMSLog{})
+		database.DB.Model(campaign).Updates(map[string]interface{}{
+			"status":     models.CampaignPaused,
+			"started_at": nil,
+			"last_error": err.Error(),
+		})
+		return
+	}
+
+	// The campaign may have been cancelled while its messages were being queued
+	update := database.DB.Model(&models.Campaign{}).
+		Where("id = ? AND status = ?", campaign.ID, models.CampaignRunning).
+		Update("total", total)
+	if update.Error == nil && update.RowsAffected == 0 {
+		database.DB.Model(&models.Campaign{}).Where("id = ?", campaign.ID).Update("total", total)
+		if err := database.DB.First(campaign, "id = ?", campaign.ID).Error; err == nil && campaign.Status == models.CampaignCancelled {
+			if err := CancelCampaignMessages(campaign.ID); err != nil {
+				log.Printf("Error cancelling messages of campaign %s: %v", campaign.ID, err)
+			}
+		}
+	}
+
+	log.Printf("Campaign %s started with %d messages", campaign.ID, total)
+}
+
+func (r *CampaignRunner) queueMessages(campaign *models.Campaign) (int, error) {
+	var client models.APIClient
+	if err := database.DB.Where("id = ?", campaign.ClientID).First(&client).Error; err != nil {
+		return 0, fmt.Errorf("client not found")
+	}
+	if !client.IsActive {
+		return 0, fmt.Errorf("client is inactive")
+	}
+
+	messages, err := AudienceMessages(campaign.ClientID, campaign.GroupID, campaign.Recipients,
+		campaign.Message, campaign.SenderID, campaign.Priority, client.DefaultRegion)
+	if err != nil {
+		return 0, err
+	}
+	for i := range messages {
+		messages[i].ShortenLinks = campaign.ShortenLinks
+	}
+
+	if _, err := r.dispatcher.Queue(DispatchRequest{
+		Client:     &client,
+		Messages:   messages,
+		CampaignID: &campaign.ID,
+	}); err != nil {
+		return 0, err
+	}
+	return len(messages), nil
+}
+
+// sendNextBatch sends up to one second's worth of the campaign's due queued messages
+// and completes the campaign once nothing is left in the queue
+func (r *CampaignRunner) sendNextBatch(campaign *models.Campaign, now time.Time) error {
+	var client models.APIClient
+	if err := database.DB.Where("id = ?", campaign.ClientID).First(&client).Error; err != nil {
+		return fmt.Errorf("client not found")
+	}
+	if !client.IsActive {
+		return fmt.Errorf("client is inactive")
+	}
+
+	// Stay within the client's quota
+	batch := min(CampaignRate(campaign), client.DailyLimit-client.DailyUsage, client.MonthlyLimit-client.MonthlyUsage)
+	if batch <= 0 {
+		return fmt.Errorf("client usage limit reached")
+	}
+
+	var logs []models.SMSLog
+	if err := database.DB.
+		Where("campaign_id = ? AND status = ? AND (scheduled_at IS NULL OR scheduled_at <= ?)",
+			campaign.ID, models.SMSStatusQueued, now.UTC()).
+		Order("created_at").
+		Limit(batch).
+		Find(&logs).Error; err != nil {
+		return fmt.Errorf("failed to load queued messages: %w", err)
+	}
+
+	if len(logs) == 0 {
+		r.completeIfDone(campaign, now)
+		return nil
+	}
+
+	// Messages cancelled or claimed elsewhere since they were loaded are skipped
+	if logs = claimLogs(logs, models.SMSStatusQueued); len(logs) > 0 {
+		r.dispatcher.sendClaimed(&client, logs, models.SMSStatusQueued)
+	}
+	return nil
+}
+
+// completeIfDone marks a running campaign completed when it has no messages left to send,
+// including messages deferred by quiet hours or held for fraud review
+func (r *CampaignRunner) completeIfDone(campaign *models.Campaign, now time.Time) {
+	var outstanding int64
+	if err := database.DB.Model(&models.SMSLog{}).
+		Where("campaign_id = ? AND status IN ?", campaign.ID, campaignOutstandingStatuses).
+		Count(&outstanding).Error; err != nil || outstanding > 0 {
+		return
+	}
+
+	database.DB.Model(&models.Campaign{}).
+		Where("id = ? AND status = ?", campaign.ID, models.CampaignRunning).
+		Updates(map[string]interface{}{
+			"status":       models.CampaignCompleted,
+			"completed_at": now,
+		})
+	log.Printf("Campaign %s completed", campaign.ID)
+}
+
+// pause stops a running campaign that cannot continue, recording why
+func (r *CampaignRunner) pause(campaign *models.Campaign, err error) {
+	log.Printf("Pausing campaign %s: %v", campaign.ID, err)
+	database.DB.Model(&models.Campaign{}).
+		Where("id = ? AND status = ?", campaign.ID, models.CampaignRunning).
+		Updates(map[string]interface{}{
+			"status":     models.CampaignPaused,
+			"last_error": err.Error(),
+		})
+}
@@ -0,0 +1,422 @@
+package service
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/Ian-Balijawa/sms-gateway/database"
+	"github.com/Ian-Balijawa/sms-gateway/models"
+	"github.com/Ian-Balijawa/sms-gateway/utils"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm/clause"
+)
+
+// contactImportBatchSize is the number of rows written to the database at a time.
+// Progress is saved after every batch.
+const contactImportBatchSize = 500
+
+const (
+	// contactImportStaleAfter is how long an import can go without a heartbeat before it is
+	// considered abandoned by the server that was running it
+	contactImportStaleAfter = 5 * time.Minute
+
+	// contactImportRecoveryInterval is how often abandoned imports are looked for
+	contactImportRecoveryInterval = time.Minute
+)
+
+// errContactImportAbandoned is returned when an import was failed as abandoned while it was running
+var errContactImportAbandoned = errors.New("import was marked as abandoned")
+
+// importRow is a validated CSV row waiting to be written
+type importRow struct {
+	phone      string
+	name       string
+	attributes models.StringMap
+}
+
+// StartContactImport processes an uploaded CSV file in a background goroutine.
+// The file is removed once the import finishes. The import is passed by value: the goroutine
+// updates its own copy as it progresses, so the caller's copy can be used concurrently.
+func StartContactImport(imp models.ContactImport, path string) {
+	go func(imp *models.ContactImport) {
+		defer os.Remove(path)
+
+		err := processContactImport(imp, path)
+		if errors.Is(err, errContactImportAbandoned) {
+			log.Printf("Contact import %s stopped: %v", imp.ID, err)
+			return
+		}
+		if err != nil {
+			log.Printf("Contact import %s failed: %v", imp.ID, err)
+			finished := time.Now()
+			database.DB.Model(imp).
+				Where("status IN ?", []string{models.ImportQueued, models.ImportProcessing}).
+				Updates(map[string]interface{}{
+					"status":      models.ImportFailed,
+					"error":       err.Error(),
+					"finished_at": finished,
+				})
+		}
+	}(&imp)
+}
+
+// StartContactImportRecovery fails abandoned imports in a background goroutine
+func StartContactImportRecovery() {
+	go func() {
+		for {
+			RecoverContactImports(time.Now())
+			time.Sleep(contactImportRecoveryInterval)
+		}
+	}()
+
+	log.Println("Contact import recovery started")
+}
+
+// RecoverContactImports marks imports whose server stopped running them as failed: imports
+// without a heartbeat for contactImportStaleAfter. Imports that other servers are running
+// keep their heartbeat fresh and are left alone. The uploaded files of abandoned imports are
+// not kept, so they have to be uploaded again.
+func RecoverContactImports(now time.Time) {
+	result := database.DB.Model(&models.ContactImport{}).
+		Where("status IN ? AND COALESCE(heartbeat_at, updated_at) < ?",
+			[]string{models.ImportQueued, models.ImportProcessing}, now.Add(-contactImportStaleAfter)).
+		Updates(map[string]interface{}{
+			"status":      models.ImportFailed,
+			"error":       "import interrupted because the server running it stopped, please upload the file again",
+			"finished_at": now,
+		})
+
+	if result.Error != nil {
+		log.Printf("Error recovering contact imports: %v", result.Error)
+		return
+	}
+	if result.RowsAffected > 0 {
+		log.Printf("Marked %d interrupted contact imports as failed", result.RowsAffected)
+	}
+}
+
+func processContactImport(imp *models.ContactImport, path string) error {
+	total, err := countCSVRows(path)
+	if err != nil {
+		return err
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to open file: %w", err)
+	}
+	defer file.Close()
+
+	reader := csv.NewReader(file)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return fmt.Errorf("failed to read CSV header: %w", err)
+	}
+
+	phoneIdx, nameIdx, attrIdx, err := mapImportColumns(imp, header)
+	if err != nil {
+		return err
+	}
+
+	// Numbers without a country code are read in the client's region
+	var client models.APIClient
+	if err := database.DB.Where("id = ?", imp.ClientID).First(&client).Error; err != nil {
+		return fmt.Errorf("client not found")
+	}
+
+	// Claim the import, unless it was given up on while the file was read
+	started := time.Now()
+	imp.Status = models.ImportProcessing
+	imp.TotalRows = total
+	imp.StartedAt = &started
+	claim := database.DB.Model(imp).
+		Where("status = ?", models.ImportQueued).
+		Updates(map[string]interface{}{
+			"status":            imp.Status,
+			"total_rows":        imp.TotalRows,
+			"started_at":        started,
+			"heartbeat_at":      started,
+			"attribute_columns": imp.AttributeColumns,
+		})
+	if claim.Error != nil {
+		return claim.Error
+	}
+	if claim.RowsAffected == 0 {
+		return errContactImportAbandoned
+	}
+
+	seen := make(map[string]bool)
+	rows := make([]importRow, 0, contactImportBatchSize)
+	failures := make([]models.ContactImportError, 0)
+	processed := 0
+	row := 1 // The header is row 1
+
+	flush := func() error {
+		if err := writeImportBatch(imp, rows, failures, processed); err != nil {
+			return err
+		}
+		rows = rows[:0]
+		failures = failures[:0]
+		return nil
+	}
+
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		row++
+		processed++
+
+		if err != nil {
+			failures = append(failures, models.ContactImportError{ImportID: imp.ID, Row: row, Reason: "malformed row"})
+		} else {
+			raw := field(record, phoneIdx)
+			parsed, parseErr := utils.ParsePhone(raw, client.DefaultRegion)
+			switch {
+			case raw == "":
+				failures = append(failures, models.ContactImportError{ImportID: imp.ID, Row: row, Reason: "missing phone number"})
+			case parseErr != nil:
+				failures = append(failures, models.ContactImportError{ImportID: imp.ID, Row: row, Phone: raw, Reason: importPhoneError(parseErr)})
+			default:
+				phone := parsed.E164
+				if seen[phone] {
+					imp.Duplicates++
+					break
+				}
+				seen[phone] = true
+
+				attributes := make(models.StringMap, len(attrIdx))
+				for column, idx := range attrIdx {
+					if value := field(record, idx); value != "" {
+						attributes[column] = value
+					}
+				}
+				rows = append(rows, importRow{
+					phone:      phone,
+					name:       field(record, nameIdx),
+					attributes: attributes,
+				})
+			}
+		}
+
+		if len(rows)+len(failures) >= contactImportBatchSize {
+			if err := flush(); err != nil {
+				return err
+			}
+		}
+	}
+
+	if err := flush(); err != nil {
+		return err
+	}
+
+	finished := time.Now()
+	database.DB.Model(imp).
+		Where("status = ?", models.ImportProcessing).
+		Updates(map[string]interface{}{
+			"status":      models.ImportCompleted,
+			"finished_at": finished,
+		})
+
+	log.Printf("Contact import %s completed: imported=%d updated=%d duplicates=%d failed=%d",
+		imp.ID, imp.Imported, imp.Updated, imp.Duplicates, imp.Failed)
+	return nil
+}
+
+// mapImportColumns resolves the import's column mapping against the CSV header.
+// When no attribute columns are given, every column other than phone and name is imported as an attribute.
+func mapImportColumns(imp *models.ContactImport, header []string) (int, int, map[string]int, error) {
+	index := make(map[string]int, len(header))
+	for i, column := range header {
+		column = strings.TrimSpace(strings.TrimPrefix(column, "\ufeff"))
+		index[strings.ToLower(column)] = i
+		header[i] = column
+	}
+
+	phoneIdx, ok := index[strings.ToLower(imp.PhoneColumn)]
+	if !ok {
+		return 0, 0, nil, fmt.Errorf("phone column %q not found in CSV header", imp.PhoneColumn)
+	}
+
+	nameIdx := -1
+	if imp.NameColumn != "" {
+		if idx, ok := index[strings.ToLower(imp.NameColumn)]; ok {
+			nameIdx = idx
+		}
+	}
+
+	attrIdx := make(map[string]int)
+	if len(imp.AttributeColumns) == 0 {
+		for i, column := range header {
+			if i != phoneIdx && i != nameIdx && column != "" {
+				attrIdx[column] = i
+				imp.AttributeColumns = append(imp.AttributeColumns, column)
+			}
+		}
+	} else {
+		for _, column := range imp.AttributeColumns {
+			idx, ok := index[strings.ToLower(column)]
+			if !ok {
+				return 0, 0, nil, fmt.Errorf("attribute column %q not found in CSV header", column)
+			}
+			attrIdx[column] = idx
+		}
+	}
+
+	return phoneIdx, nameIdx, attrIdx, nil
+}
+
+// writeImportBatch creates or updates the contacts for a batch of rows, records
+// rejected rows and saves the import's progress and heartbeat. It returns
+// errContactImportAbandoned if the import is no longer processing.
+func writeImportBatch(imp *models.ContactImport, rows []importRow, failures []models.ContactImportError, processed int) error {
+	if len(rows) > 0 {
+		phones := make([]string, len(rows))
+		for i, r := range rows {
+			phones[i] = r.phone
+		}
+
+		var existing []models.Contact
+		if err := database.DB.Where("client_id = ? AND phone IN ?", imp.ClientID, phones).Find(&existing).Error; err != nil {
+			return fmt.Errorf("failed to look up existing contacts: %w", err)
+		}
+		byPhone := make(map[string]*models.Contact, len(existing))
+		for i := range existing {
+			byPhone[existing[i].Phone] = &existing[i]
+		}
+
+		created := make([]models.Contact, 0, len(rows))
+		contactIDs := make([]uuid.UUID, 0, len(rows))
+		for _, r := range rows {
+			contact, found := byPhone[r.phone]
+			if !found {
+				created = append(created, models.Contact{
+					ID:         uuid.New(),
+					ClientID:   imp.ClientID,
+					Phone:      r.phone,
+					Name:       r.name,
+					Attributes: r.attributes,
+				})
+				contactIDs = append(contactIDs, created[len(created)-1].ID)
+				continue
+			}
+
+			contactIDs = append(contactIDs, contact.ID)
+			if !imp.UpdateExisting {
+				imp.Duplicates++
+				continue
+			}
+
+			if r.name != "" {
+				contact.Name = r.name
+			}
+			if contact.Attributes == nil {
+				contact.Attributes = models.StringMap{}
+			}
+			for key, value := range r.attributes {
+				contact.Attributes[key] = value
+			}
+			if err := database.DB.Save(contact).Error; err != nil {
+				return fmt.Errorf("failed to update contact: %w", err)
+			}
+			imp.Updated++
+		}
+
+		if len(created) > 0 {
+			if err := database.DB.CreateInBatches(&created, 100).Error; err != nil {
+				return fmt.Errorf("failed to create contacts: %w", err)
+			}
+			imp.Imported += len(created)
+		}
+
+		if imp.GroupID != nil {
+			members := make([]models.GroupMember, len(contactIDs))
+			for i, contactID := range contactIDs {
+				members[i] = models.GroupMember{GroupID: *imp.GroupID, ContactID: contactID}
+			}
+			if err := database.DB.Clauses(clause.OnConflict{DoNothing: true}).CreateInBatches(&members, 100).Error; err != nil {
+				return fmt.Errorf("failed to add contacts to group: %w", err)
+			}
+		}
+	}
+
+	if len(failures) > 0 {
+		if err := database.DB.CreateInBatches(&failures, 100).Error; err != nil {
+			return fmt.Errorf("failed to record row errors: %w", err)
+		}
+		imp.Failed += len(failures)
+	}
+
+	imp.ProcessedRows = processed
+	update := database.DB.Model(imp).
+		Where("status = ?", models.ImportProcessing).
+		Updates(map[string]interface{}{
+			"processed_rows": imp.ProcessedRows,
+			"imported":       imp.Imported,
+			"updated":        imp.Updated,
+			"duplicates":     imp.Duplicates,
+			"failed":         imp.Failed,
+			"heartbeat_at":   time.Now(),
+		})
+	if update.Error != nil {
+		return update.Error
+	}
+	if update.RowsAffected == 0 {
+		return errContactImportAbandoned
+	}
+	return nil
+}
+
+// countCSVRows returns the number of data rows in a CSV file, excluding the header
+func countCSVRows(path string) (int, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return 0, fmt.Errorf("failed to open file: %w", err)
+	}
+	defer file.Close()
+
+	reader := csv.NewReader(file)
+	reader.FieldsPerRecord = -1
+	reader.ReuseRecord = true
+
+	rows := -1
+	for {
+		_, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		rows++
+	}
+	if rows < 0 {
+		return 0, fmt.Errorf("file is empty")
+	}
+	return rows, nil
+}
+
+// field returns the trimmed value at idx, or an empty string if the row is too short
+func field(record []string, idx int) string {
+	if idx < 0 || idx >= len(record) {
+		return ""
+	}
+	return strings.TrimSpace(record[idx])
+}
+
+// importPhoneError describes an unparseable phone number for the error report
+func importPhoneError(err error) string {
+	var phoneErr *utils.PhoneError
+	if errors.As(err, &phoneErr) {
+		return "invalid phone number: " + phoneErr.Message
+	}
+	return "invalid phone number"
+}
@@ -0,0 +1,90 @@
+package service
+
+import (
+	"fmt"
+
+	"github.com/Ian-Balijawa/sms-gateway/database"
+	"github.com/Ian-Balijawa/sms-gateway/models"
+	"github.com/Ian-Balijawa/sms-gateway/utils"
+
+	"github.com/google/uuid"
+)
+
+// ContactTemplateVars returns the template variables available when messaging a contact.
+// Custom attributes are included by name; name and phone always refer to the contact fields.
+func ContactTemplateVars(contact *models.Contact) map[string]string {
+	vars := make(map[string]string, len(contact.Attributes)+2)
+	for key, value := range contact.Attributes {
+		vars[key] = value
+	}
+	vars["name"] = contact.Name
+	vars["phone"] = contact.Phone
+	return vars
+}
+
+// GroupMessages expands a client's group into one message per contact, rendering the
+// template for each contact. Contacts sharing a phone number receive a single message.
+func GroupMessages(clientID, groupID uuid.UUID, template, senderID, priority string) ([]models.SMSRequest, error) {
+	var contacts []models.Contact
+	if err := database.DB.
+		Joins("JOIN group_members ON group_members.contact_id = contacts.id").
+		Where("group_members.group_id = ? AND contacts.client_id = ?", groupID, clientID).
+		Order("contacts.created_at").
+		Find(&contacts).Error; err != nil {
+		return nil, err
+	}
+
+	messages := make([]models.SMSRequest, 0, len(contacts))
+	seen := make(map[string]bool, len(contacts))
+	for i := range contacts {
+		phone := utils.FormatPhone(contacts[i].Phone)
+		if seen[phone] {
+			continue
+		}
+		seen[phone] = true
+
+		messages = append(messages, models.SMSRequest{
+			Number:   phone,
+			Message:  utils.RenderTemplate(template, ContactTemplateVars(&contacts[i])),
+			SenderID: senderID,
+			Priority: priority,
+		})
+	}
+	return messages, nil
+}
+
+// AudienceMessages expands an optional contact group and a list of recipients into messages,
+// sending at most one message per normalized phone number. Recipients without a country
+// code are read in the given region and can only use the {{phone}} template variable.
+func AudienceMessages(clientID uuid.UUID, groupID *uuid.UUID, recipients []string, template, senderID, priority, region string) ([]models.SMSRequest, error) {
+	messages := make([]models.SMSRequest, 0, len(recipients))
+	if groupID != nil {
+		groupMessages, err := GroupMessages(clientID, *groupID, template, senderID, priority)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load group contacts: %w", err)
+		}
+		messages = append(messages, groupMessages...)
+	}
+
+	seen := make(map[string]bool, len(messages)+len(recipients))
+	for _, msg := range messages {
+		seen[msg.Number] = true
+	}
+	for _, recipient := range recipients {
+		phone := utils.FormatPhoneForRegion(recipient, region)
+		if seen[phone] {
+			continue
+		}
+		seen[phone] = true
+
+		messages = append(messages, models.SMSRequest{
+			Number: phone,
+			Message: utils.RenderTemplate(template, map[string]string{
+				"phone": phone,
+			}),
+			SenderID: senderID,
+			Priority: priority,
+		})
+	}
+	return messages, nil
+}
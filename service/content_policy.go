@@ -0,0 +1,240 @@
+package service
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/Ian-Balijawa/sms-gateway/database"
+	"github.com/Ian-Balijawa/sms-gateway/models"
+	"github.com/Ian-Balijawa/sms-gateway/utils"
+
+	"github.com/google/uuid"
+)
+
+// PolicyViolation is a content rule broken by a message
+type PolicyViolation struct {
+	RuleID uuid.UUID `json:"rule_id"`
+	Rule   string    `json:"rule"` // Rule name
+	Type   string    `json:"type"`
+	Detail string    `json:"detail"`
+}
+
+// ContentPolicy holds the content rules that apply to a client
+type ContentPolicy struct {
+	Mode  string // models.ContentPolicyReject or models.ContentPolicyFlag
+	rules []models.ContentRule
+
+	// Compiled banned word patterns, by rule ID
+	bannedWords map[uuid.UUID]*regexp.Regexp
+}
+
+// NormalizeContentRule checks a rule's type and settings, trimming its values and
+// lower-casing domains
+func NormalizeContentRule(rule *models.ContentRule) error {
+	rule.Name = strings.TrimSpace(rule.Name)
+	if rule.Name == "" {
+		return fmt.Errorf("name is required")
+	}
+
+	values := models.StringList{}
+	for _, value := range rule.Values {
+		if value = strings.TrimSpace(value); value != "" {
+			values = append(values, value)
+		}
+	}
+	rule.Values = values
+
+	switch rule.Type {
+	case models.ContentRuleBannedWords:
+		if len(rule.Values) == 0 {
+			return fmt.Errorf("values must list at least one word")
+		}
+	case models.ContentRuleDomainAllow, models.ContentRuleDomainDeny:
+		if len(rule.Values) == 0 {
+			return fmt.Errorf("values must list at least one domain")
+		}
+		for i, domain := range rule.Values {
+			host := utils.LinkHost(domain)
+			if host == "" || !strings.Contains(host, ".") {
+				return fmt.Errorf("invalid domain %q", domain)
+			}
+			rule.Values[i] = host
+		}
+	case models.ContentRuleMaxLength:
+		if rule.MaxLength <= 0 {
+			return fmt.Errorf("max_length must be a positive number of characters")
+		}
+	case models.ContentRuleOptOutFooter:
+		if len(rule.Values) == 0 {
+			rule.Values = models.StringList{"STOP"}
+		}
+	default:
+		return fmt.Errorf("invalid type %q, expected banned_words, domain_allow, domain_deny, max_length or opt_out_footer", rule.Type)
+	}
+
+	if rule.Type != models.ContentRuleMaxLength {
+		rule.MaxLength = 0
+	}
+	return nil
+}
+
+// LoadContentPolicy loads the global content rules and the client's own rules
+func LoadContentPolicy(client *models.APIClient) (*ContentPolicy, error) {
+	var rules []models.ContentRule
+	if err := database.DB.
+		Where("client_id = ? OR client_id IS NULL", client.ID).
+		Order("created_at").
+		Find(&rules).Error; err != nil {
+		return nil, err
+	}
+	return newContentPolicy(client.ContentPolicyMode, rules)
+}
+
+// newContentPolicy builds a policy enforcing the rules in the given mode, compiling
+// the banned word rules. Modes other than "flag" reject.
+func newContentPolicy(mode string, rules []models.ContentRule) (*ContentPolicy, error) {
+	policy := &ContentPolicy{
+		Mode:        mode,
+		rules:       rules,
+		bannedWords: make(map[uuid.UUID]*regexp.Regexp),
+	}
+	if policy.Mode != models.ContentPolicyFlag {
+		policy.Mode = models.ContentPolicyReject
+	}
+
+	for _, rule := range policy.rules {
+		if rule.Type != models.ContentRuleBannedWords || len(rule.Values) == 0 {
+			continue
+		}
+		words := make([]string, len(rule.Values))
+		for i, word := range rule.Values {
+			words[i] = regexp.QuoteMeta(word)
+		}
+		// Words only match on their own, not inside longer words
+		pattern, err := regexp.Compile(`(?i)(?:^|[^\pL\pN])(` + strings.Join(words, "|") + `)(?:$|[^\pL\pN])`)
+		if err != nil {
+			return nil, fmt.Errorf("invalid banned words in rule %s: %w", rule.ID, err)
+		}
+		policy.bannedWords[rule.ID] = pattern
+	}
+	return policy, nil
+}
+
+// Check returns the rules the message text breaks. The opt-out footer is only
+// required for marketing messages.
+func (p *ContentPolicy) Check(text string, marketing bool) []PolicyViolation {
+	var hosts []string
+	for _, link := range utils.FindLinks(text) {
+		if host := utils.LinkHost(text[link[0]:link[1]]); host != "" {
+			hosts = append(hosts, host)
+		}
+	}
+
+	var violations []PolicyViolation
+	for _, rule := range p.rules {
+		detail := ""
+		switch rule.Type {
+		case models.ContentRuleBannedWords:
+			if pattern := p.bannedWords[rule.ID]; pattern != nil {
+				if match := pattern.FindStringSubmatch(text); match != nil {
+					detail = fmt.Sprintf("contains banned word %q", match[1])
+				}
+			}
+		case models.ContentRuleDomainAllow:
+			for _, host := range hosts {
+				if !hostInDomains(host, rule.Values) {
+					detail = fmt.Sprintf("links to %s, which is not an allowed domain", host)
+					break
+				}
+			}
+		case models.ContentRuleDomainDeny:
+			for _, host := range hosts {
+				if hostInDomains(host, rule.Values) {
+					detail = fmt.Sprintf("links to blocked domain %s", host)
+					break
+				}
+			}
+		case models.ContentRuleMaxLength:
+			if length := utf8.RuneCountInString(text); rule.MaxLength > 0 && length > rule.MaxLength {
+				detail = fmt.Sprintf("is %d characters long, the limit is %d", length, rule.MaxLength)
+			}
+		case models.ContentRuleOptOutFooter:
+			if marketing && !containsAnyFold(text, rule.Values) {
+				detail = fmt.Sprintf("is a marketing message without an opt-out footer such as %s", strconv.Quote(rule.Values[0]))
+			}
+		}
+
+		if detail != "" {
+			violations = append(violations, PolicyViolation{
+				RuleID: rule.ID,
+				Rule:   rule.Name,
+				Type:   rule.Type,
+				Detail: detail,
+			})
+		}
+	}
+	return violations
+}
+
+// hostInDomains reports whether a host is one of the domains or a subdomain of one
+func hostInDomains(host string, domains []string) bool {
+	for _, domain := range domains {
+		if utils.HostInDomain(host, domain) {
+			return true
+		}
+	}
+	return false
+}
+
+// containsAnyFold reports whether the text contains one of the phrases, ignoring case
+func containsAnyFold(text string, phrases []string) bool {
+	text = strings.ToLower(text)
+	for _, phrase := range phrases {
+		if strings.Contains(text, strings.ToLower(phrase)) {
+			return true
+		}
+	}
+	return false
+}
+
+// applyContentPolicy checks pending messages against the client's content rules and returns the
+// messages that can be sent. Messages breaking a rule are logged as rejected, or sent and flagged
+// when the client's content policy mode is "flag". Campaign messages count as marketing.
+func (d *Dispatcher) applyContentPolicy(req DispatchRequest, logs []models.SMSLog, pending []int) ([]int, error) {
+	if len(pending) == 0 {
+		return pending, nil
+	}
+	policy, err := LoadContentPolicy(req.Client)
+	if err != nil {
+		return nil, err
+	}
+
+	remaining := pending[:0]
+	for _, i := range pending {
+		marketing := req.Messages[i].Marketing || req.CampaignID != nil
+		violations := policy.Check(logs[i].Message, marketing)
+		if len(violations) == 0 {
+			remaining = append(remaining, i)
+			continue
+		}
+
+		details := make([]string, len(violations))
+		logs[i].PolicyViolations = make(models.StringList, len(violations))
+		for j, violation := range violations {
+			details[j] = fmt.Sprintf("%s (rule %s)", violation.Detail, violation.RuleID)
+			logs[i].PolicyViolations[j] = violation.RuleID.String()
+		}
+
+		if policy.Mode == models.ContentPolicyFlag {
+			logs[i].PolicyFlagged = true
+			remaining = append(remaining, i)
+			continue
+		}
+		logs[i].Status = models.SMSStatusRejected
+		logs[i].Error = "content policy: message " + strings.Join(details, "; ")
+	}
+	return remaining, nil
+}
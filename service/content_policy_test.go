@@ -0,0 +1,105 @@
+package service
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/Ian-Balijawa/sms-gateway/models"
+
+	"github.com/google/uuid"
+)
+
+func TestContentPolicyCheck(t *testing.T) {
+	rule := func(name, ruleType string, maxLength int, values ...string) models.ContentRule {
+		return models.ContentRule{ID: uuid.New(), Name: name, Type: ruleType, Values: values, MaxLength: maxLength}
+	}
+	rules := map[string]models.ContentRule{
+		"banned":  rule("banned", models.ContentRuleBannedWords, 0, "casino", "free bet", "a+b"),
+		"allow":   rule("allow", models.ContentRuleDomainAllow, 0, "example.com"),
+		"deny":    rule("deny", models.ContentRuleDomainDeny, 0, "bit.ly"),
+		"length":  rule("length", models.ContentRuleMaxLength, 20),
+		"opt-out": rule("opt-out", models.ContentRuleOptOutFooter, 0, "STOP", "Opt out"),
+	}
+
+	tests := []struct {
+		name      string
+		rules     []string
+		text      string
+		marketing bool
+		broken    []string // Names of the rules broken, in rule order
+	}{
+		{name: "no rules", text: "Visit the casino at bit.ly/x"},
+		{name: "banned word", rules: []string{"banned"}, text: "Big win at the Casino tonight", broken: []string{"banned"}},
+		{name: "banned phrase", rules: []string{"banned"}, text: "Claim your free bet now", broken: []string{"banned"}},
+		{name: "banned word at start", rules: []string{"banned"}, text: "casino", broken: []string{"banned"}},
+		{name: "banned word inside longer word", rules: []string{"banned"}, text: "Casinos and casinoville are fine"},
+		{name: "banned word with punctuation", rules: []string{"banned"}, text: "Try a+b today", broken: []string{"banned"}},
+		{name: "banned word split", rules: []string{"banned"}, text: "free and bet"},
+		{name: "allowed domain", rules: []string{"allow"}, text: "See https://example.com/offer"},
+		{name: "allowed subdomain", rules: []string{"allow"}, text: "See https://shop.EXAMPLE.com/offer"},
+		{name: "other domain", rules: []string{"allow"}, text: "See https://example.com.evil.io/offer", broken: []string{"allow"}},
+		{name: "one link outside allowed domains", rules: []string{"allow"}, text: "example.com/a or other.org/b", broken: []string{"allow"}},
+		{name: "no links with allow rule", rules: []string{"allow"}, text: "No links here"},
+		{name: "denied domain", rules: []string{"deny"}, text: "Go to bit.ly/abc", broken: []string{"deny"}},
+		{name: "denied domain with scheme", rules: []string{"deny"}, text: "Go to https://BIT.LY/abc", broken: []string{"deny"}},
+		{name: "domain containing denied name", rules: []string{"deny"}, text: "Go to https://notbit.ly/abc"},
+		{name: "at max length", rules: []string{"length"}, text: "12345678901234567890"},
+		{name: "over max length", rules: []string{"length"}, text: "123456789012345678901", broken: []string{"length"}},
+		{name: "length counts characters", rules: []string{"length"}, text: "ééééééééééééééééééé"},
+		{name: "marketing without footer", rules: []string{"opt-out"}, text: "Sale today", marketing: true, broken: []string{"opt-out"}},
+		{name: "marketing with footer", rules: []string{"opt-out"}, text: "Sale today. Reply stop to opt out", marketing: true},
+		{name: "marketing with other footer", rules: []string{"opt-out"}, text: "Sale today. OPT OUT: 123", marketing: true},
+		{name: "transactional without footer", rules: []string{"opt-out"}, text: "Your code is 1234"},
+		{
+			name:      "several rules broken",
+			rules:     []string{"banned", "allow", "deny", "length", "opt-out"},
+			text:      "Casino bonus at bit.ly/win",
+			marketing: true,
+			broken:    []string{"banned", "allow", "deny", "length", "opt-out"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var policyRules []models.ContentRule
+			for _, name := range tt.rules {
+				policyRules = append(policyRules, rules[name])
+			}
+			policy, err := newContentPolicy(models.ContentPolicyReject, policyRules)
+			if err != nil {
+				t.Fatalf("newContentPolicy returned error: %v", err)
+			}
+
+			var broken []string
+			for _, violation := range policy.Check(tt.text, tt.marketing) {
+				if violation.RuleID != rules[violation.Rule].ID || violation.Type != rules[violation.Rule].Type {
+					t.Errorf("violation %+v does not match its rule", violation)
+				}
+				if violation.Detail == "" {
+					t.Errorf("violation of %s has no detail", violation.Rule)
+				}
+				broken = append(broken, violation.Rule)
+			}
+			if !reflect.DeepEqual(broken, tt.broken) {
+				t.Errorf("Check(%q) broke %v, want %v", tt.text, broken, tt.broken)
+			}
+		})
+	}
+}
+
+func TestNewContentPolicyMode(t *testing.T) {
+	for mode, want := range map[string]string{
+		models.ContentPolicyFlag:   models.ContentPolicyFlag,
+		models.ContentPolicyReject: models.ContentPolicyReject,
+		"":                         models.ContentPolicyReject,
+		"unknown":                  models.ContentPolicyReject,
+	} {
+		policy, err := newContentPolicy(mode, nil)
+		if err != nil {
+			t.Fatalf("newContentPolicy(%q) returned error: %v", mode, err)
+		}
+		if policy.Mode != want {
+			t.Errorf("newContentPolicy(%q).Mode = %q, want %q", mode, policy.Mode, want)
+		}
+	}
+}
@@ -0,0 +1,194 @@
+package service
+
+import (
+	"sort"
+	"time"
+
+	"github.com/Ian-Balijawa/sms-gateway/database"
+	"github.com/Ian-Balijawa/sms-gateway/models"
+
+	"github.com/google/uuid"
+)
+
+// conversationStatuses are the statuses of outbound messages that reached the provider. Messages
+// that were rejected, suppressed, blocked, failed or are still waiting to be sent are not part of
+// a conversation.
+var conversationStatuses = []string{models.SMSStatusSent, models.SMSStatusUnknown}
+
+// conversationsQuery groups a client's sent and received messages by counterpart number,
+// most recently active first
+const conversationsQuery = `
+SELECT phone,
+	COUNT(*) AS message_count,
+	SUM(outbound) AS outbound_count,
+	SUM(1 - outbound) AS inbound_count,
+	SUM(unread) AS unread_count
+FROM (
+	SELECT recipient AS phone, created_at AS at, 1 AS outbound, 0 AS unread
+	FROM sms_logs WHERE client_id = @client AND status IN @statuses
+	UNION ALL
+	SELECT "from" AS phone, received_at AS at, 0 AS outbound, CASE WHEN read_at IS NULL THEN 1 ELSE 0 END AS unread
+	FROM inbound_messages WHERE client_id = @client
+) messages
+GROUP BY phone
+HAVING @all OR SUM(unread) > 0
+ORDER BY MAX(at) DESC
+LIMIT @limit OFFSET @offset`
+
+// ListConversations returns a page of the client's conversations, most recently active first.
+// With unreadOnly set, only conversations with unread inbound messages are returned.
+func ListConversations(clientID uuid.UUID, unreadOnly bool, limit, offset int) ([]models.Conversation, error) {
+	conversations := make([]models.Conversation, 0, limit)
+	if err := database.DB.Raw(conversationsQuery, map[string]interface{}{
+		"client":   clientID,
+		"statuses": conversationStatuses,
+		"all":      !unreadOnly,
+		"limit":    limit,
+		"offset":   offset,
+	}).Scan(&conversations).Error; err != nil {
+		return nil, err
+	}
+	if len(conversations) == 0 {
+		return conversations, nil
+	}
+
+	phones := make([]string, len(conversations))
+	for i := range conversations {
+		phones[i] = conversations[i].Phone
+	}
+	var contacts []models.Contact
+	if err := database.DB.Select("phone", "name").Where("client_id = ? AND phone IN ?", clientID, phones).Find(&contacts).Error; err != nil {
+		return nil, err
+	}
+	names := make(map[string]string, len(contacts))
+	for _, contact := range contacts {
+		names[contact.Phone] = contact.Name
+	}
+
+	latest, err := latestConversationMessages(clientID, phones)
+	if err != nil {
+		return nil, err
+	}
+	for i := range conversations {
+		conversations[i].ContactName = names[conversations[i].Phone]
+		if msg, ok := latest[conversations[i].Phone]; ok {
+			conversations[i].LastMessage = &msg
+		}
+	}
+	return conversations, nil
+}
+
+// latestConversationMessages returns the most recent message exchanged with each of the numbers
+func latestConversationMessages(clientID uuid.UUID, phones []string) (map[string]models.ConversationMessage, error) {
+	var sent []models.SMSLog
+	if err := database.DB.
+		Select("id", "recipient", "message", "status", "sender_id", "created_at").
+		Where("client_id = ? AND recipient IN ? AND status IN ?", clientID, phones, conversationStatuses).
+		Where("created_at = (SELECT MAX(latest.created_at) FROM sms_logs latest "+
+			"WHERE latest.client_id = sms_logs.client_id AND latest.recipient = sms_logs.recipient AND latest.status IN ?)",
+			conversationStatuses).
+		Find(&sent).Error; err != nil {
+		return nil, err
+	}
+
+	var received []models.InboundMessage
+	if err := database.DB.
+		Select("id", "from", "text", "status", "received_at", "read_at").
+		Where("client_id = ? AND \"from\" IN ?", clientID, phones).
+		Where("received_at = (SELECT MAX(latest.received_at) FROM inbound_messages latest " +
+			"WHERE latest.client_id = inbound_messages.client_id AND latest.\"from\" = inbound_messages.\"from\")").
+		Find(&received).Error; err != nil {
+		return nil, err
+	}
+
+	latest := make(map[string]models.ConversationMessage, len(phones))
+	keep := func(msg models.ConversationMessage) {
+		if current, ok := latest[msg.Phone]; !ok || msg.At.After(current.At) {
+			latest[msg.Phone] = msg
+		}
+	}
+	for _, smsLog := range sent {
+		keep(outboundConversationMessage(smsLog))
+	}
+	for _, msg := range received {
+		keep(inboundConversationMessage(msg))
+	}
+	return latest, nil
+}
+
+// ConversationMessages returns a page of the messages exchanged with a number, newest first
+func ConversationMessages(clientID uuid.UUID, phone string, limit, offset int) ([]models.ConversationMessage, error) {
+	// The newest limit+offset messages of each direction are enough to build the page
+	window := limit + offset
+
+	var sent []models.SMSLog
+	if err := database.DB.
+		Select("id", "recipient", "message", "status", "sender_id", "created_at").
+		Where("client_id = ? AND recipient = ? AND status IN ?", clientID, phone, conversationStatuses).
+		Order("created_at DESC").
+		Limit(window).
+		Find(&sent).Error; err != nil {
+		return nil, err
+	}
+
+	var received []models.InboundMessage
+	if err := database.DB.
+		Select("id", "from", "text", "status", "received_at", "read_at").
+		Where("client_id = ? AND \"from\" = ?", clientID, phone).
+		Order("received_at DESC").
+		Limit(window).
+		Find(&received).Error; err != nil {
+		return nil, err
+	}
+
+	messages := make([]models.ConversationMessage, 0, len(sent)+len(received))
+	for _, smsLog := range sent {
+		messages = append(messages, outboundConversationMessage(smsLog))
+	}
+	for _, msg := range received {
+		messages = append(messages, inboundConversationMessage(msg))
+	}
+	sort.SliceStable(messages, func(i, j int) bool {
+		return messages[i].At.After(messages[j].At)
+	})
+
+	if offset >= len(messages) {
+		return []models.ConversationMessage{}, nil
+	}
+	return messages[offset:min(offset+limit, len(messages))], nil
+}
+
+// outboundConversationMessage describes a sent message in a conversation
+func outboundConversationMessage(smsLog models.SMSLog) models.ConversationMessage {
+	return models.ConversationMessage{
+		ID:        smsLog.ID,
+		Direction: models.DirectionOutbound,
+		Phone:     smsLog.Recipient,
+		Text:      smsLog.Message,
+		Status:    smsLog.Status,
+		SenderID:  smsLog.SenderID,
+		At:        smsLog.CreatedAt,
+	}
+}
+
+// inboundConversationMessage describes a received message in a conversation
+func inboundConversationMessage(msg models.InboundMessage) models.ConversationMessage {
+	return models.ConversationMessage{
+		ID:        msg.ID,
+		Direction: models.DirectionInbound,
+		Phone:     msg.From,
+		Text:      msg.Text,
+		Status:    msg.Status,
+		At:        msg.ReceivedAt,
+		ReadAt:    msg.ReadAt,
+	}
+}
+
+// MarkConversationRead marks every unread inbound message from a number as read
+// and returns how many were marked
+func MarkConversationRead(clientID uuid.UUID, phone string) (int64, error) {
+	result := database.DB.Model(&models.InboundMessage{}).
+		Where("client_id = ? AND \"from\" = ? AND read_at IS NULL", clientID, phone).
+		Update("read_at", time.Now())
+	return result.RowsAffected, result.Error
+}
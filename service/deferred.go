@@ -0,0 +1,143 @@
+package service
+
+import (
+	"log"
+	"time"
+
+	"github.com/Ian-Balijawa/sms-gateway/database"
+	"github.com/Ian-Balijawa/sms-gateway/models"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	// deferredPollInterval is how often the dispatcher looks for deferred messages that are due
+	deferredPollInterval = 30 * time.Second
+
+	// deferredBatchSize is the maximum number of deferred messages loaded at a time
+	deferredBatchSize = 500
+)
+
+// StartDeferredSender sends deferred messages in a background goroutine once their scheduled time has passed
+func (d *Dispatcher) StartDeferredSender() {
+	go func() {
+		for {
+			// Keep going without waiting while full batches are due
+			if d.sendDueDeferred(time.Now()) < deferredBatchSize {
+				time.Sleep(deferredPollInterval)
+			}
+		}
+	}()
+
+	log.Println("Deferred message sender started")
+}
+
+// sendDueDeferred sends one batch of due deferred messages and returns the batch size
+func (d *Dispatcher) sendDueDeferred(now time.Time) int {
+	var due []models.SMSLog
+	if err := database.DB.
+		Where("status = ? AND scheduled_at <= ?", models.SMSStatusDeferred, now.UTC()).
+		Order("scheduled_at").
+		Limit(deferredBatchSize).
+		Find(&due).Error; err != nil {
+		log.Printf("Error loading deferred messages: %v", err)
+		return 0
+	}
+
+	byClient := make(map[uuid.UUID][]models.SMSLog)
+	for _, smsLog := range claimLogs(due, models.SMSStatusDeferred) {
+		byClient[smsLog.ClientID] = append(byClient[smsLog.ClientID], smsLog)
+	}
+
+	for clientID, logs := range byClient {
+		d.sendDeferredForClient(clientID, logs)
+	}
+	return len(due)
+}
+
+// claimLogs moves log entries from status to pending one at a time, so that each message is only
+// sent once even if it is cancelled or claimed elsewhere in the meantime. It returns the entries
+// that were claimed.
+func claimLogs(logs []models.SMSLog, status string) []models.SMSLog {
+	claimed := logs[:0]
+	for _, smsLog := range logs {
+		claim := database.DB.Model(&models.SMSLog{}).
+			Where("id = ? AND status = ?", smsLog.ID, status).
+			Update("status", models.SMSStatusPending)
+		if claim.Error != nil {
+			log.Printf("Error claiming message %s: %v", smsLog.ID, claim.Error)
+			continue
+		}
+		if claim.RowsAffected == 1 {
+			smsLog.Status = models.SMSStatusPending
+			claimed = append(claimed, smsLog)
+		}
+	}
+	return claimed
+}
+
+// sendDeferredForClient sends a client's claimed deferred messages and updates their logs
+func (d *Dispatcher) sendDeferredForClient(clientID uuid.UUID, logs []models.SMSLog) {
+	var client models.APIClient
+	if err := database.DB.Where("id = ?", clientID).First(&client).Error; err != nil || !client.IsActive {
+		for i := range logs {
+			logs[i].Status = models.SMSStatusFailed
+			logs[i].Error = "client is inactive"
+		}
+		d.saveClaimed(logs)
+		return
+	}
+
+	d.sendClaimed(&client, logs, models.SMSStatusDeferred)
+}
+
+// sendClaimed sends log entries created by an earlier request and claimed for sending
+// (deferred or queued messages), saves their outcome and records usage.
+// Recipients may have opted out and sender IDs may have been revoked while the message was
+// waiting, so both are checked again; if they cannot be checked the entries are returned to
+// retryStatus.
+// Queued (campaign) messages are screened for fraud here, as they are sent, so that the
+// velocity checks see the campaign's actual sending pace. Deferred messages are not screened
+// again: they were screened when they were accepted, or held by the fraud guard for review.
+func (d *Dispatcher) sendClaimed(client *models.APIClient, logs []models.SMSLog, retryStatus string) {
+	pending := make([]int, len(logs))
+	for i := range logs {
+		pending[i] = i
+	}
+
+	remaining, err := d.resolveSenderIDs(client, logs, pending)
+	if err == nil {
+		remaining, err = d.filterSuppressed(client, logs, remaining)
+	}
+	if err == nil && retryStatus == models.SMSStatusQueued {
+		remaining, err = d.screenFraud(client, logs, remaining, time.Now())
+	}
+	if err != nil {
+		log.Printf("Error checking sender IDs, suppression list and fraud guard for claimed messages: %v", err)
+		for i := range logs {
+			logs[i].Status = retryStatus
+		}
+	} else if err := d.send(client, logs, remaining); err != nil {
+		log.Printf("Error sending claimed messages for client %s: %v", client.ID, err)
+	}
+
+	d.saveClaimed(logs)
+
+	sent := 0
+	for i := range logs {
+		if logs[i].Status == models.SMSStatusSent || logs[i].Status == models.SMSStatusUnknown {
+			sent++
+		}
+	}
+	d.recordUsage(client, sent)
+}
+
+// saveClaimed saves the outcome of claimed log entries
+func (d *Dispatcher) saveClaimed(logs []models.SMSLog) {
+	for i := range logs {
+		if err := database.DB.Omit(clause.Associations).Save(&logs[i]).Error; err != nil {
+			log.Printf("Error updating message %s: %v", logs[i].ID, err)
+		}
+	}
+}
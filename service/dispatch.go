@@ -0,0 +1,455 @@
+package service
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Ian-Balijawa/sms-gateway/config"
+	"github.com/Ian-Balijawa/sms-gateway/database"
+	"github.com/Ian-Balijawa/sms-gateway/models"
+	"github.com/Ian-Balijawa/sms-gateway/utils"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Dispatcher sends messages on behalf of a client and records every message in SMSLog.
+// It is shared by the HTTP handlers and the background schedulers so that all
+// outbound traffic goes through the same path. Messages reach the provider through
+// priority lanes, see LaneScheduler.
+type Dispatcher struct {
+	provider Provider
+	lanes    *LaneScheduler
+}
+
+func NewDispatcher(provider Provider) *Dispatcher {
+	return &Dispatcher{
+		provider: provider,
+		lanes:    NewLaneScheduler(provider),
+	}
+}
+
+// Lanes returns the priority lanes messages are sent through
+func (d *Dispatcher) Lanes() *LaneScheduler {
+	return d.lanes
+}
+
+// DispatchRequest describes a batch of messages sent for a single client
+type DispatchRequest struct {
+	Client   *models.APIClient
+	Messages []models.SMSRequest
+
+	// Request metadata recorded on each log entry
+	IPAddress string
+	UserAgent string
+
+	// Bulk sends (bulk API requests, group sends) use the bulk lane, except for transactional messages
+	Bulk bool
+
+	// Origin of the batch, if not sent directly through the API
+	RecurringJobID *uuid.UUID
+	RecurringRunID *uuid.UUID
+	CampaignID     *uuid.UUID
+}
+
+// DispatchResult holds the log entries created for a batch, in request order
+type DispatchResult struct {
+	Logs       []models.SMSLog
+	Successful int
+	Failed     int
+	Suppressed int
+	Deferred   int
+	Rejected   int
+	Unknown    int // Accepted by the provider without a per-message result
+	Queued     int
+	Blocked    int // Stopped by the fraud guard
+}
+
+// Dispatch sends the messages through the provider and logs each one.
+// Invalid recipients, sender IDs the client may not use and messages breaking the client's
+// content policy are logged as rejected, recipients on a suppression list are logged as
+// suppressed and not sent, messages the fraud guard scores as suspicious are blocked or deferred for
+// review, and messages to recipients in quiet hours are deferred until the window ends.
+// Links in messages asking for it are replaced with short links before anything is sent; the
+// links are deleted again if the dispatch fails or the logs cannot be saved.
+// If the provider cannot be reached every sendable message is logged as failed and the error is returned.
+func (d *Dispatcher) Dispatch(req DispatchRequest) (*DispatchResult, error) {
+	logs := make([]models.SMSLog, len(req.Messages))
+	pending := make([]int, len(req.Messages))
+	for i, msg := range req.Messages {
+		logs[i] = d.newLog(req, msg)
+		pending[i] = i
+	}
+
+	pending = d.rejectInvalid(req, logs, pending)
+
+	pending, err := d.resolveSenderIDs(req.Client, logs, pending)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check sender IDs: %w", err)
+	}
+
+	pending, err = d.applyContentPolicy(req, logs, pending)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check content policy: %w", err)
+	}
+
+	pending, err = d.filterSuppressed(req.Client, logs, pending)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check suppression list: %w", err)
+	}
+
+	pending, err = d.shortenLinks(req, logs, pending)
+	if err != nil {
+		discardShortLinks(req, logs)
+		return nil, fmt.Errorf("failed to shorten links: %w", err)
+	}
+
+	now := time.Now()
+	pending, err = d.screenFraud(req.Client, logs, pending, now)
+	if err != nil {
+		discardShortLinks(req, logs)
+		return nil, fmt.Errorf("failed to screen messages for fraud: %w", err)
+	}
+
+	pending = d.deferQuietHours(req, logs, pending, now)
+
+	sendErr := d.send(req.Client, logs, pending)
+
+	result, err := d.saveResult(logs)
+	if err != nil {
+		log.Printf("Error saving SMS logs: %v", err)
+		discardShortLinks(req, logs)
+	}
+
+	// Messages with an unknown outcome were accepted by the provider and count towards usage
+	d.recordUsage(req.Client, result.Successful+result.Unknown)
+
+	return result, sendErr
+}
+
+// Queue logs the messages as queued without sending them, for a campaign to send later.
+// Invalid recipients and sender IDs and messages breaking the content policy are logged as
+// rejected and suppressed recipients as suppressed straight away, and messages to recipients
+// in quiet hours are scheduled for the end of the window. Links are shortened when queued.
+// Queued messages are screened for fraud when they are sent, see sendClaimed.
+func (d *Dispatcher) Queue(req DispatchRequest) (*DispatchResult, error) {
+	logs := make([]models.SMSLog, len(req.Messages))
+	pending := make([]int, len(req.Messages))
+	for i, msg := range req.Messages {
+		logs[i] = d.newLog(req, msg)
+		pending[i] = i
+	}
+
+	pending = d.rejectInvalid(req, logs, pending)
+
+	pending, err := d.resolveSenderIDs(req.Client, logs, pending)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check sender IDs: %w", err)
+	}
+
+	pending, err = d.applyContentPolicy(req, logs, pending)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check content policy: %w", err)
+	}
+
+	pending, err = d.filterSuppressed(req.Client, logs, pending)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check suppression list: %w", err)
+	}
+
+	pending, err = d.shortenLinks(req, logs, pending)
+	if err != nil {
+		discardShortLinks(req, logs)
+		return nil, fmt.Errorf("failed to shorten links: %w", err)
+	}
+
+	now := time.Now()
+	for _, i := range pending {
+		logs[i].Status = models.SMSStatusQueued
+		if until, quiet := quietHoursUntil(req.Client, req.Messages[i], logs[i].Recipient, now); quiet {
+			until = until.UTC()
+			logs[i].ScheduledAt = &until
+		}
+	}
+
+	result, err := d.saveResult(logs)
+	if err != nil {
+		discardShortLinks(req, logs)
+	}
+	return result, err
+}
+
+// saveResult saves new log entries and counts them by status.
+// The counts are returned even if the entries could not be saved.
+func (d *Dispatcher) saveResult(logs []models.SMSLog) (*DispatchResult, error) {
+	var err error
+	if len(logs) > 0 {
+		err = database.DB.CreateInBatches(&logs, 100).Error
+	}
+
+	result := &DispatchResult{Logs: logs}
+	for _, smsLog := range logs {
+		switch smsLog.Status {
+		case models.SMSStatusSent:
+			result.Successful++
+		case models.SMSStatusSuppressed:
+			result.Suppressed++
+		case models.SMSStatusDeferred:
+			result.Deferred++
+		case models.SMSStatusRejected:
+			result.Rejected++
+		case models.SMSStatusUnknown:
+			result.Unknown++
+		case models.SMSStatusQueued:
+			result.Queued++
+		case models.SMSStatusBlocked:
+			result.Blocked++
+		default:
+			result.Failed++
+		}
+	}
+
+	return result, err
+}
+
+// rejectInvalid marks pending messages to recipients that cannot be parsed and returns the rest
+func (d *Dispatcher) rejectInvalid(req DispatchRequest, logs []models.SMSLog, pending []int) []int {
+	remaining := pending[:0]
+	for _, i := range pending {
+		if _, err := utils.ParsePhone(req.Messages[i].Number, req.Client.DefaultRegion); err != nil {
+			logs[i].Status = models.SMSStatusRejected
+			logs[i].Error = err.Error()
+			continue
+		}
+		remaining = append(remaining, i)
+	}
+	return remaining
+}
+
+// filterSuppressed marks pending messages to suppressed recipients and returns the rest
+func (d *Dispatcher) filterSuppressed(client *models.APIClient, logs []models.SMSLog, pending []int) ([]int, error) {
+	phones := make([]string, 0, len(pending))
+	for _, i := range pending {
+		phones = append(phones, logs[i].Recipient)
+	}
+
+	suppressed, err := SuppressedNumbers(client.ID, phones)
+	if err != nil {
+		return nil, err
+	}
+
+	remaining := pending[:0]
+	for _, i := range pending {
+		if suppressed[logs[i].Recipient] {
+			logs[i].Status = models.SMSStatusSuppressed
+			logs[i].Error = "recipient is on the suppression list"
+			continue
+		}
+		remaining = append(remaining, i)
+	}
+	return remaining, nil
+}
+
+// deferQuietHours holds back messages to recipients currently in quiet hours, scheduling
+// them for the end of the window, and returns the messages that can be sent now
+func (d *Dispatcher) deferQuietHours(req DispatchRequest, logs []models.SMSLog, pending []int, now time.Time) []int {
+	remaining := pending[:0]
+	for _, i := range pending {
+		if until, quiet := quietHoursUntil(req.Client, req.Messages[i], logs[i].Recipient, now); quiet {
+			// Stored in UTC so that due messages compare correctly in every database
+			until = until.UTC()
+			logs[i].Status = models.SMSStatusDeferred
+			logs[i].ScheduledAt = &until
+			continue
+		}
+		remaining = append(remaining, i)
+	}
+	return remaining
+}
+
+// quietHoursUntil reports whether the recipient is in quiet hours and when they end.
+// Transactional messages and recipients in unknown countries are never deferred.
+func quietHoursUntil(client *models.APIClient, msg models.SMSRequest, recipient string, now time.Time) (time.Time, bool) {
+	if msg.Priority == models.PriorityTransactional {
+		return time.Time{}, false
+	}
+
+	start, end := client.QuietHoursStart, client.QuietHoursEnd
+	if msg.QuietHours != nil {
+		start, end = msg.QuietHours.Start, msg.QuietHours.End
+	}
+	if start == "" || end == "" {
+		return time.Time{}, false
+	}
+
+	country, ok := utils.CountryForPhone(recipient)
+	if !ok {
+		return time.Time{}, false
+	}
+	location, err := time.LoadLocation(country.Timezone)
+	if err != nil {
+		return time.Time{}, false
+	}
+
+	until, quiet, err := utils.QuietHoursEnd(now, location, start, end)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return until, quiet
+}
+
+// send charges the pending messages to the client's wallet, submits them to the provider through
+// their lanes and records the outcome on their logs. Messages the provider fails to send are refunded.
+// An error is returned if no message reached the provider.
+func (d *Dispatcher) send(client *models.APIClient, logs []models.SMSLog, pending []int) error {
+	pending = d.charge(client, logs, pending)
+	if len(pending) == 0 {
+		return nil
+	}
+
+	// Lanes are sent concurrently, each as fast as its limits allow
+	byLane := make(map[string][]int)
+	for _, i := range pending {
+		if logs[i].Lane == "" {
+			// Logged before lanes existed
+			logs[i].Lane = MessageLane(DispatchRequest{CampaignID: logs[i].CampaignID, RecurringJobID: logs[i].RecurringJobID}, models.SMSRequest{Priority: logs[i].Priority})
+		}
+		byLane[logs[i].Lane] = append(byLane[logs[i].Lane], i)
+	}
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	var sendErr error
+	reached := false
+	for lane, indexes := range byLane {
+		wg.Add(1)
+		go func(lane string, indexes []int) {
+			defer wg.Done()
+			err := d.sendLane(lane, logs, indexes)
+
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				sendErr = err
+			} else {
+				reached = true
+			}
+		}(lane, indexes)
+	}
+	wg.Wait()
+
+	d.refundFailed(client, logs, pending)
+	if reached {
+		return nil
+	}
+	return sendErr
+}
+
+// sendLane submits messages of one lane to the provider and records the outcome on their logs
+func (d *Dispatcher) sendLane(lane string, logs []models.SMSLog, pending []int) error {
+	messages := make([]models.SMSRequest, len(pending))
+	for j, i := range pending {
+		text := logs[i].Message
+		if logs[i].OTPText != "" {
+			text = logs[i].OTPText
+		}
+		messages[j] = models.SMSRequest{
+			Number:   logs[i].Recipient,
+			Message:  text,
+			SenderID: logs[i].SenderID,
+			Priority: logs[i].Priority,
+		}
+	}
+
+	results, err := d.lanes.Send(lane, messages, config.AppConfig.SMSSenderID)
+	if err != nil {
+		for _, i := range pending {
+			logs[i].Status = models.SMSStatusFailed
+			logs[i].Provider = d.provider.Name()
+			logs[i].ProviderStatus = "error"
+			logs[i].Error = err.Error()
+		}
+		return err
+	}
+
+	sentAt := time.Now()
+	for j, i := range pending {
+		result := results[j]
+		logs[i].Provider = d.provider.Name()
+		logs[i].ProviderCost = walletCost(result.Cost)
+		logs[i].ProviderStatus = result.Status
+		logs[i].ProviderMessage = result.Message
+		logs[i].ProviderMessageID = result.MessageID
+
+		switch result.Result {
+		case ProviderResultSent:
+			logs[i].Status = models.SMSStatusSent
+			logs[i].SentAt = &sentAt
+			if logs[i].ProviderMessage == "" {
+				logs[i].ProviderMessage = "SMS sent successfully"
+			}
+		case ProviderResultUnknown:
+			logs[i].Status = models.SMSStatusUnknown
+			logs[i].SentAt = &sentAt
+		default:
+			logs[i].Status = models.SMSStatusFailed
+			logs[i].Error = result.Message
+		}
+	}
+	return nil
+}
+
+// newLog creates the log entry for a message before it is sent
+func (d *Dispatcher) newLog(req DispatchRequest, msg models.SMSRequest) models.SMSLog {
+	// Invalid numbers are rejected later and logged as given
+	recipient := strings.TrimSpace(msg.Number)
+	if parsed, err := utils.ParsePhone(msg.Number, req.Client.DefaultRegion); err == nil {
+		recipient = parsed.E164
+	}
+	operator, lineType, _ := utils.LookupOperator(recipient)
+
+	smsLog := models.SMSLog{
+		ID:             uuid.New(),
+		CreatedAt:      time.Now(),
+		ClientID:       req.Client.ID,
+		Recipient:      recipient,
+		Operator:       operator,
+		LineType:       lineType,
+		Message:        msg.Message,
+		Segments:       utils.MessageSegments(msg.Message),
+		SenderID:       msg.SenderID,
+		Priority:       msg.Priority,
+		Lane:           MessageLane(req, msg),
+		Status:         models.SMSStatusPending,
+		RecurringJobID: req.RecurringJobID,
+		RecurringRunID: req.RecurringRunID,
+		CampaignID:     req.CampaignID,
+		IPAddress:      req.IPAddress,
+		UserAgent:      req.UserAgent,
+	}
+	if msg.OTPCode != "" {
+		smsLog.OTPText = msg.Message
+		smsLog.Message = strings.ReplaceAll(msg.Message, msg.OTPCode, strings.Repeat("*", len(msg.OTPCode)))
+	}
+	return smsLog
+}
+
+// recordUsage increments the client's usage counters for messages handed to the provider
+func (d *Dispatcher) recordUsage(client *models.APIClient, sent int) {
+	if sent == 0 {
+		return
+	}
+
+	database.DB.Model(&models.APIClient{}).
+		Where("id = ?", client.ID).
+		Updates(map[string]interface{}{
+			"daily_usage":   gorm.Expr("daily_usage + ?", sent),
+			"monthly_usage": gorm.Expr("monthly_usage + ?", sent),
+		})
+
+	client.DailyUsage += sent
+	client.MonthlyUsage += sent
+}
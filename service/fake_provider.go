@@ -0,0 +1,48 @@
+package service
+
+import (
+	"log"
+
+	"github.com/Ian-Balijawa/sms-gateway/config"
+	"github.com/Ian-Balijawa/sms-gateway/models"
+
+	"github.com/google/uuid"
+)
+
+// FakeProvider accepts every message without delivering it, for running the gateway offline
+type FakeProvider struct{}
+
+func NewFakeProvider() *FakeProvider {
+	return &FakeProvider{}
+}
+
+// Name identifies the provider on message logs
+func (p *FakeProvider) Name() string {
+	return "fake"
+}
+
+// MaxBatchSize follows SMS_BATCH_SIZE, so that sends are batched as they would be with a real provider
+func (p *FakeProvider) MaxBatchSize() int {
+	return max(config.AppConfig.SMSBatchSize, 1)
+}
+
+// SendSMS logs the messages and reports each one as sent with a generated message ID
+func (p *FakeProvider) SendSMS(messages []models.SMSRequest, defaultSenderID string) ([]SMSProviderResult, error) {
+	results := make([]SMSProviderResult, len(messages))
+	for i, msg := range messages {
+		senderID := msg.SenderID
+		if senderID == "" {
+			senderID = defaultSenderID
+		}
+		messageID := "fake-" + uuid.New().String()
+		log.Printf("Fake provider: id=%s to=%s sender=%s message=%q", messageID, msg.Number, senderID, msg.Message)
+
+		results[i] = SMSProviderResult{
+			Result:    ProviderResultSent,
+			Status:    "Success",
+			Message:   "accepted by fake provider",
+			MessageID: messageID,
+		}
+	}
+	return results, nil
+}
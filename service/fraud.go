@@ -0,0 +1,298 @@
+package service
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Ian-Balijawa/sms-gateway/config"
+	"github.com/Ian-Balijawa/sms-gateway/database"
+	"github.com/Ian-Balijawa/sms-gateway/models"
+	"github.com/Ian-Balijawa/sms-gateway/utils"
+
+	"github.com/google/uuid"
+)
+
+// Risk added to a message's fraud score by each signal. Scores are capped at 100.
+const (
+	fraudRiskForeign    = 20 // Destination outside the client's home region
+	fraudRiskUnknown    = 40 // Destination country not in the country table
+	fraudRiskHighRisk   = 70 // Destination listed in FRAUD_HIGH_RISK_COUNTRIES
+	fraudRiskSamePrefix = 40 // Many recipients in the same number range
+	fraudRiskSequential = 50 // Many recipients with neighbouring numbers
+	fraudRiskSpike      = 30 // Volume far above the client's hourly average
+)
+
+const (
+	// fraudVelocityWindow is how far back recent recipients are counted for the range checks
+	fraudVelocityWindow = 10 * time.Minute
+
+	// fraudRangeDigits is the number of trailing digits that vary within a number range
+	fraudRangeDigits = 3
+
+	// fraudSequentialSpan is how far apart two numbers in a range may be to count as neighbours
+	fraudSequentialSpan = 10
+
+	// fraudBaselineHours is the history used for the client's average hourly volume
+	fraudBaselineHours = 7 * 24
+)
+
+// fraudAssessment is the fraud score of one message and the signals behind it
+type fraudAssessment struct {
+	score   int
+	reasons []string
+}
+
+func (a *fraudAssessment) add(risk int, reason string) {
+	a.score = min(a.score+risk, 100)
+	a.reasons = append(a.reasons, reason)
+}
+
+// HeldForFraudReview reports whether a deferred message is held for fraud review. Flagged
+// messages scoring below the delay score are sent, so when they are deferred it is by quiet hours.
+func HeldForFraudReview(smsLog models.SMSLog) bool {
+	return smsLog.Status == models.SMSStatusDeferred && smsLog.FraudFlagged &&
+		smsLog.FraudScore >= config.AppConfig.FraudDelayScore
+}
+
+// screenFraud scores pending messages for SMS pumping. Messages reaching the block score are
+// logged as blocked, messages reaching the delay score are deferred for review and messages
+// reaching the flag score are sent and flagged. Whitelisted recipients are not scored.
+// It returns the messages that can be sent now.
+func (d *Dispatcher) screenFraud(client *models.APIClient, logs []models.SMSLog, pending []int, now time.Time) ([]int, error) {
+	cfg := config.AppConfig
+	if !cfg.FraudGuardEnabled || len(pending) == 0 {
+		return pending, nil
+	}
+
+	whitelist, err := fraudWhitelist(client.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	recipients := make([]string, 0, len(pending))
+	for _, i := range pending {
+		recipients = append(recipients, logs[i].Recipient)
+	}
+	ranges, err := recentNumberRanges(client.ID, recipients, now)
+	if err != nil {
+		return nil, err
+	}
+	spike, err := volumeSpike(client.ID, len(pending), now)
+	if err != nil {
+		return nil, err
+	}
+
+	home := client.DefaultRegion
+	if home == "" {
+		home = utils.DefaultRegion()
+	}
+	highRisk := make(map[string]bool)
+	for _, code := range strings.Split(cfg.FraudHighRiskCountries, ",") {
+		if code = strings.ToUpper(strings.TrimSpace(code)); code != "" {
+			highRisk[code] = true
+		}
+	}
+
+	remaining := pending[:0]
+	for _, i := range pending {
+		recipient := logs[i].Recipient
+		if fraudWhitelisted(whitelist, recipient) {
+			remaining = append(remaining, i)
+			continue
+		}
+
+		var assessment fraudAssessment
+		assessDestination(&assessment, recipient, home, highRisk)
+		assessNumberRange(&assessment, recipient, ranges)
+		if spike {
+			assessment.add(fraudRiskSpike, "sending volume far above the client's hourly average")
+		}
+
+		if applyFraudAssessment(&logs[i], assessment, now) {
+			remaining = append(remaining, i)
+		}
+	}
+	return remaining, nil
+}
+
+// applyFraudAssessment records a message's fraud score and blocks, defers or flags the
+// message by the configured thresholds. It reports whether the message can be sent now.
+func applyFraudAssessment(smsLog *models.SMSLog, assessment fraudAssessment, now time.Time) bool {
+	if assessment.score == 0 {
+		return true
+	}
+	cfg := config.AppConfig
+	smsLog.FraudScore = assessment.score
+	smsLog.FraudReason = strings.Join(assessment.reasons, "; ")
+
+	switch {
+	case assessment.score >= cfg.FraudBlockScore:
+		smsLog.FraudFlagged = true
+		smsLog.Status = models.SMSStatusBlocked
+		smsLog.Error = "blocked by fraud guard: " + smsLog.FraudReason
+		return false
+	case assessment.score >= cfg.FraudDelayScore && smsLog.OTPText != "":
+		// A one-time code would expire while held for review, so it is not sent at all.
+		// Failed rather than blocked so that it cannot be released later.
+		smsLog.FraudFlagged = true
+		smsLog.Status = models.SMSStatusFailed
+		smsLog.Error = "one-time code not sent, it would be held for fraud review: " + smsLog.FraudReason
+		return false
+	case assessment.score >= cfg.FraudDelayScore:
+		// Stored in UTC so that due messages compare correctly in every database
+		until := now.Add(time.Duration(cfg.FraudDelayMinutes) * time.Minute).UTC()
+		smsLog.FraudFlagged = true
+		smsLog.Status = models.SMSStatusDeferred
+		smsLog.ScheduledAt = &until
+		return false
+	case assessment.score >= cfg.FraudFlagScore:
+		smsLog.FraudFlagged = true
+	}
+	return true
+}
+
+// assessDestination scores the recipient's country against the client's home region
+func assessDestination(assessment *fraudAssessment, recipient, home string, highRisk map[string]bool) {
+	country, ok := utils.CountryForPhone(recipient)
+	switch {
+	case !ok:
+		assessment.add(fraudRiskUnknown, "destination country not recognized")
+	case highRisk[country.Code]:
+		assessment.add(fraudRiskHighRisk, "high-risk destination "+country.Name)
+	case country.Code != home:
+		assessment.add(fraudRiskForeign, "international destination "+country.Name)
+	}
+}
+
+// assessNumberRange scores the recipient by how many numbers in its range the client has
+// messaged recently, and how many of those are neighbours of the recipient
+func assessNumberRange(assessment *fraudAssessment, recipient string, ranges map[string]map[int]bool) {
+	key, suffix, ok := numberRange(recipient)
+	if !ok {
+		return
+	}
+	numbers := ranges[key]
+	cfg := config.AppConfig
+
+	if cfg.FraudPrefixThreshold > 0 && len(numbers) >= cfg.FraudPrefixThreshold {
+		assessment.add(fraudRiskSamePrefix, fmt.Sprintf("%d recipients in range %s%s within %d minutes",
+			len(numbers), key, strings.Repeat("x", fraudRangeDigits), int(fraudVelocityWindow.Minutes())))
+	}
+
+	neighbours := 0
+	for number := range numbers {
+		if number != suffix && number >= suffix-fraudSequentialSpan && number <= suffix+fraudSequentialSpan {
+			neighbours++
+		}
+	}
+	if cfg.FraudSequentialThreshold > 0 && neighbours >= cfg.FraudSequentialThreshold {
+		assessment.add(fraudRiskSequential, fmt.Sprintf("%d sequential numbers near %s", neighbours, recipient))
+	}
+}
+
+// numberRange splits an E.164 number into its range (all but the last digits) and its position in the range
+func numberRange(recipient string) (string, int, bool) {
+	if !strings.HasPrefix(recipient, "+") || len(recipient) <= fraudRangeDigits+4 {
+		return "", 0, false
+	}
+	cut := len(recipient) - fraudRangeDigits
+	suffix, err := strconv.Atoi(recipient[cut:])
+	if err != nil {
+		return "", 0, false
+	}
+	return recipient[:cut], suffix, true
+}
+
+// fraudCountedStatuses are the statuses of messages handed to the provider, which are the
+// only ones counted towards velocity and volume. Queued, deferred and blocked messages have
+// not reached anyone yet.
+var fraudCountedStatuses = []string{models.SMSStatusSent, models.SMSStatusUnknown}
+
+// recentNumberRanges returns the distinct numbers the client messaged within the velocity
+// window in the ranges of the given recipients, together with the recipients themselves
+func recentNumberRanges(clientID uuid.UUID, recipients []string, now time.Time) (map[string]map[int]bool, error) {
+	ranges := make(map[string]map[int]bool)
+	add := func(recipient string) {
+		key, suffix, ok := numberRange(recipient)
+		if !ok {
+			return
+		}
+		if ranges[key] == nil {
+			ranges[key] = make(map[int]bool)
+		}
+		ranges[key][suffix] = true
+	}
+
+	for _, recipient := range recipients {
+		add(recipient)
+	}
+	keys := make([]string, 0, len(ranges))
+	for key := range ranges {
+		keys = append(keys, key)
+	}
+
+	for start := 0; start < len(keys); start += suppressionLookupBatch {
+		end := min(start+suppressionLookupBatch, len(keys))
+
+		var recent []string
+		if err := database.DB.Model(&models.SMSLog{}).
+			Where("client_id = ? AND status IN ? AND sent_at >= ?", clientID, fraudCountedStatuses, now.Add(-fraudVelocityWindow)).
+			Where("substr(recipient, 1, length(recipient) - ?) IN ?", fraudRangeDigits, keys[start:end]).
+			Distinct("recipient").
+			Pluck("recipient", &recent).Error; err != nil {
+			return nil, err
+		}
+		for _, recipient := range recent {
+			add(recipient)
+		}
+	}
+	return ranges, nil
+}
+
+// volumeSpike reports whether the client's volume over the last hour, including a batch
+// about to be sent, is far above its average hourly volume
+func volumeSpike(clientID uuid.UUID, batch int, now time.Time) (bool, error) {
+	cfg := config.AppConfig
+	if cfg.FraudSpikeMultiplier <= 0 {
+		return false, nil
+	}
+
+	hourAgo := now.Add(-time.Hour)
+	var lastHour, history int64
+	if err := database.DB.Model(&models.SMSLog{}).
+		Where("client_id = ? AND status IN ? AND sent_at >= ?", clientID, fraudCountedStatuses, hourAgo).
+		Count(&lastHour).Error; err != nil {
+		return false, err
+	}
+	if lastHour+int64(batch) < int64(cfg.FraudSpikeMinimum) {
+		return false, nil
+	}
+	if err := database.DB.Model(&models.SMSLog{}).
+		Where("client_id = ? AND status IN ? AND sent_at >= ? AND sent_at < ?",
+			clientID, fraudCountedStatuses, now.Add(-fraudBaselineHours*time.Hour), hourAgo).
+		Count(&history).Error; err != nil {
+		return false, err
+	}
+
+	average := float64(history) / float64(fraudBaselineHours-1)
+	return float64(lastHour+int64(batch)) > average*float64(cfg.FraudSpikeMultiplier), nil
+}
+
+// fraudWhitelist returns the whitelist entries that apply to the client, including global entries
+func fraudWhitelist(clientID uuid.UUID) ([]models.FraudWhitelist, error) {
+	var entries []models.FraudWhitelist
+	err := database.DB.Where("client_id = ? OR client_id IS NULL", clientID).Find(&entries).Error
+	return entries, err
+}
+
+// fraudWhitelisted reports whether the recipient matches a whitelist entry
+func fraudWhitelisted(entries []models.FraudWhitelist, recipient string) bool {
+	for _, entry := range entries {
+		if strings.HasPrefix(recipient, entry.Prefix) {
+			return true
+		}
+	}
+	return false
+}
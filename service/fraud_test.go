@@ -0,0 +1,204 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Ian-Balijawa/sms-gateway/config"
+	"github.com/Ian-Balijawa/sms-gateway/models"
+)
+
+// setConfig replaces the application configuration for the duration of a test
+func setConfig(t *testing.T, cfg config.Config) {
+	t.Helper()
+	previous := config.AppConfig
+	config.AppConfig = &cfg
+	t.Cleanup(func() { config.AppConfig = previous })
+}
+
+func TestApplyFraudAssessment(t *testing.T) {
+	setConfig(t, config.Config{
+		FraudBlockScore:   80,
+		FraudDelayScore:   60,
+		FraudFlagScore:    40,
+		FraudDelayMinutes: 30,
+	})
+	now := time.Date(2024, time.May, 10, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name    string
+		score   int
+		sendNow bool
+		status  string
+		flagged bool
+	}{
+		{name: "no risk", score: 0, sendNow: true, status: models.SMSStatusPending},
+		{name: "below flag score", score: 39, sendNow: true, status: models.SMSStatusPending},
+		{name: "flag score", score: 40, sendNow: true, status: models.SMSStatusPending, flagged: true},
+		{name: "below delay score", score: 59, sendNow: true, status: models.SMSStatusPending, flagged: true},
+		{name: "delay score", score: 60, status: models.SMSStatusDeferred, flagged: true},
+		{name: "below block score", score: 79, status: models.SMSStatusDeferred, flagged: true},
+		{name: "block score", score: 80, status: models.SMSStatusBlocked, flagged: true},
+		{name: "maximum score", score: 100, status: models.SMSStatusBlocked, flagged: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			smsLog := models.SMSLog{Status: models.SMSStatusPending}
+			assessment := fraudAssessment{score: tt.score}
+			if tt.score > 0 {
+				assessment.reasons = []string{"test signal"}
+			}
+
+			if sendNow := applyFraudAssessment(&smsLog, assessment, now); sendNow != tt.sendNow {
+				t.Errorf("applyFraudAssessment() = %v, want %v", sendNow, tt.sendNow)
+			}
+			if smsLog.Status != tt.status {
+				t.Errorf("Status = %q, want %q", smsLog.Status, tt.status)
+			}
+			if smsLog.FraudFlagged != tt.flagged {
+				t.Errorf("FraudFlagged = %v, want %v", smsLog.FraudFlagged, tt.flagged)
+			}
+			if smsLog.FraudScore != tt.score {
+				t.Errorf("FraudScore = %d, want %d", smsLog.FraudScore, tt.score)
+			}
+
+			switch tt.status {
+			case models.SMSStatusDeferred:
+				want := now.Add(30 * time.Minute)
+				if smsLog.ScheduledAt == nil || !smsLog.ScheduledAt.Equal(want) {
+					t.Errorf("ScheduledAt = %v, want %v", smsLog.ScheduledAt, want)
+				}
+				if !HeldForFraudReview(smsLog) {
+					t.Error("HeldForFraudReview() = false for a message held for review")
+				}
+			case models.SMSStatusBlocked:
+				if smsLog.Error != "blocked by fraud guard: test signal" {
+					t.Errorf("Error = %q", smsLog.Error)
+				}
+			default:
+				if smsLog.ScheduledAt != nil {
+					t.Errorf("ScheduledAt = %v, want none", smsLog.ScheduledAt)
+				}
+			}
+		})
+	}
+}
+
+func TestApplyFraudAssessmentOneTimeCode(t *testing.T) {
+	setConfig(t, config.Config{FraudBlockScore: 80, FraudDelayScore: 60, FraudFlagScore: 40, FraudDelayMinutes: 30})
+	now := time.Date(2024, time.May, 10, 12, 0, 0, 0, time.UTC)
+
+	// A one-time code that would be held for review is failed rather than sent after it expires
+	smsLog := models.SMSLog{Status: models.SMSStatusPending, Message: "Your code is ******", OTPText: "Your code is 123456"}
+	if applyFraudAssessment(&smsLog, fraudAssessment{score: 60, reasons: []string{"test signal"}}, now) {
+		t.Error("applyFraudAssessment() = true, want false")
+	}
+	if smsLog.Status != models.SMSStatusFailed || smsLog.ScheduledAt != nil || HeldForFraudReview(smsLog) {
+		t.Errorf("Status = %q, ScheduledAt = %v, want failed and not scheduled", smsLog.Status, smsLog.ScheduledAt)
+	}
+}
+
+func TestHeldForFraudReviewQuietHours(t *testing.T) {
+	setConfig(t, config.Config{FraudDelayScore: 60})
+
+	// Flagged below the delay score, then deferred by quiet hours
+	smsLog := models.SMSLog{Status: models.SMSStatusDeferred, FraudFlagged: true, FraudScore: 40}
+	if HeldForFraudReview(smsLog) {
+		t.Error("HeldForFraudReview() = true for a message deferred by quiet hours")
+	}
+}
+
+func TestAssessDestination(t *testing.T) {
+	highRisk := map[string]bool{"NG": true}
+
+	tests := []struct {
+		name      string
+		recipient string
+		home      string
+		score     int
+	}{
+		{name: "home region", recipient: "+256701234567", home: "UG", score: 0},
+		{name: "international", recipient: "+254712345678", home: "UG", score: fraudRiskForeign},
+		{name: "high risk", recipient: "+2348031234567", home: "UG", score: fraudRiskHighRisk},
+		{name: "high risk home region", recipient: "+2348031234567", home: "NG", score: fraudRiskHighRisk},
+		{name: "unknown country", recipient: "+8801712345678", home: "UG", score: fraudRiskUnknown},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var assessment fraudAssessment
+			assessDestination(&assessment, tt.recipient, tt.home, highRisk)
+			if assessment.score != tt.score {
+				t.Errorf("score = %d, want %d (%v)", assessment.score, tt.score, assessment.reasons)
+			}
+		})
+	}
+}
+
+func TestAssessNumberRange(t *testing.T) {
+	setConfig(t, config.Config{
+		FraudPrefixThreshold:     20,
+		FraudSequentialThreshold: 5,
+	})
+
+	// numbersInRange returns a range of the recipient's prefix holding the given suffixes
+	numbersInRange := func(suffixes ...int) map[string]map[int]bool {
+		numbers := make(map[int]bool)
+		for _, suffix := range suffixes {
+			numbers[suffix] = true
+		}
+		return map[string]map[int]bool{"+256701234": numbers}
+	}
+	spread := func(count int) []int {
+		suffixes := make([]int, count)
+		for i := range suffixes {
+			suffixes[i] = i * 40
+		}
+		return suffixes
+	}
+
+	tests := []struct {
+		name      string
+		recipient string
+		ranges    map[string]map[int]bool
+		score     int
+	}{
+		{name: "first number in range", recipient: "+256701234500", ranges: numbersInRange(500), score: 0},
+		{name: "spread below prefix threshold", recipient: "+256701234000", ranges: numbersInRange(spread(19)...), score: 0},
+		{name: "spread at prefix threshold", recipient: "+256701234000", ranges: numbersInRange(spread(20)...), score: fraudRiskSamePrefix},
+		{name: "neighbours below threshold", recipient: "+256701234500", ranges: numbersInRange(500, 501, 502, 503, 504), score: 0},
+		{name: "neighbours at threshold", recipient: "+256701234500", ranges: numbersInRange(500, 501, 502, 503, 504, 510), score: fraudRiskSequential},
+		{name: "numbers too far apart", recipient: "+256701234500", ranges: numbersInRange(500, 511, 522, 533, 544, 555), score: 0},
+		{name: "other range", recipient: "+256701235500", ranges: numbersInRange(500, 501, 502, 503, 504, 505), score: 0},
+		{
+			name:      "range and neighbours",
+			recipient: "+256701234500",
+			ranges:    numbersInRange(append(spread(14), 495, 496, 497, 498, 499, 500)...),
+			score:     fraudRiskSamePrefix + fraudRiskSequential,
+		},
+		{name: "too short to have a range", recipient: "+25670", ranges: numbersInRange(), score: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var assessment fraudAssessment
+			assessNumberRange(&assessment, tt.recipient, tt.ranges)
+			if assessment.score != tt.score {
+				t.Errorf("score = %d, want %d (%v)", assessment.score, tt.score, assessment.reasons)
+			}
+		})
+	}
+}
+
+func TestFraudScoreIsCapped(t *testing.T) {
+	var assessment fraudAssessment
+	assessment.add(fraudRiskHighRisk, "high-risk destination")
+	assessment.add(fraudRiskSequential, "sequential numbers")
+	if assessment.score != 100 {
+		t.Errorf("score = %d, want 100", assessment.score)
+	}
+	if len(assessment.reasons) != 2 {
+		t.Errorf("got %d reasons, want 2", len(assessment.reasons))
+	}
+}
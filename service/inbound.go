@@ -0,0 +1,298 @@
+package service
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/Ian-Balijawa/sms-gateway/database"
+	"github.com/Ian-Balijawa/sms-gateway/models"
+	"github.com/Ian-Balijawa/sms-gateway/utils"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	// inboundPollInterval is how often failed webhook deliveries are retried
+	inboundPollInterval = 30 * time.Second
+
+	// inboundMaxForwardAttempts is the number of webhook deliveries tried before giving up
+	inboundMaxForwardAttempts = 6
+)
+
+// optOutKeywords are replies that add the sender to the client's suppression list
+var optOutKeywords = map[string]bool{
+	"STOP": true, "STOPALL": true, "UNSUBSCRIBE": true, "CANCEL": true, "END": true, "QUIT": true,
+}
+
+var webhookClient = &http.Client{
+	Timeout: 10 * time.Second,
+}
+
+// NormalizeInboundNumber normalizes a destination number for routing. Long numbers are stored
+// in E.164; shortcodes, which are not valid phone numbers, keep their digits only.
+func NormalizeInboundNumber(number, region string) string {
+	if parsed, err := utils.ParsePhone(number, region); err == nil {
+		return parsed.E164
+	}
+	var digits strings.Builder
+	for _, r := range number {
+		if r >= '0' && r <= '9' {
+			digits.WriteRune(r)
+		}
+	}
+	return digits.String()
+}
+
+// NormalizeInboundKeyword returns the keyword form of a word: trimmed and upper case
+func NormalizeInboundKeyword(keyword string) string {
+	return strings.ToUpper(strings.TrimSpace(keyword))
+}
+
+// InboundKeyword returns the first word of a message, upper case
+func InboundKeyword(text string) string {
+	fields := strings.Fields(text)
+	if len(fields) == 0 {
+		return ""
+	}
+	return NormalizeInboundKeyword(fields[0])
+}
+
+// ReceiveInbound records a message pushed by a provider, routes it to the client owning the
+// destination number and keyword, forwards it to the client's webhook and runs the client's
+// keyword rules. A message the provider already delivered (same provider message ID) is
+// returned with duplicate set.
+func (d *Dispatcher) ReceiveInbound(msg *models.InboundMessage) (bool, error) {
+	if msg.ProviderMessageID != "" {
+		var existing models.InboundMessage
+		err := database.DB.Where("provider = ? AND provider_message_id = ?", msg.Provider, msg.ProviderMessageID).First(&existing).Error
+		if err == nil {
+			*msg = existing
+			return true, nil
+		}
+		if err != gorm.ErrRecordNotFound {
+			return false, err
+		}
+	}
+
+	if msg.ReceivedAt.IsZero() {
+		msg.ReceivedAt = time.Now()
+	}
+	msg.ReceivedAt = msg.ReceivedAt.UTC()
+	msg.To = NormalizeInboundNumber(msg.To, utils.DefaultRegion())
+	msg.Keyword = InboundKeyword(msg.Text)
+
+	route, err := routeInbound(msg.To, msg.Keyword)
+	if err != nil {
+		return false, err
+	}
+	if route == nil {
+		msg.From = utils.FormatPhone(msg.From)
+		msg.Status = models.InboundStatusUnrouted
+		log.Printf("Inbound message to %s (keyword %q) matched no route", msg.To, msg.Keyword)
+		return saveInbound(msg)
+	}
+
+	var client models.APIClient
+	if err := database.DB.Where("id = ?", route.ClientID).First(&client).Error; err != nil {
+		return false, fmt.Errorf("failed to load client for route %s: %w", route.ID, err)
+	}
+	msg.ClientID = &client.ID
+	msg.RouteID = &route.ID
+	msg.From = utils.FormatPhoneForRegion(msg.From, client.DefaultRegion)
+
+	msg.Status = models.InboundStatusReceived
+	if client.WebhookURL != "" {
+		msg.Status = models.InboundStatusPending
+		msg.NextAttemptAt = &msg.ReceivedAt
+	}
+	if duplicate, err := saveInbound(msg); duplicate || err != nil {
+		return duplicate, err
+	}
+
+	if optOutKeywords[msg.Keyword] {
+		if _, _, err := AddSuppression(&client.ID, msg.From, "replied "+msg.Keyword, models.SuppressionSourceInbound); err != nil {
+			log.Printf("Error suppressing %s after opt-out reply: %v", msg.From, err)
+		}
+	}
+
+	if msg.Status == models.InboundStatusPending {
+		go deliverInbound(*msg)
+	}
+	received := *msg
+	go d.applyKeywordRules(&client, &received)
+	return false, nil
+}
+
+// saveInbound inserts a new inbound message. If the provider message ID was stored in the
+// meantime, by a redelivery received at the same time, msg is replaced with the stored message
+// and saveInbound reports a duplicate.
+func saveInbound(msg *models.InboundMessage) (bool, error) {
+	result := database.DB.Clauses(clause.OnConflict{DoNothing: true}).Create(msg)
+	if result.Error != nil || result.RowsAffected == 1 {
+		return false, result.Error
+	}
+
+	var existing models.InboundMessage
+	if err := database.DB.Where("provider = ? AND provider_message_id = ?", msg.Provider, msg.ProviderMessageID).First(&existing).Error; err != nil {
+		return false, err
+	}
+	*msg = existing
+	return true, nil
+}
+
+// routeInbound finds the route for a destination number and keyword. A route for the exact
+// keyword wins over the number's default route. It returns nil if no route matches.
+func routeInbound(number, keyword string) (*models.InboundRoute, error) {
+	var routes []models.InboundRoute
+	if err := database.DB.
+		Where("number = ? AND keyword IN ?", number, []string{keyword, ""}).
+		Find(&routes).Error; err != nil {
+		return nil, fmt.Errorf("failed to look up inbound routes: %w", err)
+	}
+
+	var match *models.InboundRoute
+	for i := range routes {
+		if match == nil || routes[i].Keyword != "" {
+			match = &routes[i]
+		}
+	}
+	return match, nil
+}
+
+// StartInboundForwarder retries failed webhook deliveries in a background goroutine
+func StartInboundForwarder() {
+	go func() {
+		for {
+			forwardDueInbound(time.Now())
+			time.Sleep(inboundPollInterval)
+		}
+	}()
+
+	log.Println("Inbound message forwarder started")
+}
+
+func forwardDueInbound(now time.Time) {
+	var due []models.InboundMessage
+	if err := database.DB.
+		Where("status = ? AND next_attempt_at <= ?", models.InboundStatusPending, now.UTC()).
+		Order("next_attempt_at").
+		Limit(100).
+		Find(&due).Error; err != nil {
+		log.Printf("Error loading inbound messages to forward: %v", err)
+		return
+	}
+
+	for _, msg := range due {
+		deliverInbound(msg)
+	}
+}
+
+// deliverInbound makes one attempt to POST the message to its client's webhook
+func deliverInbound(msg models.InboundMessage) {
+	// Claim the attempt by scheduling the next one. forward_attempts acts as a version
+	// so that a message is not delivered twice at the same time.
+	attempt := msg.ForwardAttempts + 1
+	next := time.Now().UTC().Add(time.Duration(attempt*attempt) * time.Minute)
+	claim := database.DB.Model(&models.InboundMessage{}).
+		Where("id = ? AND status = ? AND forward_attempts = ?", msg.ID, models.InboundStatusPending, msg.ForwardAttempts).
+		Updates(map[string]interface{}{
+			"forward_attempts": attempt,
+			"next_attempt_at":  next,
+		})
+	if claim.Error != nil || claim.RowsAffected == 0 {
+		return
+	}
+
+	err := forwardInbound(&msg)
+
+	updates := map[string]interface{}{}
+	switch {
+	case err == nil:
+		now := time.Now()
+		updates["status"] = models.InboundStatusForwarded
+		updates["forwarded_at"] = now
+		updates["forward_error"] = ""
+		updates["next_attempt_at"] = nil
+	case attempt >= inboundMaxForwardAttempts:
+		log.Printf("Giving up forwarding inbound message %s after %d attempts: %v", msg.ID, attempt, err)
+		updates["status"] = models.InboundStatusForwardFailed
+		updates["forward_error"] = err.Error()
+		updates["next_attempt_at"] = nil
+	default:
+		log.Printf("Forwarding inbound message %s failed (attempt %d): %v", msg.ID, attempt, err)
+		updates["forward_error"] = err.Error()
+	}
+	database.DB.Model(&models.InboundMessage{}).Where("id = ?", msg.ID).Updates(updates)
+}
+
+// forwardInbound POSTs the message to the client's webhook. The body is signed with the
+// client's webhook secret in the X-Webhook-Signature header as "sha256=<hex HMAC>".
+func forwardInbound(msg *models.InboundMessage) error {
+	if msg.ClientID == nil {
+		return fmt.Errorf("message is not routed to a client")
+	}
+	var client models.APIClient
+	if err := database.DB.Where("id = ?", msg.ClientID).First(&client).Error; err != nil {
+		return fmt.Errorf("client not found")
+	}
+	if client.WebhookURL == "" {
+		return fmt.Errorf("client has no webhook")
+	}
+
+	body, err := json.Marshal(inboundWebhookPayload(msg))
+	if err != nil {
+		return fmt.Errorf("failed to marshal payload: %w", err)
+	}
+	return postWebhook(client.WebhookURL, client.WebhookSecret, body)
+}
+
+// inboundWebhookPayload is the body POSTed to a client's webhook for an inbound message
+func inboundWebhookPayload(msg *models.InboundMessage) map[string]interface{} {
+	return map[string]interface{}{
+		"event":       "inbound_message",
+		"id":          msg.ID,
+		"from":        msg.From,
+		"to":          msg.To,
+		"text":        msg.Text,
+		"keyword":     msg.Keyword,
+		"received_at": msg.ReceivedAt,
+		"provider":    msg.Provider,
+	}
+}
+
+// postWebhook POSTs a JSON body to a client webhook, signing it when a secret is set.
+// Any response other than 2xx is an error.
+func postWebhook(url, secret string, body []byte) error {
+	req, err := http.NewRequest("POST", url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if secret != "" {
+		mac := hmac.New(sha256.New, []byte(secret))
+		mac.Write(body)
+		req.Header.Set("X-Webhook-Signature", "sha256="+hex.EncodeToString(mac.Sum(nil)))
+	}
+
+	resp, err := webhookClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("webhook request failed: %w", err)
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook returned status %d", resp.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,274 @@
+package service
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/Ian-Balijawa/sms-gateway/database"
+	"github.com/Ian-Balijawa/sms-gateway/models"
+	"github.com/Ian-Balijawa/sms-gateway/utils"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ValidateKeywordRule checks the rule's match type, pattern and the shape of its actions.
+// Ownership of referenced groups is checked by the caller.
+func ValidateKeywordRule(rule *models.KeywordRule) error {
+	if strings.TrimSpace(rule.Pattern) == "" {
+		return fmt.Errorf("pattern is required")
+	}
+	switch rule.MatchType {
+	case models.KeywordMatchExact, models.KeywordMatchPrefix:
+	case models.KeywordMatchRegex:
+		if _, err := regexp.Compile(rule.Pattern); err != nil {
+			return fmt.Errorf("invalid regex pattern: %w", err)
+		}
+	default:
+		return fmt.Errorf("invalid match_type %q, expected exact, prefix or regex", rule.MatchType)
+	}
+
+	if len(rule.Actions) == 0 {
+		return fmt.Errorf("at least one action is required")
+	}
+	for i, action := range rule.Actions {
+		switch action.Type {
+		case models.RuleActionReply:
+			if action.Message == "" {
+				return fmt.Errorf("action %d: message is required for reply", i+1)
+			}
+		case models.RuleActionAddToGroup, models.RuleActionRemoveFromGroup:
+			if action.GroupID == nil {
+				return fmt.Errorf("action %d: group_id is required for %s", i+1, action.Type)
+			}
+		case models.RuleActionSuppress, models.RuleActionForward:
+		default:
+			return fmt.Errorf("action %d: invalid type %q", i+1, action.Type)
+		}
+	}
+	return nil
+}
+
+// KeywordRuleMatches reports whether the text of an inbound message matches the rule
+func KeywordRuleMatches(rule *models.KeywordRule, text string) bool {
+	text = strings.TrimSpace(text)
+	switch rule.MatchType {
+	case models.KeywordMatchExact:
+		return strings.EqualFold(text, strings.TrimSpace(rule.Pattern))
+	case models.KeywordMatchPrefix:
+		pattern := strings.TrimSpace(rule.Pattern)
+		return len(text) >= len(pattern) && strings.EqualFold(text[:len(pattern)], pattern)
+	case models.KeywordMatchRegex:
+		re, err := regexp.Compile(rule.Pattern)
+		return err == nil && re.MatchString(text)
+	}
+	return false
+}
+
+// MatchKeywordRule returns the client's first active rule matching the text, or nil
+func MatchKeywordRule(clientID uuid.UUID, text string) (*models.KeywordRule, error) {
+	var rules []models.KeywordRule
+	if err := database.DB.
+		Where("client_id = ? AND is_active = ?", clientID, true).
+		Order("position, created_at").
+		Find(&rules).Error; err != nil {
+		return nil, err
+	}
+	for i := range rules {
+		if KeywordRuleMatches(&rules[i], text) {
+			return &rules[i], nil
+		}
+	}
+	return nil, nil
+}
+
+// applyKeywordRules runs the actions of the client's first rule matching the message and
+// records the outcome of each action as a rule hit
+func (d *Dispatcher) applyKeywordRules(client *models.APIClient, msg *models.InboundMessage) {
+	rule, err := MatchKeywordRule(client.ID, msg.Text)
+	if err != nil {
+		log.Printf("Error loading keyword rules for client %s: %v", client.ID, err)
+		return
+	}
+	if rule == nil {
+		return
+	}
+
+	hits := make([]models.InboundRuleHit, 0, len(rule.Actions))
+	for _, action := range rule.Actions {
+		hit := models.InboundRuleHit{
+			InboundMessageID: msg.ID,
+			RuleID:           rule.ID,
+			RuleName:         rule.Name,
+			Action:           action.Type,
+			Status:           models.RuleHitSucceeded,
+		}
+		result, err := d.runRuleAction(client, rule, action, msg)
+		hit.Result = result
+		if err != nil {
+			hit.Status = models.RuleHitFailed
+			hit.Error = err.Error()
+		}
+		hits = append(hits, hit)
+	}
+
+	if err := database.DB.Create(&hits).Error; err != nil {
+		log.Printf("Error saving keyword rule hits for inbound message %s: %v", msg.ID, err)
+	}
+	database.DB.Model(&models.KeywordRule{}).Where("id = ?", rule.ID).Updates(map[string]interface{}{
+		"hit_count":   gorm.Expr("hit_count + 1"),
+		"last_hit_at": time.Now(),
+	})
+}
+
+// runRuleAction runs one action for an inbound message and describes what it did
+func (d *Dispatcher) runRuleAction(client *models.APIClient, rule *models.KeywordRule, action models.RuleAction, msg *models.InboundMessage) (string, error) {
+	switch action.Type {
+	case models.RuleActionReply:
+		return d.replyToInbound(client, action, msg)
+
+	case models.RuleActionAddToGroup:
+		contact, err := inboundContact(client.ID, msg.From, true)
+		if err != nil {
+			return "", err
+		}
+		if err := groupOwnedBy(*action.GroupID, client.ID); err != nil {
+			return "", err
+		}
+		member := models.GroupMember{GroupID: *action.GroupID, ContactID: contact.ID}
+		if err := database.DB.Clauses(clause.OnConflict{DoNothing: true}).Create(&member).Error; err != nil {
+			return "", err
+		}
+		return "contact " + contact.ID.String() + " added to group " + action.GroupID.String(), nil
+
+	case models.RuleActionRemoveFromGroup:
+		contact, err := inboundContact(client.ID, msg.From, false)
+		if err != nil {
+			return "", err
+		}
+		if contact == nil {
+			return "sender is not a contact", nil
+		}
+		if err := database.DB.Where("group_id = ? AND contact_id = ?", action.GroupID, contact.ID).
+			Delete(&models.GroupMember{}).Error; err != nil {
+			return "", err
+		}
+		return "contact " + contact.ID.String() + " removed from group " + action.GroupID.String(), nil
+
+	case models.RuleActionSuppress:
+		suppression, created, err := AddSuppression(&client.ID, msg.From, "keyword rule "+rule.Name, models.SuppressionSourceInbound)
+		if err != nil {
+			return "", err
+		}
+		if !created {
+			return "already suppressed", nil
+		}
+		return "suppression " + suppression.ID.String(), nil
+
+	case models.RuleActionForward:
+		url := action.URL
+		if url == "" {
+			url = client.WebhookURL
+		}
+		if url == "" {
+			return "", fmt.Errorf("no url and the client has no webhook")
+		}
+		payload := inboundWebhookPayload(msg)
+		payload["event"] = "keyword_rule"
+		payload["rule_id"] = rule.ID
+		payload["rule_name"] = rule.Name
+		body, err := json.Marshal(payload)
+		if err != nil {
+			return "", err
+		}
+		if err := postWebhook(url, client.WebhookSecret, body); err != nil {
+			return "", err
+		}
+		return "forwarded to " + url, nil
+	}
+	return "", fmt.Errorf("unknown action %q", action.Type)
+}
+
+// replyToInbound sends the action's message template to the sender of an inbound message.
+// The template can use the sender's contact fields, text and keyword.
+func (d *Dispatcher) replyToInbound(client *models.APIClient, action models.RuleAction, msg *models.InboundMessage) (string, error) {
+	// Reload the client so the quota check uses current usage
+	if err := database.DB.Where("id = ?", client.ID).First(client).Error; err != nil {
+		return "", fmt.Errorf("client not found")
+	}
+	if !client.IsActive {
+		return "", fmt.Errorf("client is inactive")
+	}
+	if client.DailyUsage >= client.DailyLimit || client.MonthlyUsage >= client.MonthlyLimit {
+		return "", fmt.Errorf("client usage limit reached")
+	}
+
+	vars := map[string]string{"phone": msg.From}
+	if contact, err := inboundContact(client.ID, msg.From, false); err == nil && contact != nil {
+		vars = ContactTemplateVars(contact)
+	}
+	vars["text"] = msg.Text
+	vars["keyword"] = msg.Keyword
+
+	result, err := d.Dispatch(DispatchRequest{
+		Client: client,
+		Messages: []models.SMSRequest{{
+			Number:   msg.From,
+			Message:  utils.RenderTemplate(action.Message, vars),
+			SenderID: action.SenderID,
+		}},
+	})
+	if result == nil || len(result.Logs) == 0 {
+		return "", err
+	}
+
+	reply := result.Logs[0]
+	summary := "reply " + reply.ID.String() + " " + reply.Status
+	switch reply.Status {
+	case models.SMSStatusSent, models.SMSStatusDeferred, models.SMSStatusUnknown:
+		return summary, nil
+	}
+	if reply.Error != "" {
+		return summary, fmt.Errorf("%s", reply.Error)
+	}
+	return summary, fmt.Errorf("reply %s", reply.Status)
+}
+
+// inboundContact finds the client's contact for a phone number, creating it when create is set.
+// It returns nil without an error if there is no contact and create is not set.
+func inboundContact(clientID uuid.UUID, phone string, create bool) (*models.Contact, error) {
+	var contact models.Contact
+	err := database.DB.Where("client_id = ? AND phone = ?", clientID, phone).First(&contact).Error
+	if err == nil {
+		return &contact, nil
+	}
+	if err != gorm.ErrRecordNotFound {
+		return nil, err
+	}
+	if !create {
+		return nil, nil
+	}
+
+	contact = models.Contact{ClientID: clientID, Phone: phone}
+	if err := database.DB.Create(&contact).Error; err != nil {
+		return nil, err
+	}
+	return &contact, nil
+}
+
+// groupOwnedBy checks that the group exists and belongs to the client
+func groupOwnedBy(groupID, clientID uuid.UUID) error {
+	var count int64
+	if err := database.DB.Model(&models.Group{}).Where("id = ? AND client_id = ?", groupID, clientID).Count(&count).Error; err != nil {
+		return err
+	}
+	if count == 0 {
+		return fmt.Errorf("group not found")
+	}
+	return nil
+}
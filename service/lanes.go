@@ -0,0 +1,213 @@
+package service
+
+import (
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/Ian-Balijawa/sms-gateway/config"
+	"github.com/Ian-Balijawa/sms-gateway/models"
+)
+
+// LaneStats describes the limits and current load of a priority lane
+type LaneStats struct {
+	Name        string `json:"name"`
+	Concurrency int    `json:"concurrency"` // Provider requests allowed in flight
+	Rate        int    `json:"rate"`        // Messages per second, 0 for no limit
+	InFlight    int    `json:"in_flight"`   // Provider requests in flight
+	Waiting     int    `json:"waiting"`     // Provider requests waiting to start
+	Sent        int64  `json:"sent"`        // Messages handed to the provider since the gateway started
+}
+
+// lane is a priority class of outbound traffic with its own limits
+type lane struct {
+	LaneStats
+	next time.Time // When the rate limit allows the next request to start
+}
+
+// LaneScheduler sends messages to the provider through priority lanes. Each lane has its own
+// concurrency and rate limits, and a lane only starts provider requests while no higher lane
+// has requests waiting, so transactional traffic is never held up behind campaigns.
+type LaneScheduler struct {
+	provider Provider
+
+	mu    sync.Mutex
+	cond  *sync.Cond
+	lanes []*lane // Highest priority first
+}
+
+func NewLaneScheduler(provider Provider) *LaneScheduler {
+	cfg := config.AppConfig
+	s := &LaneScheduler{
+		provider: provider,
+		lanes: []*lane{
+			newLane(models.LaneTransactional, cfg.LaneTransactionalConcurrency, cfg.LaneTransactionalRate),
+			newLane(models.LaneStandard, cfg.LaneStandardConcurrency, cfg.LaneStandardRate),
+			newLane(models.LaneBulk, cfg.LaneBulkConcurrency, cfg.LaneBulkRate),
+		},
+	}
+	s.cond = sync.NewCond(&s.mu)
+	return s
+}
+
+func newLane(name string, concurrency, rate int) *lane {
+	return &lane{LaneStats: LaneStats{
+		Name:        name,
+		Concurrency: max(concurrency, 1),
+		Rate:        max(rate, 0),
+	}}
+}
+
+// MessageLane returns the lane a message is sent through. Campaign messages always use the bulk
+// lane so that a campaign cannot crowd out transactional traffic, whatever its priority.
+func MessageLane(req DispatchRequest, msg models.SMSRequest) string {
+	switch {
+	case req.CampaignID != nil:
+		return models.LaneBulk
+	case msg.Priority == models.PriorityTransactional:
+		return models.LaneTransactional
+	case req.Bulk || req.RecurringJobID != nil || msg.Marketing:
+		return models.LaneBulk
+	default:
+		return models.LaneStandard
+	}
+}
+
+// Stats returns the limits and current load of every lane, highest priority first
+func (s *LaneScheduler) Stats() []LaneStats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stats := make([]LaneStats, len(s.lanes))
+	for i, l := range s.lanes {
+		stats[i] = l.LaneStats
+	}
+	return stats
+}
+
+// Send submits the messages to the provider through a lane, in provider-sized batches, and returns
+// one result per message, in order. Messages in a batch that could not be delivered to the provider
+// are reported as failed. An error is returned only if no batch reached the provider.
+// Messages for an unknown lane use the standard lane.
+func (s *LaneScheduler) Send(laneName string, messages []models.SMSRequest, defaultSenderID string) ([]SMSProviderResult, error) {
+	l := s.lane(laneName)
+	if len(messages) == 0 {
+		return nil, nil
+	}
+
+	batchSize := max(s.provider.MaxBatchSize(), 1)
+	results := make([]SMSProviderResult, len(messages))
+	batches := (len(messages) + batchSize - 1) / batchSize
+	errs := make([]error, batches)
+
+	var wg sync.WaitGroup
+	for b := 0; b < batches; b++ {
+		start := b * batchSize
+		end := min(start+batchSize, len(messages))
+
+		// Batches start in order, as the lane's limits allow
+		delay := s.acquire(l, end-start)
+
+		wg.Add(1)
+		go func(b, start, end int) {
+			defer wg.Done()
+			defer s.release(l)
+			time.Sleep(delay)
+
+			batchResults, err := s.provider.SendSMS(messages[start:end], defaultSenderID)
+			if err == nil && len(batchResults) != end-start {
+				err = fmt.Errorf("provider returned %d results for %d messages", len(batchResults), end-start)
+			}
+			if err != nil {
+				errs[b] = err
+				log.Printf("SMS batch %d/%d (%d messages) in the %s lane failed: %v", b+1, batches, end-start, l.Name, err)
+				for i := start; i < end; i++ {
+					results[i] = SMSProviderResult{
+						Result:  ProviderResultFailed,
+						Status:  "error",
+						Message: err.Error(),
+					}
+				}
+				return
+			}
+			copy(results[start:end], batchResults)
+		}(b, start, end)
+	}
+	wg.Wait()
+
+	failed := 0
+	for _, err := range errs {
+		if err != nil {
+			failed++
+		}
+	}
+	if failed == batches {
+		return nil, errs[0]
+	}
+	return results, nil
+}
+
+// lane returns the lane with the given name, or the standard lane
+func (s *LaneScheduler) lane(name string) *lane {
+	var standard *lane
+	for _, l := range s.lanes {
+		if l.Name == name {
+			return l
+		}
+		if l.Name == models.LaneStandard {
+			standard = l
+		}
+	}
+	return standard
+}
+
+// acquire waits until the lane may start a provider request for n messages and reserves it.
+// It returns how long the request must still wait for the lane's rate limit.
+func (s *LaneScheduler) acquire(l *lane, n int) time.Duration {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	l.Waiting++
+	for l.InFlight >= l.Concurrency || s.higherWaiting(l) {
+		s.cond.Wait()
+	}
+	l.Waiting--
+	l.InFlight++
+	l.Sent += int64(n)
+	// Lower lanes may be waiting for this lane to drain
+	s.cond.Broadcast()
+
+	if l.Rate == 0 {
+		return 0
+	}
+	now := time.Now()
+	if l.next.Before(now) {
+		l.next = now
+	}
+	delay := l.next.Sub(now)
+	l.next = l.next.Add(time.Duration(n) * time.Second / time.Duration(l.Rate))
+	return delay
+}
+
+// release frees a provider request slot of the lane
+func (s *LaneScheduler) release(l *lane) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	l.InFlight--
+	s.cond.Broadcast()
+}
+
+// higherWaiting reports whether a lane of higher priority than l has requests waiting to start
+func (s *LaneScheduler) higherWaiting(l *lane) bool {
+	for _, other := range s.lanes {
+		if other == l {
+			return false
+		}
+		if other.Waiting > 0 {
+			return true
+		}
+	}
+	return false
+}
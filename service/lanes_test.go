@@ -0,0 +1,309 @@
+package service
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Ian-Balijawa/sms-gateway/config"
+	"github.com/Ian-Balijawa/sms-gateway/models"
+
+	"github.com/google/uuid"
+)
+
+// newTestLaneScheduler returns a scheduler with the given lane limits and no provider
+func newTestLaneScheduler(t *testing.T, transactional, standard, bulk [2]int) *LaneScheduler {
+	t.Helper()
+	setConfig(t, config.Config{
+		LaneTransactionalConcurrency: transactional[0],
+		LaneTransactionalRate:        transactional[1],
+		LaneStandardConcurrency:      standard[0],
+		LaneStandardRate:             standard[1],
+		LaneBulkConcurrency:          bulk[0],
+		LaneBulkRate:                 bulk[1],
+	})
+	return NewLaneScheduler(nil)
+}
+
+// laneStats returns the current stats of a lane
+func laneStats(s *LaneScheduler, name string) LaneStats {
+	for _, stats := range s.Stats() {
+		if stats.Name == name {
+			return stats
+		}
+	}
+	return LaneStats{}
+}
+
+// waitFor fails the test if the condition does not hold within a second
+func waitFor(t *testing.T, what string, condition func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for !condition() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestLaneSchedulerConcurrency(t *testing.T) {
+	s := newTestLaneScheduler(t, [2]int{1, 0}, [2]int{2, 0}, [2]int{1, 0})
+	standard := s.lane(models.LaneStandard)
+
+	s.acquire(standard, 1)
+	s.acquire(standard, 1)
+
+	started := make(chan struct{})
+	go func() {
+		s.acquire(standard, 1)
+		close(started)
+	}()
+	waitFor(t, "the third request to wait", func() bool { return laneStats(s, models.LaneStandard).Waiting == 1 })
+
+	select {
+	case <-started:
+		t.Fatal("request started beyond the lane's concurrency")
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	s.release(standard)
+	<-started
+
+	stats := laneStats(s, models.LaneStandard)
+	if stats.InFlight != 2 || stats.Waiting != 0 || stats.Sent != 3 {
+		t.Errorf("stats = %+v, want 2 in flight, 0 waiting and 3 sent", stats)
+	}
+}
+
+func TestLaneSchedulerPriority(t *testing.T) {
+	s := newTestLaneScheduler(t, [2]int{1, 0}, [2]int{1, 0}, [2]int{4, 0})
+	transactional := s.lane(models.LaneTransactional)
+	standard := s.lane(models.LaneStandard)
+	bulk := s.lane(models.LaneBulk)
+
+	// A bulk request starts while no higher lane is waiting
+	s.acquire(bulk, 1)
+
+	// A transactional request is in flight and another one waits for it
+	s.acquire(transactional, 1)
+	order := make(chan string, 3)
+	go func() {
+		s.acquire(transactional, 1)
+		order <- models.LaneTransactional
+	}()
+	waitFor(t, "the transactional request to wait", func() bool {
+		return laneStats(s, models.LaneTransactional).Waiting == 1
+	})
+
+	// Lower lanes hold back while a higher lane is waiting, even with free slots
+	go func() {
+		s.acquire(standard, 1)
+		order <- models.LaneStandard
+	}()
+	go func() {
+		s.acquire(bulk, 1)
+		order <- models.LaneBulk
+	}()
+	waitFor(t, "the standard and bulk requests to wait", func() bool {
+		return laneStats(s, models.LaneStandard).Waiting == 1 && laneStats(s, models.LaneBulk).Waiting == 1
+	})
+	select {
+	case lane := <-order:
+		t.Fatalf("%s request started while a transactional request was waiting", lane)
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	// Once the transactional request starts, nothing is waiting in a higher lane
+	s.release(transactional)
+	started := make(map[string]bool)
+	for range 3 {
+		select {
+		case lane := <-order:
+			started[lane] = true
+		case <-time.After(time.Second):
+			t.Fatalf("only %v started after the transactional lane drained", started)
+		}
+	}
+}
+
+func TestLaneSchedulerPriorityCascade(t *testing.T) {
+	s := newTestLaneScheduler(t, [2]int{1, 0}, [2]int{1, 0}, [2]int{4, 0})
+	standard := s.lane(models.LaneStandard)
+	bulk := s.lane(models.LaneBulk)
+
+	// The standard lane is full and has a request waiting, so the bulk lane waits too
+	s.acquire(standard, 1)
+	standardStarted := make(chan struct{})
+	go func() {
+		s.acquire(standard, 1)
+		close(standardStarted)
+	}()
+	waitFor(t, "the standard request to wait", func() bool { return laneStats(s, models.LaneStandard).Waiting == 1 })
+
+	bulkStarted := make(chan struct{})
+	go func() {
+		s.acquire(bulk, 1)
+		close(bulkStarted)
+	}()
+	waitFor(t, "the bulk request to wait", func() bool { return laneStats(s, models.LaneBulk).Waiting == 1 })
+	select {
+	case <-bulkStarted:
+		t.Fatal("bulk request started while a standard request was waiting")
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	s.release(standard)
+	<-standardStarted
+	<-bulkStarted
+	if stats := laneStats(s, models.LaneBulk); stats.InFlight != 1 || stats.Waiting != 0 {
+		t.Errorf("bulk stats = %+v, want 1 in flight and none waiting", stats)
+	}
+}
+
+func TestLaneSchedulerRate(t *testing.T) {
+	s := newTestLaneScheduler(t, [2]int{4, 0}, [2]int{4, 0}, [2]int{4, 10})
+	bulk := s.lane(models.LaneBulk)
+	standard := s.lane(models.LaneStandard)
+
+	// 10 messages per second: each request waits for the messages reserved before it
+	tests := []struct {
+		messages int
+		delay    time.Duration
+	}{
+		{messages: 5, delay: 0},
+		{messages: 5, delay: 500 * time.Millisecond},
+		{messages: 1, delay: time.Second},
+		{messages: 3, delay: 1100 * time.Millisecond},
+	}
+	for i, tt := range tests {
+		delay := s.acquire(bulk, tt.messages)
+		// Time passes between calls, so the delay may be slightly shorter than reserved
+		if delay > tt.delay || delay < tt.delay-100*time.Millisecond {
+			t.Errorf("request %d waits %v, want about %v", i, delay, tt.delay)
+		}
+	}
+
+	// Lanes without a rate limit never wait
+	if delay := s.acquire(standard, 100); delay != 0 {
+		t.Errorf("unlimited lane waits %v, want 0", delay)
+	}
+
+	// A lane that has been idle does not build up credit
+	s.lane(models.LaneTransactional).Rate = 10
+	s.lane(models.LaneTransactional).next = time.Now().Add(-time.Hour)
+	if delay := s.acquire(s.lane(models.LaneTransactional), 5); delay != 0 {
+		t.Errorf("idle lane waits %v, want 0", delay)
+	}
+	if delay := s.acquire(s.lane(models.LaneTransactional), 1); delay < 400*time.Millisecond {
+		t.Errorf("second request after idle waits %v, want about 500ms", delay)
+	}
+}
+
+func TestLaneSchedulerLimits(t *testing.T) {
+	s := newTestLaneScheduler(t, [2]int{0, -5}, [2]int{3, 20}, [2]int{1, 0})
+
+	transactional := laneStats(s, models.LaneTransactional)
+	if transactional.Concurrency != 1 || transactional.Rate != 0 {
+		t.Errorf("transactional limits = %d/%d, want invalid limits raised to 1/0", transactional.Concurrency, transactional.Rate)
+	}
+	if standard := laneStats(s, models.LaneStandard); standard.Concurrency != 3 || standard.Rate != 20 {
+		t.Errorf("standard limits = %d/%d, want 3/20", standard.Concurrency, standard.Rate)
+	}
+	if lane := s.lane("express"); lane.Name != models.LaneStandard {
+		t.Errorf("unknown lane uses %s, want standard", lane.Name)
+	}
+}
+
+// batchProvider records the size of each request and fails requests whose first number is "fail"
+type batchProvider struct {
+	maxBatchSize int
+
+	mu      sync.Mutex
+	batches []int
+}
+
+func (p *batchProvider) Name() string      { return "batch" }
+func (p *batchProvider) MaxBatchSize() int { return p.maxBatchSize }
+
+func (p *batchProvider) SendSMS(messages []models.SMSRequest, defaultSenderID string) ([]SMSProviderResult, error) {
+	p.mu.Lock()
+	p.batches = append(p.batches, len(messages))
+	p.mu.Unlock()
+	if messages[0].Number == "fail" {
+		return nil, errors.New("connection refused")
+	}
+	results := make([]SMSProviderResult, len(messages))
+	for i, msg := range messages {
+		results[i] = SMSProviderResult{Result: ProviderResultSent, MessageID: msg.Number}
+	}
+	return results, nil
+}
+
+func TestLaneSchedulerBatches(t *testing.T) {
+	newTestLaneScheduler(t, [2]int{1, 0}, [2]int{2, 0}, [2]int{1, 0})
+	provider := &batchProvider{maxBatchSize: 3}
+	s := NewLaneScheduler(provider)
+
+	messages := make([]models.SMSRequest, 7)
+	for i := range messages {
+		messages[i].Number = string(rune('a' + i))
+	}
+	messages[3].Number = "fail"
+
+	results, err := s.Send(models.LaneStandard, messages, "")
+	if err != nil {
+		t.Fatalf("Send returned error: %v", err)
+	}
+	total := 0
+	for _, size := range provider.batches {
+		if size > 3 {
+			t.Errorf("request of %d messages, want at most 3", size)
+		}
+		total += size
+	}
+	if len(provider.batches) != 3 || total != 7 {
+		t.Errorf("requests = %v, want 3 requests of 7 messages", provider.batches)
+	}
+
+	// Only the batch that could not reach the provider fails, results stay in order
+	for i, result := range results {
+		failed := i >= 3 && i < 6
+		if failed != (result.Result == ProviderResultFailed) {
+			t.Errorf("results[%d] = %+v, failed = %v", i, result, failed)
+		}
+		if !failed && result.MessageID != messages[i].Number {
+			t.Errorf("results[%d].MessageID = %q, want %q", i, result.MessageID, messages[i].Number)
+		}
+	}
+}
+
+func TestMessageLane(t *testing.T) {
+	campaignID := uuid.New()
+	jobID := uuid.New()
+
+	tests := []struct {
+		name string
+		req  DispatchRequest
+		msg  models.SMSRequest
+		want string
+	}{
+		{name: "single send", msg: models.SMSRequest{Priority: "1"}, want: models.LaneStandard},
+		{name: "transactional", msg: models.SMSRequest{Priority: models.PriorityTransactional}, want: models.LaneTransactional},
+		{name: "marketing", msg: models.SMSRequest{Marketing: true}, want: models.LaneBulk},
+		{name: "bulk send", req: DispatchRequest{Bulk: true}, want: models.LaneBulk},
+		{name: "transactional in bulk send", req: DispatchRequest{Bulk: true}, msg: models.SMSRequest{Priority: models.PriorityTransactional}, want: models.LaneTransactional},
+		{name: "recurring job", req: DispatchRequest{RecurringJobID: &jobID}, want: models.LaneBulk},
+		{name: "campaign", req: DispatchRequest{CampaignID: &campaignID}, want: models.LaneBulk},
+		{name: "transactional campaign", req: DispatchRequest{CampaignID: &campaignID}, msg: models.SMSRequest{Priority: models.PriorityTransactional}, want: models.LaneBulk},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := MessageLane(tt.req, tt.msg); got != tt.want {
+				t.Errorf("MessageLane() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,178 @@
+package service
+
+import (
+	"sort"
+	"time"
+
+	"github.com/Ian-Balijawa/sms-gateway/config"
+	"github.com/Ian-Balijawa/sms-gateway/database"
+	"github.com/Ian-Balijawa/sms-gateway/models"
+
+	"github.com/google/uuid"
+)
+
+// walletCost converts a cost reported by the provider to the wallet currency's smallest unit,
+// the unit message prices are in, so that provider costs and prices can be compared
+func walletCost(cost *float64) *float64 {
+	if cost == nil {
+		return nil
+	}
+	converted := *cost * config.AppConfig.ProviderCostRate
+	return &converted
+}
+
+// MarginTotals compares what clients were charged with what the provider charged.
+// Margin only covers messages with a provider cost, so that messages the provider did
+// not report a cost for do not count as profit.
+type MarginTotals struct {
+	Messages       int64   `json:"messages"`
+	CostedMessages int64   `json:"costed_messages"` // Messages with a provider-reported cost
+	Price          int64   `json:"price"`           // Charged to clients for all messages
+	CostedPrice    int64   `json:"costed_price"`    // Charged to clients for costed messages
+	ProviderCost   float64 `json:"provider_cost"`   // In the same unit as Price
+	Margin         float64 `json:"margin"`          // CostedPrice - ProviderCost
+	LossMessages   int64   `json:"loss_messages"`   // Messages the provider charged more for than the client paid
+}
+
+// add accumulates another set of totals
+func (t *MarginTotals) add(other MarginTotals) {
+	t.Messages += other.Messages
+	t.CostedMessages += other.CostedMessages
+	t.Price += other.Price
+	t.CostedPrice += other.CostedPrice
+	t.ProviderCost += other.ProviderCost
+	t.Margin = float64(t.CostedPrice) - t.ProviderCost
+	t.LossMessages += other.LossMessages
+}
+
+// ClientMargin is the margin on one client's messages
+type ClientMargin struct {
+	ClientID   uuid.UUID `json:"client_id"`
+	ClientName string    `json:"client_name"`
+	MarginTotals
+}
+
+// ProviderMargin is the margin on messages sent through one provider
+type ProviderMargin struct {
+	Provider string `json:"provider"`
+	MarginTotals
+}
+
+// DayMargin is the margin on messages sent on one day (UTC)
+type DayMargin struct {
+	Day string `json:"day"` // YYYY-MM-DD
+	MarginTotals
+}
+
+// ReconciliationReport aggregates the margin on billable messages sent in [From, To)
+type ReconciliationReport struct {
+	From       time.Time        `json:"from"`
+	To         time.Time        `json:"to"`
+	Currency   string           `json:"currency"`
+	Totals     MarginTotals     `json:"totals"`
+	ByClient   []ClientMargin   `json:"by_client"`
+	ByProvider []ProviderMargin `json:"by_provider"`
+	ByDay      []DayMargin      `json:"by_day"`
+}
+
+// utcDay returns an SQL expression for the UTC date of a timestamp column, whatever the
+// database session's time zone. SQLite converts timestamps stored with an offset to UTC.
+func utcDay(column string) string {
+	if database.DB.Dialector.Name() == "postgres" {
+		return "DATE(" + column + " AT TIME ZONE 'UTC')"
+	}
+	return "DATE(" + column + ")"
+}
+
+// BuildReconciliationReport totals client prices against provider costs for billable
+// messages sent between from and to, optionally for a single client
+func BuildReconciliationReport(from, to time.Time, clientID *uuid.UUID) (*ReconciliationReport, error) {
+	var rows []struct {
+		ClientID uuid.UUID
+		Provider string
+		Day      string
+		MarginTotals
+	}
+	query := database.DB.Model(&models.SMSLog{}).
+		Select("client_id, provider, "+utcDay("created_at")+" AS day, COUNT(*) AS messages, "+
+			"SUM(CASE WHEN provider_cost IS NOT NULL THEN 1 ELSE 0 END) AS costed_messages, "+
+			"COALESCE(SUM(price), 0) AS price, "+
+			"COALESCE(SUM(CASE WHEN provider_cost IS NOT NULL THEN price ELSE 0 END), 0) AS costed_price, "+
+			"COALESCE(SUM(provider_cost), 0) AS provider_cost, "+
+			"SUM(CASE WHEN provider_cost > price THEN 1 ELSE 0 END) AS loss_messages").
+		Where("status IN ? AND created_at >= ? AND created_at < ?", BillableStatuses, from, to)
+	if clientID != nil {
+		query = query.Where("client_id = ?", *clientID)
+	}
+	if err := query.Group("client_id, provider, day").Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	report := &ReconciliationReport{
+		From:       from,
+		To:         to,
+		Currency:   config.AppConfig.WalletCurrency,
+		ByClient:   []ClientMargin{},
+		ByProvider: []ProviderMargin{},
+		ByDay:      []DayMargin{},
+	}
+	byClient := make(map[uuid.UUID]*ClientMargin)
+	byProvider := make(map[string]*ProviderMargin)
+	byDay := make(map[string]*DayMargin)
+
+	for _, row := range rows {
+		// Dates scan as "YYYY-MM-DD" or as a timestamp depending on the database
+		day := row.Day
+		if len(day) > 10 {
+			day = day[:10]
+		}
+		provider := row.Provider
+		if provider == "" {
+			provider = "unknown"
+		}
+
+		if byClient[row.ClientID] == nil {
+			byClient[row.ClientID] = &ClientMargin{ClientID: row.ClientID}
+		}
+		if byProvider[provider] == nil {
+			byProvider[provider] = &ProviderMargin{Provider: provider}
+		}
+		if byDay[day] == nil {
+			byDay[day] = &DayMargin{Day: day}
+		}
+
+		byClient[row.ClientID].add(row.MarginTotals)
+		byProvider[provider].add(row.MarginTotals)
+		byDay[day].add(row.MarginTotals)
+		report.Totals.add(row.MarginTotals)
+	}
+
+	if len(byClient) > 0 {
+		ids := make([]uuid.UUID, 0, len(byClient))
+		for id := range byClient {
+			ids = append(ids, id)
+		}
+		var clients []models.APIClient
+		if err := database.DB.Unscoped().Select("id", "name").Where("id IN ?", ids).Find(&clients).Error; err != nil {
+			return nil, err
+		}
+		for _, client := range clients {
+			byClient[client.ID].ClientName = client.Name
+		}
+	}
+
+	for _, margin := range byClient {
+		report.ByClient = append(report.ByClient, *margin)
+	}
+	for _, margin := range byProvider {
+		report.ByProvider = append(report.ByProvider, *margin)
+	}
+	for _, margin := range byDay {
+		report.ByDay = append(report.ByDay, *margin)
+	}
+	sort.Slice(report.ByClient, func(i, j int) bool { return report.ByClient[i].Margin < report.ByClient[j].Margin })
+	sort.Slice(report.ByProvider, func(i, j int) bool { return report.ByProvider[i].Provider < report.ByProvider[j].Provider })
+	sort.Slice(report.ByDay, func(i, j int) bool { return report.ByDay[i].Day < report.ByDay[j].Day })
+
+	return report, nil
+}
@@ -0,0 +1,232 @@
+package service
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"time"
+	_ "time/tzdata" // Recurring jobs need time zones even on hosts without tzdata
+
+	"github.com/Ian-Balijawa/sms-gateway/database"
+	"github.com/Ian-Balijawa/sms-gateway/models"
+
+	"github.com/robfig/cron/v3"
+	"gorm.io/gorm"
+)
+
+// recurringPollInterval is how often the scheduler looks for due jobs
+const recurringPollInterval = 30 * time.Second
+
+var weekdayNames = map[string]bool{
+	"sun": true, "mon": true, "tue": true, "wed": true, "thu": true, "fri": true, "sat": true,
+}
+
+// NextRecurringRun returns the first time after the given time at which the job should run
+func NextRecurringRun(job *models.RecurringJob, after time.Time) (time.Time, error) {
+	schedule, err := recurringSchedule(job)
+	if err != nil {
+		return time.Time{}, err
+	}
+	next := schedule.Next(after)
+	if next.IsZero() {
+		return time.Time{}, fmt.Errorf("schedule never fires")
+	}
+	return next, nil
+}
+
+// recurringSchedule builds a cron schedule for the job, evaluated in the job's time zone.
+// Daily and weekly rules are translated into the equivalent cron expression.
+func recurringSchedule(job *models.RecurringJob) (cron.Schedule, error) {
+	timezone := job.Timezone
+	if timezone == "" {
+		timezone = "UTC"
+	}
+	location, err := time.LoadLocation(timezone)
+	if err != nil {
+		return nil, fmt.Errorf("invalid timezone %q", timezone)
+	}
+
+	var spec string
+	switch job.ScheduleType {
+	case models.ScheduleCron:
+		spec = strings.TrimSpace(job.CronExpression)
+		if spec == "" {
+			return nil, fmt.Errorf("cron_expression is required for cron schedules")
+		}
+		if strings.HasPrefix(spec, "@every") || strings.HasPrefix(spec, "CRON_TZ=") || strings.HasPrefix(spec, "TZ=") {
+			return nil, fmt.Errorf("unsupported cron expression %q, use the timezone field instead", spec)
+		}
+	case models.ScheduleDaily, models.ScheduleWeekly:
+		hour, minute, err := parseTimeOfDay(job.TimeOfDay)
+		if err != nil {
+			return nil, err
+		}
+		days := "*"
+		if job.ScheduleType == models.ScheduleWeekly {
+			if len(job.Weekdays) == 0 {
+				return nil, fmt.Errorf("weekdays are required for weekly schedules")
+			}
+			for _, day := range job.Weekdays {
+				if !weekdayNames[strings.ToLower(day)] {
+					return nil, fmt.Errorf("invalid weekday %q", day)
+				}
+			}
+			days = strings.ToLower(strings.Join(job.Weekdays, ","))
+		}
+		spec = fmt.Sprintf("%d %d * * %s", minute, hour, days)
+	default:
+		return nil, fmt.Errorf("invalid schedule_type %q, expected cron, daily or weekly", job.ScheduleType)
+	}
+
+	schedule, err := cron.ParseStandard(spec)
+	if err != nil {
+		return nil, fmt.Errorf("invalid schedule: %w", err)
+	}
+	if specSchedule, ok := schedule.(*cron.SpecSchedule); ok {
+		specSchedule.Location = location
+	}
+	return schedule, nil
+}
+
+func parseTimeOfDay(value string) (int, int, error) {
+	t, err := time.Parse("15:04", value)
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid time_of_day %q, expected HH:MM", value)
+	}
+	return t.Hour(), t.Minute(), nil
+}
+
+// RecurringScheduler runs recurring jobs when they become due
+type RecurringScheduler struct {
+	dispatcher *Dispatcher
+}
+
+func NewRecurringScheduler(dispatcher *Dispatcher) *RecurringScheduler {
+	return &RecurringScheduler{
+		dispatcher: dispatcher,
+	}
+}
+
+// Start polls for due jobs in a background goroutine
+func (s *RecurringScheduler) Start() {
+	go func() {
+		for {
+			s.runDueJobs(time.Now())
+			time.Sleep(recurringPollInterval)
+		}
+	}()
+
+	log.Println("Recurring job scheduler started")
+}
+
+func (s *RecurringScheduler) runDueJobs(now time.Time) {
+	var jobs []models.RecurringJob
+	if err := database.DB.
+		Where("status = ? AND next_run_at <= ?", models.RecurringJobActive, now).
+		Order("next_run_at").
+		Find(&jobs).Error; err != nil {
+		log.Printf("Error loading due recurring jobs: %v", err)
+		return
+	}
+
+	for i := range jobs {
+		s.runJob(&jobs[i], now)
+	}
+}
+
+func (s *RecurringScheduler) runJob(job *models.RecurringJob, now time.Time) {
+	next, err := NextRecurringRun(job, now)
+	if err != nil {
+		// The schedule can no longer be evaluated, stop the job until it is fixed
+		log.Printf("Pausing recurring job %s: %v", job.ID, err)
+		database.DB.Model(job).Updates(map[string]interface{}{
+			"status":     models.RecurringJobPaused,
+			"last_error": err.Error(),
+		})
+		return
+	}
+
+	// Claim this run by moving next_run_at forward. run_count acts as a version so
+	// that if another instance got there first no rows are updated and the run is skipped.
+	claim := database.DB.Model(&models.RecurringJob{}).
+		Where("id = ? AND run_count = ?", job.ID, job.RunCount).
+		Updates(map[string]interface{}{
+			"next_run_at": next,
+			"last_run_at": now,
+			"run_count":   gorm.Expr("run_count + 1"),
+		})
+	if claim.Error != nil || claim.RowsAffected == 0 {
+		return
+	}
+
+	run := models.RecurringJobRun{
+		JobID:     job.ID,
+		StartedAt: now,
+		Status:    models.RecurringRunRunning,
+	}
+	if err := database.DB.Create(&run).Error; err != nil {
+		log.Printf("Error creating run for recurring job %s: %v", job.ID, err)
+		return
+	}
+
+	runErr := s.execute(job, &run)
+
+	finished := time.Now()
+	run.FinishedAt = &finished
+	run.Status = models.RecurringRunCompleted
+	lastError := ""
+	if runErr != nil {
+		run.Status = models.RecurringRunFailed
+		run.Error = runErr.Error()
+		lastError = runErr.Error()
+	}
+	database.DB.Save(&run)
+	database.DB.Model(job).Update("last_error", lastError)
+
+	log.Printf("Recurring job %s run %s: status=%s sent=%d failed=%d", job.ID, run.ID, run.Status, run.Sent, run.Failed)
+}
+
+// execute sends the job's messages and records the counts on the run
+func (s *RecurringScheduler) execute(job *models.RecurringJob, run *models.RecurringJobRun) error {
+	var client models.APIClient
+	if err := database.DB.Where("id = ?", job.ClientID).First(&client).Error; err != nil {
+		return fmt.Errorf("client not found")
+	}
+	if !client.IsActive {
+		return fmt.Errorf("client is inactive")
+	}
+
+	messages, err := recurringMessages(job, client.DefaultRegion)
+	if err != nil {
+		return err
+	}
+	run.Total = len(messages)
+	if len(messages) == 0 {
+		return nil
+	}
+
+	if client.DailyUsage+len(messages) > client.DailyLimit {
+		return fmt.Errorf("run would exceed daily limit")
+	}
+	if client.MonthlyUsage+len(messages) > client.MonthlyLimit {
+		return fmt.Errorf("run would exceed monthly limit")
+	}
+
+	result, err := s.dispatcher.Dispatch(DispatchRequest{
+		Client:         &client,
+		Messages:       messages,
+		RecurringJobID: &job.ID,
+		RecurringRunID: &run.ID,
+	})
+	if result != nil {
+		run.Sent = result.Successful
+		run.Failed = result.Failed + result.Rejected + result.Blocked
+	}
+	return err
+}
+
+// recurringMessages expands the job's group and recipient list into messages.
+// Recipients without a country code are read in the given region.
+func recurringMessages(job *models.RecurringJob, region string) ([]models.SMSRequest, error) {
+	return AudienceMessages(job.ClientID, job.GroupID, job.Recipients, job.Message, job.SenderID, job.Priority, region)
+}
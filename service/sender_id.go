@@ -0,0 +1,121 @@
+package service
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+
+	"github.com/Ian-Balijawa/sms-gateway/config"
+	"github.com/Ian-Balijawa/sms-gateway/database"
+	"github.com/Ian-Balijawa/sms-gateway/models"
+
+	"github.com/google/uuid"
+)
+
+// NormalizeSenderIDName trims a requested sender name and checks that handsets can display it:
+// up to 11 letters, digits and spaces with at least one letter, or a number of up to 15 digits
+func NormalizeSenderIDName(name string) (string, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return "", fmt.Errorf("name is required")
+	}
+
+	letters, digits := 0, 0
+	for _, r := range name {
+		switch {
+		case r <= unicode.MaxASCII && unicode.IsLetter(r):
+			letters++
+		case r >= '0' && r <= '9':
+			digits++
+		case r == ' ':
+		default:
+			return "", fmt.Errorf("name may only contain letters, digits and spaces")
+		}
+	}
+	if letters == 0 {
+		if strings.Contains(name, " ") || digits > 15 {
+			return "", fmt.Errorf("numeric sender IDs must be at most 15 digits")
+		}
+		return name, nil
+	}
+	if len(name) > 11 {
+		return "", fmt.Errorf("alphanumeric sender IDs must be at most 11 characters")
+	}
+	return name, nil
+}
+
+// SenderIDs holds the sender IDs a client may send with
+type SenderIDs struct {
+	approved    map[string]bool
+	defaultName string
+}
+
+// LoadSenderIDs loads the client's approved sender IDs and default
+func LoadSenderIDs(clientID uuid.UUID) (*SenderIDs, error) {
+	var senderIDs []models.SenderID
+	if err := database.DB.
+		Where("client_id = ? AND status = ?", clientID, models.SenderIDApproved).
+		Find(&senderIDs).Error; err != nil {
+		return nil, err
+	}
+
+	ids := &SenderIDs{approved: make(map[string]bool, len(senderIDs))}
+	for _, senderID := range senderIDs {
+		ids.approved[senderID.Name] = true
+		if senderID.IsDefault {
+			ids.defaultName = senderID.Name
+		}
+	}
+	return ids, nil
+}
+
+// Resolve returns the sender ID to send a message with. An empty name resolves to the
+// client's default, or to SMS_SENDER_ID if the client has none. Other names must be
+// approved for the client, except SMS_SENDER_ID which every client may use.
+func (s *SenderIDs) Resolve(name string) (string, error) {
+	name = strings.TrimSpace(name)
+	switch {
+	case name == "" && s.defaultName != "":
+		return s.defaultName, nil
+	case name == "":
+		return config.AppConfig.SMSSenderID, nil
+	case name == config.AppConfig.SMSSenderID || s.approved[name]:
+		return name, nil
+	}
+	return "", fmt.Errorf("sender ID %q is not approved", name)
+}
+
+// CheckSenderID returns an error if the client may not send with the sender ID
+func CheckSenderID(clientID uuid.UUID, name string) error {
+	ids, err := LoadSenderIDs(clientID)
+	if err != nil {
+		return fmt.Errorf("failed to load sender IDs: %w", err)
+	}
+	_, err = ids.Resolve(name)
+	return err
+}
+
+// resolveSenderIDs sets the sender ID of each pending message, marking messages with a sender
+// ID the client may not use as rejected, and returns the rest
+func (d *Dispatcher) resolveSenderIDs(client *models.APIClient, logs []models.SMSLog, pending []int) ([]int, error) {
+	if len(pending) == 0 {
+		return pending, nil
+	}
+	ids, err := LoadSenderIDs(client.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	remaining := pending[:0]
+	for _, i := range pending {
+		senderID, err := ids.Resolve(logs[i].SenderID)
+		if err != nil {
+			logs[i].Status = models.SMSStatusRejected
+			logs[i].Error = err.Error()
+			continue
+		}
+		logs[i].SenderID = senderID
+		remaining = append(remaining, i)
+	}
+	return remaining, nil
+}
@@ -0,0 +1,47 @@
+package service
+
+import "testing"
+
+func TestNormalizeSenderIDName(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    string
+		wantErr bool
+	}{
+		{name: "alphanumeric", input: "ACME", want: "ACME"},
+		{name: "trimmed", input: "  ACME Ltd  ", want: "ACME Ltd"},
+		{name: "letters and digits", input: "Shop24", want: "Shop24"},
+		{name: "eleven characters", input: "ABCDEFGHIJK", want: "ABCDEFGHIJK"},
+		{name: "twelve characters", input: "ABCDEFGHIJKL", wantErr: true},
+		{name: "spaces count towards length", input: "ACME Stores", want: "ACME Stores"},
+		{name: "spaces over length", input: "ACME  Stores", wantErr: true},
+		{name: "numeric", input: "256701234567", want: "256701234567"},
+		{name: "numeric fifteen digits", input: "123456789012345", want: "123456789012345"},
+		{name: "numeric sixteen digits", input: "1234567890123456", wantErr: true},
+		{name: "numeric with space", input: "2567 01234", wantErr: true},
+		{name: "empty", input: "", wantErr: true},
+		{name: "only spaces", input: "   ", wantErr: true},
+		{name: "punctuation", input: "ACME-Ltd", wantErr: true},
+		{name: "plus sign", input: "+256701234567", wantErr: true},
+		{name: "non-ascii letter", input: "Café", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := NormalizeSenderIDName(tt.input)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("NormalizeSenderIDName(%q) = %q, want an error", tt.input, got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("NormalizeSenderIDName(%q) returned error: %v", tt.input, err)
+			}
+			if got != tt.want {
+				t.Errorf("NormalizeSenderIDName(%q) = %q, want %q", tt.input, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,238 @@
+package service
+
+import (
+	"crypto/rand"
+	"fmt"
+	"log"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/Ian-Balijawa/sms-gateway/config"
+	"github.com/Ian-Balijawa/sms-gateway/database"
+	"github.com/Ian-Balijawa/sms-gateway/models"
+	"github.com/Ian-Balijawa/sms-gateway/utils"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// shortLinkAlphabet holds the characters of short link codes
+const shortLinkAlphabet = "abcdefghijkmnpqrstuvwxyzABCDEFGHJKLMNPQRSTUVWXYZ23456789"
+
+// ShortLinksEnabled reports whether the gateway has a public URL to serve short links from
+func ShortLinksEnabled() bool {
+	return config.AppConfig.ShortLinkBaseURL != ""
+}
+
+// CheckShortLinks returns an error if any message asks for shortened links while
+// link shortening is not configured
+func CheckShortLinks(messages []models.SMSRequest) error {
+	if ShortLinksEnabled() {
+		return nil
+	}
+	for _, msg := range messages {
+		if msg.ShortenLinks {
+			return fmt.Errorf("link shortening is not configured, set SHORT_LINK_BASE_URL")
+		}
+	}
+	return nil
+}
+
+// ShortLinkURL returns the public URL of a short link code
+func ShortLinkURL(code string) string {
+	return strings.TrimRight(config.AppConfig.ShortLinkBaseURL, "/") + "/l/" + code
+}
+
+// ShortenedText returns the message as it will be sent with its links shortened, using
+// placeholder codes. It is used to count segments before the links are created.
+func ShortenedText(text string) string {
+	if !ShortLinksEnabled() {
+		return text
+	}
+	placeholder := ShortLinkURL(strings.Repeat("x", config.AppConfig.ShortLinkCodeLength))
+	return utils.ReplaceLinks(text, func(link string) string {
+		if isShortLink(link) {
+			return link
+		}
+		return placeholder
+	})
+}
+
+// isShortLink reports whether a link already points at the gateway's short links
+func isShortLink(link string) bool {
+	return strings.HasPrefix(link, ShortLinkURL(""))
+}
+
+// newShortLinkCode returns a random short link code of the configured length
+func newShortLinkCode() (string, error) {
+	length := config.AppConfig.ShortLinkCodeLength
+	if length <= 0 {
+		length = 7
+	}
+
+	code := make([]byte, length)
+	for i := range code {
+		n, err := rand.Int(rand.Reader, big.NewInt(int64(len(shortLinkAlphabet))))
+		if err != nil {
+			return "", err
+		}
+		code[i] = shortLinkAlphabet[n.Int64()]
+	}
+	return string(code), nil
+}
+
+// RecordLinkClick counts a click on a short link and records when and by which browser it was made
+func RecordLinkClick(link *models.ShortLink, userAgent, ipAddress string) error {
+	now := time.Now().UTC()
+	return database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&models.LinkClick{
+			CreatedAt:   now,
+			ShortLinkID: link.ID,
+			SMSLogID:    link.SMSLogID,
+			UserAgent:   userAgent,
+			IPAddress:   ipAddress,
+		}).Error; err != nil {
+			return err
+		}
+		return tx.Model(&models.ShortLink{}).
+			Where("id = ?", link.ID).
+			Updates(map[string]interface{}{
+				"clicks":          gorm.Expr("clicks + ?", 1),
+				"last_clicked_at": now,
+			}).Error
+	})
+}
+
+// shortenLinkAttempts is how many times short links whose codes are already taken are given
+// new codes before the dispatch fails
+const shortenLinkAttempts = 5
+
+// shortenLinks replaces the links in pending messages that ask for it with short links,
+// recounting their segments, and saves the short links. While link shortening is not
+// configured, messages asking for it are rejected, as they are by the API.
+// It returns the messages that can be sent.
+func (d *Dispatcher) shortenLinks(req DispatchRequest, logs []models.SMSLog, pending []int) ([]int, error) {
+	if !ShortLinksEnabled() {
+		remaining := pending[:0]
+		for _, i := range pending {
+			if req.Messages[i].ShortenLinks {
+				logs[i].Status = models.SMSStatusRejected
+				logs[i].Error = "link shortening is not configured"
+				continue
+			}
+			remaining = append(remaining, i)
+		}
+		return remaining, nil
+	}
+
+	var links []models.ShortLink
+	var owners []int // Index of the message each link belongs to
+	for _, i := range pending {
+		if !req.Messages[i].ShortenLinks {
+			continue
+		}
+
+		var err error
+		message := utils.ReplaceLinks(logs[i].Message, func(link string) string {
+			if err != nil || isShortLink(link) {
+				return link
+			}
+			var code string
+			if code, err = newShortLinkCode(); err != nil {
+				return link
+			}
+
+			target := link
+			if !strings.Contains(target, "://") {
+				target = "http://" + target
+			}
+			links = append(links, models.ShortLink{
+				ClientID: req.Client.ID,
+				SMSLogID: logs[i].ID,
+				Code:     code,
+				URL:      target,
+			})
+			owners = append(owners, i)
+			return ShortLinkURL(code)
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		logs[i].Message = message
+		logs[i].Segments = utils.MessageSegments(message)
+	}
+
+	return pending, saveShortLinks(logs, links, owners)
+}
+
+// saveShortLinks saves new short links. Codes are random, so links whose code is already taken
+// are given a new code, in the link and in its message, and saved again.
+func saveShortLinks(logs []models.SMSLog, links []models.ShortLink, owners []int) error {
+	for attempt := 0; len(links) > 0; attempt++ {
+		if attempt == shortenLinkAttempts {
+			return fmt.Errorf("no free short link code found for %d links", len(links))
+		}
+
+		result := database.DB.Clauses(clause.OnConflict{DoNothing: true}).CreateInBatches(&links, 100)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == int64(len(links)) {
+			return nil
+		}
+
+		ids := make([]uuid.UUID, len(links))
+		for j := range links {
+			ids[j] = links[j].ID
+		}
+		var saved []uuid.UUID
+		if err := database.DB.Model(&models.ShortLink{}).Where("id IN ?", ids).Pluck("id", &saved).Error; err != nil {
+			return err
+		}
+		isSaved := make(map[uuid.UUID]bool, len(saved))
+		for _, id := range saved {
+			isSaved[id] = true
+		}
+
+		retry := links[:0]
+		retryOwners := owners[:0]
+		for j, link := range links {
+			if isSaved[link.ID] {
+				continue
+			}
+			code, err := newShortLinkCode()
+			if err != nil {
+				return err
+			}
+			i := owners[j]
+			logs[i].Message = strings.Replace(logs[i].Message, ShortLinkURL(link.Code), ShortLinkURL(code), 1)
+			link.ID = uuid.Nil
+			link.Code = code
+			retry = append(retry, link)
+			retryOwners = append(retryOwners, i)
+		}
+		links, owners = retry, retryOwners
+	}
+	return nil
+}
+
+// discardShortLinks deletes the short links created for messages whose logs are not saved,
+// so that no link is left pointing at a message that does not exist
+func discardShortLinks(req DispatchRequest, logs []models.SMSLog) {
+	var ids []uuid.UUID
+	for i := range logs {
+		if req.Messages[i].ShortenLinks {
+			ids = append(ids, logs[i].ID)
+		}
+	}
+
+	for start := 0; start < len(ids); start += suppressionLookupBatch {
+		end := min(start+suppressionLookupBatch, len(ids))
+		if err := database.DB.Where("sms_log_id IN ?", ids[start:end]).Delete(&models.ShortLink{}).Error; err != nil {
+			log.Printf("Error deleting short links of unsaved messages: %v", err)
+		}
+	}
+}
@@ -7,6 +7,8 @@ import (
 	"io"
 	"log"
 	"net/http"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/Ian-Balijawa/sms-gateway/config"
@@ -17,10 +19,121 @@ import (
 type SMSProviderResponse struct {
 	Status  string `json:"Status"`
 	Message string `json:"Message"`
+
+	// Provider reference for the message, under whichever key the provider uses
+	MessageID             string `json:"MessageID,omitempty"`
+	MsgID                 string `json:"MsgId,omitempty"`
+	MsgFollowUpUniqueCode string `json:"MsgFollowUpUniqueCode,omitempty"`
+
+	// What the provider charged for the message, when it reports it
+	Cost *providerAmount `json:"Cost,omitempty"`
+
+	// Per-message results, when the provider returns them for a batch
+	Messages []SMSProviderResponse `json:"Messages,omitempty"`
+	Data     []SMSProviderResponse `json:"Data,omitempty"`
+}
+
+// providerAmount is a number the provider may send either as a JSON number or as a string
+type providerAmount float64
+
+// UnmarshalJSON accepts 35, 35.5, "35" and "35.5"; other values are ignored
+func (a *providerAmount) UnmarshalJSON(data []byte) error {
+	text := strings.Trim(string(data), "\" ")
+	if value, err := strconv.ParseFloat(text, 64); err == nil {
+		*a = providerAmount(value)
+	}
+	return nil
+}
+
+// Outcomes reported in SMSProviderResult
+const (
+	ProviderResultSent    = "sent"
+	ProviderResultFailed  = "failed"
+	ProviderResultUnknown = "unknown" // Accepted as a batch, but the provider gave no result for this message
+)
+
+// SMSProviderResult is the provider's outcome for one message
+type SMSProviderResult struct {
+	Result    string   // One of the ProviderResult* outcomes
+	Status    string   // Status reported by the provider
+	Message   string   // Message reported by the provider
+	MessageID string   // Provider reference for the message, if any
+	Cost      *float64 // What the provider charged for the message, if reported
+}
+
+// succeeded reports whether the provider status means the message was accepted
+func (r SMSProviderResponse) succeeded() bool {
+	switch strings.ToLower(r.Status) {
+	case "success", "ok", "sent":
+		return true
+	}
+	return false
+}
+
+// failed reports whether the provider status means the message was refused
+func (r SMSProviderResponse) failed() bool {
+	switch strings.ToLower(r.Status) {
+	case "failed", "failure", "fail", "error", "rejected":
+		return true
+	}
+	return false
+}
+
+// messageID returns the provider reference for the message, if any
+func (r SMSProviderResponse) messageID() string {
+	switch {
+	case r.MessageID != "":
+		return r.MessageID
+	case r.MsgID != "":
+		return r.MsgID
+	default:
+		return r.MsgFollowUpUniqueCode
+	}
+}
+
+// result converts a response describing a single message. Statuses that are neither
+// a success nor a failure leave the outcome unknown.
+func (r SMSProviderResponse) result() SMSProviderResult {
+	result := SMSProviderResult{
+		Result:    ProviderResultUnknown,
+		Status:    r.Status,
+		Message:   r.Message,
+		MessageID: r.messageID(),
+	}
+	switch {
+	case r.succeeded():
+		result.Result = ProviderResultSent
+	case r.failed():
+		result.Result = ProviderResultFailed
+	}
+	if r.Cost != nil {
+		cost := float64(*r.Cost)
+		result.Cost = &cost
+	}
+	return result
+}
+
+// Provider sends messages to handsets, returning one result per message in order.
+// SendSMS makes a single provider request of at most MaxBatchSize messages; callers
+// split larger sends into batches, see LaneScheduler.
+type Provider interface {
+	Name() string
+	MaxBatchSize() int
+	SendSMS(messages []models.SMSRequest, defaultSenderID string) ([]SMSProviderResult, error)
+}
+
+// NewProvider returns the provider selected by SMS_PROVIDER
+func NewProvider() Provider {
+	if strings.EqualFold(config.AppConfig.SMSProvider, "fake") {
+		log.Println("Using the fake SMS provider, messages will not be delivered")
+		return NewFakeProvider()
+	}
+	return NewSMSProvider()
 }
 
 type SMSProvider struct {
-	client *http.Client
+	client    *http.Client
+	batchSize int
 }
 
 func NewSMSProvider() *SMSProvider {
@@ -28,9 +141,20 @@ func NewSMSProvider() *SMSProvider {
 		client: &http.Client{
 			Timeout: 10 * time.Second,
 		},
+		batchSize: max(config.AppConfig.SMSBatchSize, 1),
 	}
 }
 
+// Name identifies the provider on message logs
+func (s *SMSProvider) Name() string {
+	return "egosms"
+}
+
+// MaxBatchSize is the most messages sent in one request, set by SMS_BATCH_SIZE
+func (s *SMSProvider) MaxBatchSize() int {
+	return s.batchSize
+}
+
 func (s *SMSProvider) GetAPIURL() string {
 	if config.AppConfig.SMSSandboxMode {
 		return config.AppConfig.SMSSandboxURL
@@ -38,7 +162,8 @@ func (s *SMSProvider) GetAPIURL() string {
 	return config.AppConfig.SMSLiveURL
 }
 
-func (s *SMSProvider) SendSMS(messages []models.SMSRequest, defaultSenderID string) ([]SMSProviderResponse, error) {
+// SendSMS submits the messages in a single provider request
+func (s *SMSProvider) SendSMS(messages []models.SMSRequest, defaultSenderID string) ([]SMSProviderResult, error) {
 	// Prepare payload matching the egosms.co API format
 	payload := map[string]interface{}{
 		"method": "SendSms",
@@ -68,6 +193,42 @@ func (s *SMSProvider) SendSMS(messages []models.SMSRequest, defaultSenderID stri
 		})
 	}
 
+	body, err := s.post(payload)
+	if err != nil {
+		return nil, err
+	}
+	return parseProviderResponse(body, len(messages)), nil
+}
+
+// Balance returns the credit left on the provider account
+func (s *SMSProvider) Balance() (float64, error) {
+	body, err := s.post(map[string]interface{}{
+		"method": "Balance",
+		"userdata": map[string]string{
+			"username": config.AppConfig.SMSUsername,
+			"password": config.AppConfig.SMSPassword,
+		},
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	var resp struct {
+		Status  string          `json:"Status"`
+		Message string          `json:"Message"`
+		Balance *providerAmount `json:"Balance"`
+	}
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return 0, fmt.Errorf("unexpected balance response: %s", string(body))
+	}
+	if resp.Balance == nil {
+		return 0, fmt.Errorf("provider did not return a balance: %s %s", resp.Status, resp.Message)
+	}
+	return float64(*resp.Balance), nil
+}
+
+// post sends a request to the provider API and returns the response body
+func (s *SMSProvider) post(payload map[string]interface{}) ([]byte, error) {
 	// Serialize payload
 	jsonData, err := json.Marshal(payload)
 	if err != nil {
@@ -92,24 +253,70 @@ func (s *SMSProvider) SendSMS(messages []models.SMSRequest, defaultSenderID stri
 	if err != nil {
 		return nil, fmt.Errorf("failed to read response: %w", err)
 	}
+	return body, nil
+}
+
+// parseProviderResponse maps the provider's reply to exactly one result per message.
+// Per-message results are used when the provider returns one for every message; a single
+// message takes the batch status. A batch that was rejected as a whole fails every message,
+// while any other batch without per-message results is reported as unknown rather than
+// assuming every message went out.
+func parseProviderResponse(body []byte, count int) []SMSProviderResult {
+	results := make([]SMSProviderResult, count)
+
+	// Some replies are a plain array of per-message results
+	var list []SMSProviderResponse
+	if err := json.Unmarshal(body, &list); err == nil {
+		if len(list) == count {
+			for i, resp := range list {
+				results[i] = resp.result()
+			}
+			return results
+		}
+		log.Printf("SMS provider returned %d results for %d messages", len(list), count)
+		return unknownResults(results, "", "provider returned a result count that does not match the batch")
+	}
 
-	// Parse response
 	var providerResp SMSProviderResponse
 	if err := json.Unmarshal(body, &providerResp); err != nil {
-		// If response is not in expected format, return raw response
+		// Not in the expected format, so nothing is known about the batch
 		log.Printf("Unexpected response format: %s", string(body))
-		return []SMSProviderResponse{{
-			Status:  "Failed",
-			Message: string(body),
-		}}, nil
+		return unknownResults(results, "", string(body))
 	}
 
-	// Return array of responses (provider may return single or multiple)
-	responses := []SMSProviderResponse{providerResp}
-
-	// Log the response
 	log.Printf("SMS Provider Response: Status=%s, Message=%s", providerResp.Status, providerResp.Message)
 
-	return responses, nil
+	perMessage := providerResp.Messages
+	if len(perMessage) == 0 {
+		perMessage = providerResp.Data
+	}
+	switch {
+	case len(perMessage) == count:
+		for i, resp := range perMessage {
+			results[i] = resp.result()
+		}
+	case count == 1:
+		results[0] = providerResp.result()
+	case providerResp.failed():
+		for i := range results {
+			results[i] = providerResp.result()
+			results[i].MessageID = ""
+			results[i].Cost = nil
+		}
+	default:
+		unknownResults(results, providerResp.Status, providerResp.Message)
+	}
+	return results
 }
 
+// unknownResults marks every result as unknown with the provider's batch status and message
+func unknownResults(results []SMSProviderResult, status, message string) []SMSProviderResult {
+	for i := range results {
+		results[i] = SMSProviderResult{
+			Result:  ProviderResultUnknown,
+			Status:  status,
+			Message: message,
+		}
+	}
+	return results
+}
@@ -0,0 +1,140 @@
+package service
+
+import (
+	"testing"
+)
+
+func TestParseProviderResponse(t *testing.T) {
+	type want struct {
+		result    string
+		status    string
+		messageID string
+		cost      float64 // 0 for no cost
+	}
+
+	tests := []struct {
+		name  string
+		body  string
+		count int
+		want  []want
+	}{
+		{
+			name:  "single message sent",
+			body:  `{"Status":"Success","Message":"Message Sent","MsgFollowUpUniqueCode":"abc"}`,
+			count: 1,
+			want:  []want{{result: ProviderResultSent, status: "Success", messageID: "abc"}},
+		},
+		{
+			name:  "single message failed",
+			body:  `{"Status":"Failed","Message":"Insufficient credit"}`,
+			count: 1,
+			want:  []want{{result: ProviderResultFailed, status: "Failed"}},
+		},
+		{
+			name:  "single message with unknown status",
+			body:  `{"Status":"Pending","Message":"Queued for delivery"}`,
+			count: 1,
+			want:  []want{{result: ProviderResultUnknown, status: "Pending"}},
+		},
+		{
+			name:  "single message without status",
+			body:  `{}`,
+			count: 1,
+			want:  []want{{result: ProviderResultUnknown}},
+		},
+		{
+			name:  "status is case-insensitive",
+			body:  `{"Status":"ok","MessageID":"m-1","Cost":"35.5"}`,
+			count: 1,
+			want:  []want{{result: ProviderResultSent, status: "ok", messageID: "m-1", cost: 35.5}},
+		},
+		{
+			name: "per-message results",
+			body: `{"Status":"Success","Messages":[` +
+				`{"Status":"Success","MsgId":"a","Cost":20},` +
+				`{"Status":"Failed","Message":"Bad number"},` +
+				`{"Status":"Submitted","MsgId":"c"}]}`,
+			count: 3,
+			want: []want{
+				{result: ProviderResultSent, status: "Success", messageID: "a", cost: 20},
+				{result: ProviderResultFailed, status: "Failed"},
+				{result: ProviderResultUnknown, status: "Submitted", messageID: "c"},
+			},
+		},
+		{
+			name:  "per-message results under Data",
+			body:  `{"Status":"Success","Data":[{"Status":"Sent"},{"Status":"Rejected"}]}`,
+			count: 2,
+			want:  []want{{result: ProviderResultSent, status: "Sent"}, {result: ProviderResultFailed, status: "Rejected"}},
+		},
+		{
+			name:  "plain array of results",
+			body:  `[{"Status":"Success","MessageID":"x"},{"Status":"Error"}]`,
+			count: 2,
+			want:  []want{{result: ProviderResultSent, status: "Success", messageID: "x"}, {result: ProviderResultFailed, status: "Error"}},
+		},
+		{
+			name:  "plain array with the wrong number of results",
+			body:  `[{"Status":"Success"}]`,
+			count: 2,
+			want:  []want{{result: ProviderResultUnknown}, {result: ProviderResultUnknown}},
+		},
+		{
+			name:  "batch accepted without per-message results",
+			body:  `{"Status":"Success","Message":"Messages Sent"}`,
+			count: 2,
+			want:  []want{{result: ProviderResultUnknown, status: "Success"}, {result: ProviderResultUnknown, status: "Success"}},
+		},
+		{
+			name:  "per-message results that do not match the batch",
+			body:  `{"Status":"Success","Messages":[{"Status":"Success"}]}`,
+			count: 2,
+			want:  []want{{result: ProviderResultUnknown, status: "Success"}, {result: ProviderResultUnknown, status: "Success"}},
+		},
+		{
+			name:  "batch rejected as a whole",
+			body:  `{"Status":"Failed","Message":"Insufficient credit","MsgId":"batch","Cost":5}`,
+			count: 2,
+			want:  []want{{result: ProviderResultFailed, status: "Failed"}, {result: ProviderResultFailed, status: "Failed"}},
+		},
+		{
+			name:  "batch with unknown status",
+			body:  `{"Status":"Processing"}`,
+			count: 2,
+			want:  []want{{result: ProviderResultUnknown, status: "Processing"}, {result: ProviderResultUnknown, status: "Processing"}},
+		},
+		{
+			name:  "not json",
+			body:  `<html>Bad Gateway</html>`,
+			count: 2,
+			want:  []want{{result: ProviderResultUnknown}, {result: ProviderResultUnknown}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			results := parseProviderResponse([]byte(tt.body), tt.count)
+			if len(results) != len(tt.want) {
+				t.Fatalf("got %d results, want %d", len(results), len(tt.want))
+			}
+			for i, w := range tt.want {
+				got := results[i]
+				if got.Result != w.result {
+					t.Errorf("results[%d].Result = %q, want %q", i, got.Result, w.result)
+				}
+				if got.Status != w.status {
+					t.Errorf("results[%d].Status = %q, want %q", i, got.Status, w.status)
+				}
+				if got.MessageID != w.messageID {
+					t.Errorf("results[%d].MessageID = %q, want %q", i, got.MessageID, w.messageID)
+				}
+				switch {
+				case w.cost == 0 && got.Cost != nil:
+					t.Errorf("results[%d].Cost = %v, want none", i, *got.Cost)
+				case w.cost != 0 && (got.Cost == nil || *got.Cost != w.cost):
+					t.Errorf("results[%d].Cost = %v, want %v", i, got.Cost, w.cost)
+				}
+			}
+		})
+	}
+}
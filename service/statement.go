@@ -0,0 +1,130 @@
+package service
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/Ian-Balijawa/sms-gateway/config"
+	"github.com/Ian-Balijawa/sms-gateway/database"
+	"github.com/Ian-Balijawa/sms-gateway/models"
+	"github.com/Ian-Balijawa/sms-gateway/utils"
+
+	"github.com/google/uuid"
+)
+
+// BillableStatuses are the statuses of messages handed to the provider
+var BillableStatuses = []string{models.SMSStatusSent, models.SMSStatusUnknown}
+
+// StatementPeriod parses a "YYYY-MM" period into the start of the month and of the next month, in UTC
+func StatementPeriod(period string) (time.Time, time.Time, error) {
+	start, err := time.ParseInLocation("2006-01", period, time.UTC)
+	if err != nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("period must be a month in YYYY-MM format")
+	}
+	return start, start.AddDate(0, 1, 0), nil
+}
+
+// GetStatement returns the client's finalized statement for the period, or a draft computed
+// from the message history if it has not been finalized
+func GetStatement(clientID uuid.UUID, period string) (*models.Statement, error) {
+	var statement models.Statement
+	err := database.DB.Where("client_id = ? AND period = ?", clientID, period).Limit(1).Find(&statement).Error
+	if err != nil {
+		return nil, err
+	}
+	if statement.ID != uuid.Nil {
+		return &statement, nil
+	}
+	return BuildStatement(clientID, period)
+}
+
+// FinalizeStatement computes the client's statement for an ended period and stores it.
+// If the period is already finalized the stored statement is returned and created is false.
+func FinalizeStatement(clientID uuid.UUID, period string) (*models.Statement, bool, error) {
+	statement, err := GetStatement(clientID, period)
+	if err != nil || statement.Status == models.StatementFinalized {
+		return statement, false, err
+	}
+
+	now := time.Now().UTC()
+	statement.Status = models.StatementFinalized
+	statement.FinalizedAt = &now
+	if err := database.DB.Create(statement).Error; err != nil {
+		return nil, false, err
+	}
+	return statement, true, nil
+}
+
+// BuildStatement computes a draft statement for the client's billable messages in the period,
+// totalled by destination country and sender ID
+func BuildStatement(clientID uuid.UUID, period string) (*models.Statement, error) {
+	start, end, err := StatementPeriod(period)
+	if err != nil {
+		return nil, err
+	}
+
+	// Calling codes have at most three digits, so the first four characters of an
+	// E.164 number are enough to find its country
+	var rows []struct {
+		SenderID   string
+		DialPrefix string
+		Messages   int64
+		Segments   int64
+		Cost       int64
+	}
+	if err := database.DB.Model(&models.SMSLog{}).
+		Select("sender_id, substr(recipient, 1, 4) AS dial_prefix, COUNT(*) AS messages, "+
+			"COALESCE(SUM(segments), 0) AS segments, COALESCE(SUM(price), 0) AS cost").
+		Where("client_id = ? AND status IN ? AND created_at >= ? AND created_at < ?", clientID, BillableStatuses, start, end).
+		Group("sender_id, dial_prefix").
+		Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	statement := &models.Statement{
+		CreatedAt:   time.Now().UTC(),
+		ClientID:    clientID,
+		Period:      period,
+		PeriodStart: start,
+		PeriodEnd:   end,
+		Status:      models.StatementDraft,
+		Currency:    config.AppConfig.WalletCurrency,
+		Lines:       models.StatementLines{},
+	}
+
+	type lineKey struct{ destination, senderID string }
+	lines := make(map[lineKey]*models.StatementLine)
+	for _, row := range rows {
+		destination := "unknown"
+		if country, ok := utils.CountryForPhone(row.DialPrefix); ok {
+			destination = country.Code
+		}
+
+		key := lineKey{destination, row.SenderID}
+		line, ok := lines[key]
+		if !ok {
+			line = &models.StatementLine{Destination: destination, SenderID: row.SenderID}
+			lines[key] = line
+		}
+		line.Messages += row.Messages
+		line.Segments += row.Segments
+		line.Cost += row.Cost
+
+		statement.Messages += row.Messages
+		statement.Segments += row.Segments
+		statement.Cost += row.Cost
+	}
+
+	for _, line := range lines {
+		statement.Lines = append(statement.Lines, *line)
+	}
+	sort.Slice(statement.Lines, func(i, j int) bool {
+		a, b := statement.Lines[i], statement.Lines[j]
+		if a.Destination != b.Destination {
+			return a.Destination < b.Destination
+		}
+		return a.SenderID < b.SenderID
+	})
+	return statement, nil
+}
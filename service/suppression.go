@@ -0,0 +1,86 @@
+package service
+
+import (
+	"github.com/Ian-Balijawa/sms-gateway/database"
+	"github.com/Ian-Balijawa/sms-gateway/models"
+	"github.com/Ian-Balijawa/sms-gateway/utils"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// suppressionLookupBatch bounds the size of the IN clause when checking large batches
+const suppressionLookupBatch = 500
+
+// AddSuppression adds a phone number to a client's suppression list, or to the global list
+// when clientID is nil. If the number is already suppressed in that scope the existing entry
+// is returned and created is false.
+func AddSuppression(clientID *uuid.UUID, phone, reason, source string) (*models.Suppression, bool, error) {
+	phone = utils.FormatPhone(phone)
+
+	var existing models.Suppression
+	if err := suppressionScope(clientID).Where("phone = ?", phone).First(&existing).Error; err == nil {
+		return &existing, false, nil
+	}
+
+	suppression := models.Suppression{
+		ClientID: clientID,
+		Phone:    phone,
+		Reason:   reason,
+		Source:   source,
+	}
+	if err := database.DB.Create(&suppression).Error; err != nil {
+		return nil, false, err
+	}
+	return &suppression, true, nil
+}
+
+// RemoveSuppression removes a phone number from a client's suppression list, or from the
+// global list when clientID is nil. It reports whether an entry was removed.
+func RemoveSuppression(clientID *uuid.UUID, phone string) (bool, error) {
+	result := suppressionScope(clientID).
+		Where("phone = ?", utils.FormatPhone(phone)).
+		Delete(&models.Suppression{})
+	return result.RowsAffected > 0, result.Error
+}
+
+// SuppressedNumbers returns the subset of the given normalized phone numbers that the client
+// must not message, taking both the client's and the global suppression lists into account
+func SuppressedNumbers(clientID uuid.UUID, phones []string) (map[string]bool, error) {
+	suppressed := make(map[string]bool)
+	for start := 0; start < len(phones); start += suppressionLookupBatch {
+		end := start + suppressionLookupBatch
+		if end > len(phones) {
+			end = len(phones)
+		}
+
+		var matches []string
+		if err := database.DB.Model(&models.Suppression{}).
+			Where("phone IN ? AND (client_id = ? OR client_id IS NULL)", phones[start:end], clientID).
+			Pluck("phone", &matches).Error; err != nil {
+			return nil, err
+		}
+		for _, phone := range matches {
+			suppressed[phone] = true
+		}
+	}
+	return suppressed, nil
+}
+
+// IsSuppressed reports whether the client must not message the phone number
+func IsSuppressed(clientID uuid.UUID, phone string) (bool, error) {
+	phone = utils.FormatPhone(phone)
+	suppressed, err := SuppressedNumbers(clientID, []string{phone})
+	if err != nil {
+		return false, err
+	}
+	return suppressed[phone], nil
+}
+
+// suppressionScope restricts a query to one client's list, or to the global list when clientID is nil
+func suppressionScope(clientID *uuid.UUID) *gorm.DB {
+	if clientID == nil {
+		return database.DB.Where("client_id IS NULL")
+	}
+	return database.DB.Where("client_id = ?", *clientID)
+}
@@ -0,0 +1,69 @@
+package service
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/Ian-Balijawa/sms-gateway/config"
+	"github.com/Ian-Balijawa/sms-gateway/models"
+	"github.com/Ian-Balijawa/sms-gateway/utils"
+)
+
+// DefaultOTPTemplate is used for clients without their own verification message template
+const DefaultOTPTemplate = "Your verification code is {{code}}. It expires in {{minutes}} minutes."
+
+// GenerateOTP returns a random numeric code of the configured length
+func GenerateOTP() (string, error) {
+	length := min(max(config.AppConfig.OTPLength, 4), 10)
+
+	var code strings.Builder
+	for i := 0; i < length; i++ {
+		digit, err := rand.Int(rand.Reader, big.NewInt(10))
+		if err != nil {
+			return "", fmt.Errorf("failed to generate code: %w", err)
+		}
+		code.WriteByte(byte('0' + digit.Int64()))
+	}
+	return code.String(), nil
+}
+
+// HashOTP returns the HMAC of a code, bound to its verification so that a hash cannot be
+// reused for another verification
+func HashOTP(v *models.Verification, code string) string {
+	mac := hmac.New(sha256.New, []byte(config.AppConfig.OTPSecret))
+	mac.Write([]byte(v.ID.String() + ":" + code))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// CheckOTP reports whether the code matches the verification, comparing in constant time
+func CheckOTP(v *models.Verification, code string) bool {
+	expected, err := hex.DecodeString(v.CodeHash)
+	if err != nil {
+		return false
+	}
+	actual, _ := hex.DecodeString(HashOTP(v, strings.TrimSpace(code)))
+	return hmac.Equal(expected, actual)
+}
+
+// OTPMessage renders the client's verification template, or the default one, with the code
+func OTPMessage(client *models.APIClient, code string) string {
+	template := client.OTPTemplate
+	if template == "" {
+		template = DefaultOTPTemplate
+	}
+	return utils.RenderTemplate(template, map[string]string{
+		"code":    code,
+		"minutes": fmt.Sprint(max(config.AppConfig.OTPTTLSeconds/60, 1)),
+	})
+}
+
+// OTPTemplateHasCode reports whether a verification template includes the {{code}} placeholder
+func OTPTemplateHasCode(template string) bool {
+	const marker = "\x00code\x00"
+	return strings.Contains(utils.RenderTemplate(template, map[string]string{"code": marker}), marker)
+}
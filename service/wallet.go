@@ -0,0 +1,211 @@
+package service
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+
+	"github.com/Ian-Balijawa/sms-gateway/config"
+	"github.com/Ian-Balijawa/sms-gateway/database"
+	"github.com/Ian-Balijawa/sms-gateway/models"
+	"github.com/Ian-Balijawa/sms-gateway/utils"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// PriceList holds the price rules ordered from the longest prefix to the shortest
+type PriceList []models.PriceRule
+
+// LoadPriceList loads the price rules for lookups
+func LoadPriceList() (PriceList, error) {
+	var rules []models.PriceRule
+	if err := database.DB.Find(&rules).Error; err != nil {
+		return nil, err
+	}
+	sort.Slice(rules, func(i, j int) bool {
+		return len(rules[i].Prefix) > len(rules[j].Prefix)
+	})
+	return PriceList(rules), nil
+}
+
+// PricePerSegment returns the price of one segment to a normalized (E.164) number
+func (p PriceList) PricePerSegment(recipient string) (int64, bool) {
+	for _, rule := range p {
+		if strings.HasPrefix(recipient, rule.Prefix) {
+			return rule.PricePerSegment, true
+		}
+	}
+	return 0, false
+}
+
+// QuoteMessages returns what sending the messages would cost the client. Invalid and
+// unpriced recipients are not counted, as they are rejected without charge.
+func QuoteMessages(client *models.APIClient, messages []models.SMSRequest) (int64, error) {
+	if !config.AppConfig.WalletEnabled {
+		return 0, nil
+	}
+
+	prices, err := LoadPriceList()
+	if err != nil {
+		return 0, err
+	}
+
+	var total int64
+	for _, msg := range messages {
+		phone, err := utils.ParsePhone(msg.Number, client.DefaultRegion)
+		if err != nil {
+			continue
+		}
+		if price, ok := prices.PricePerSegment(phone.E164); ok {
+			text := msg.Message
+			if msg.ShortenLinks {
+				text = ShortenedText(text)
+			}
+			total += price * int64(utils.MessageSegments(text))
+		}
+	}
+	return total, nil
+}
+
+// GetWallet returns the client's wallet, creating an empty one if it has none
+func GetWallet(clientID uuid.UUID) (*models.Wallet, error) {
+	var wallet models.Wallet
+	if err := database.DB.Where(models.Wallet{ClientID: clientID}).FirstOrCreate(&wallet).Error; err != nil {
+		return nil, err
+	}
+	return &wallet, nil
+}
+
+// CreditWallet adds a top-up or adjustment to the client's wallet and records it in the ledger.
+// Negative adjustments may not take the balance below zero; ok is false if they would.
+func CreditWallet(clientID uuid.UUID, txType string, amount int64, reference, note string) (*models.WalletTransaction, bool, error) {
+	entry := &models.WalletTransaction{
+		ClientID:  clientID,
+		Type:      txType,
+		Amount:    amount,
+		Reference: reference,
+		Note:      note,
+	}
+	ok, err := applyWalletEntry(entry)
+	return entry, ok, err
+}
+
+// applyWalletEntry changes the wallet balance by the entry's amount and records the entry,
+// in one transaction. Debits are only applied if the balance covers them.
+func applyWalletEntry(entry *models.WalletTransaction) (bool, error) {
+	applied := false
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		var wallet models.Wallet
+		if err := tx.Where(models.Wallet{ClientID: entry.ClientID}).FirstOrCreate(&wallet).Error; err != nil {
+			return err
+		}
+
+		update := tx.Model(&models.Wallet{}).Where("client_id = ?", entry.ClientID)
+		if entry.Amount < 0 {
+			update = update.Where("balance >= ?", -entry.Amount)
+		}
+		result := update.Update("balance", gorm.Expr("balance + ?", entry.Amount))
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return nil
+		}
+
+		if err := tx.Where("client_id = ?", entry.ClientID).First(&wallet).Error; err != nil {
+			return err
+		}
+		entry.BalanceAfter = wallet.Balance
+		if err := tx.Create(entry).Error; err != nil {
+			return err
+		}
+		applied = true
+		return nil
+	})
+	return applied && err == nil, err
+}
+
+// charge prices the pending messages and debits their total from the client's wallet.
+// Messages to unpriced destinations are rejected. If the wallet cannot cover the total,
+// or cannot be charged, every priced message is failed. It returns the messages that can be sent.
+func (d *Dispatcher) charge(client *models.APIClient, logs []models.SMSLog, pending []int) []int {
+	if !config.AppConfig.WalletEnabled || len(pending) == 0 {
+		return pending
+	}
+
+	prices, err := LoadPriceList()
+	if err != nil {
+		failMessages(logs, pending, fmt.Sprintf("failed to load price list: %v", err))
+		return nil
+	}
+
+	var total int64
+	priced := pending[:0]
+	for _, i := range pending {
+		price, ok := prices.PricePerSegment(logs[i].Recipient)
+		if !ok {
+			logs[i].Status = models.SMSStatusRejected
+			logs[i].Error = "no price configured for destination"
+			continue
+		}
+		logs[i].Price = price * int64(logs[i].Segments)
+		total += logs[i].Price
+		priced = append(priced, i)
+	}
+	if total == 0 {
+		return priced
+	}
+
+	ok, err := applyWalletEntry(&models.WalletTransaction{
+		ClientID: client.ID,
+		Type:     models.WalletTxDebit,
+		Amount:   -total,
+		Messages: len(priced),
+	})
+	if err != nil || !ok {
+		reason := "insufficient wallet balance"
+		if err != nil {
+			reason = fmt.Sprintf("failed to charge wallet: %v", err)
+		}
+		failMessages(logs, priced, reason)
+		for _, i := range priced {
+			logs[i].Price = 0
+		}
+		return nil
+	}
+	return priced
+}
+
+// refundFailed credits the client's wallet for charged messages the provider did not send
+func (d *Dispatcher) refundFailed(client *models.APIClient, logs []models.SMSLog, sent []int) {
+	for _, i := range sent {
+		if logs[i].Status != models.SMSStatusFailed || logs[i].Price == 0 {
+			continue
+		}
+
+		logID := logs[i].ID
+		ok, err := applyWalletEntry(&models.WalletTransaction{
+			ClientID: client.ID,
+			Type:     models.WalletTxRefund,
+			Amount:   logs[i].Price,
+			Messages: 1,
+			SMSLogID: &logID,
+			Note:     logs[i].Error,
+		})
+		if err != nil || !ok {
+			log.Printf("Error refunding message %s: %v", logID, err)
+			continue
+		}
+		logs[i].Price = 0
+	}
+}
+
+// failMessages marks messages as failed before they reach the provider
+func failMessages(logs []models.SMSLog, indexes []int, reason string) {
+	for _, i := range indexes {
+		logs[i].Status = models.SMSStatusFailed
+		logs[i].Error = reason
+	}
+}
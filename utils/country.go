@@ -0,0 +1,79 @@
+package utils
+
+import (
+	"strings"
+)
+
+// Country describes a destination country identified by its E.164 calling code
+type Country struct {
+	Code        string // ISO 3166-1 alpha-2 code
+	Name        string
+	CallingCode string
+	Timezone    string // IANA time zone used for the whole country
+
+	// National numbering plan
+	TrunkPrefix string // Dialled before national numbers inside the country, e.g. "0"
+	MinLength   int    // Shortest national significant number, without the trunk prefix
+	MaxLength   int    // Longest national significant number, without the trunk prefix
+}
+
+// countries lists the destinations the gateway knows about. Countries spanning
+// several time zones use the zone of their capital.
+var countries = []Country{
+	// East Africa
+	{Code: "UG", Name: "Uganda", CallingCode: "256", Timezone: "Africa/Kampala", TrunkPrefix: "0", MinLength: 9, MaxLength: 9},
+	{Code: "KE", Name: "Kenya", CallingCode: "254", Timezone: "Africa/Nairobi", TrunkPrefix: "0", MinLength: 9, MaxLength: 9},
+	{Code: "TZ", Name: "Tanzania", CallingCode: "255", Timezone: "Africa/Dar_es_Salaam", TrunkPrefix: "0", MinLength: 9, MaxLength: 9},
+	{Code: "RW", Name: "Rwanda", CallingCode: "250", Timezone: "Africa/Kigali", TrunkPrefix: "0", MinLength: 9, MaxLength: 9},
+	{Code: "BI", Name: "Burundi", CallingCode: "257", Timezone: "Africa/Bujumbura", MinLength: 8, MaxLength: 8},
+	{Code: "SS", Name: "South Sudan", CallingCode: "211", Timezone: "Africa/Juba", TrunkPrefix: "0", MinLength: 9, MaxLength: 9},
+	{Code: "CD", Name: "DR Congo", CallingCode: "243", Timezone: "Africa/Kinshasa", TrunkPrefix: "0", MinLength: 9, MaxLength: 9},
+	{Code: "ET", Name: "Ethiopia", CallingCode: "251", Timezone: "Africa/Addis_Ababa", TrunkPrefix: "0", MinLength: 9, MaxLength: 9},
+	{Code: "SO", Name: "Somalia", CallingCode: "252", Timezone: "Africa/Mogadishu", TrunkPrefix: "0", MinLength: 7, MaxLength: 9},
+
+	// Rest of Africa
+	{Code: "NG", Name: "Nigeria", CallingCode: "234", Timezone: "Africa/Lagos", TrunkPrefix: "0", MinLength: 8, MaxLength: 10},
+	{Code: "GH", Name: "Ghana", CallingCode: "233", Timezone: "Africa/Accra", TrunkPrefix: "0", MinLength: 9, MaxLength: 9},
+	{Code: "ZA", Name: "South Africa", CallingCode: "27", Timezone: "Africa/Johannesburg", TrunkPrefix: "0", MinLength: 9, MaxLength: 9},
+	{Code: "ZM", Name: "Zambia", CallingCode: "260", Timezone: "Africa/Lusaka", TrunkPrefix: "0", MinLength: 9, MaxLength: 9},
+	{Code: "MW", Name: "Malawi", CallingCode: "265", Timezone: "Africa/Blantyre", TrunkPrefix: "0", MinLength: 7, MaxLength: 9},
+	{Code: "ZW", Name: "Zimbabwe", CallingCode: "263", Timezone: "Africa/Harare", TrunkPrefix: "0", MinLength: 7, MaxLength: 10},
+	{Code: "MZ", Name: "Mozambique", CallingCode: "258", Timezone: "Africa/Maputo", MinLength: 8, MaxLength: 9},
+	{Code: "EG", Name: "Egypt", CallingCode: "20", Timezone: "Africa/Cairo", TrunkPrefix: "0", MinLength: 8, MaxLength: 10},
+
+	// Rest of the world
+	{Code: "US", Name: "United States", CallingCode: "1", Timezone: "America/New_York", TrunkPrefix: "1", MinLength: 10, MaxLength: 10},
+	{Code: "GB", Name: "United Kingdom", CallingCode: "44", Timezone: "Europe/London", TrunkPrefix: "0", MinLength: 9, MaxLength: 10},
+	{Code: "DE", Name: "Germany", CallingCode: "49", Timezone: "Europe/Berlin", TrunkPrefix: "0", MinLength: 6, MaxLength: 13},
+	{Code: "FR", Name: "France", CallingCode: "33", Timezone: "Europe/Paris", TrunkPrefix: "0", MinLength: 9, MaxLength: 9},
+	{Code: "IN", Name: "India", CallingCode: "91", Timezone: "Asia/Kolkata", TrunkPrefix: "0", MinLength: 10, MaxLength: 10},
+	{Code: "CN", Name: "China", CallingCode: "86", Timezone: "Asia/Shanghai", TrunkPrefix: "0", MinLength: 7, MaxLength: 12},
+	{Code: "AE", Name: "United Arab Emirates", CallingCode: "971", Timezone: "Asia/Dubai", TrunkPrefix: "0", MinLength: 8, MaxLength: 9},
+	{Code: "SA", Name: "Saudi Arabia", CallingCode: "966", Timezone: "Asia/Riyadh", TrunkPrefix: "0", MinLength: 8, MaxLength: 9},
+}
+
+// CountryForPhone returns the country of a normalized (E.164) phone number
+// by matching the longest known calling code
+func CountryForPhone(phone string) (*Country, bool) {
+	digits := strings.TrimPrefix(phone, "+")
+
+	var match *Country
+	for i := range countries {
+		code := countries[i].CallingCode
+		if strings.HasPrefix(digits, code) && (match == nil || len(code) > len(match.CallingCode)) {
+			match = &countries[i]
+		}
+	}
+	return match, match != nil
+}
+
+// CountryByCode returns the country with the given ISO 3166-1 alpha-2 code (case-insensitive)
+func CountryByCode(code string) (*Country, bool) {
+	code = strings.ToUpper(strings.TrimSpace(code))
+	for i := range countries {
+		if countries[i].Code == code {
+			return &countries[i], true
+		}
+	}
+	return nil, false
+}
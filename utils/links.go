@@ -0,0 +1,59 @@
+package utils
+
+import (
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// linkPattern matches links in message text: anything starting with a scheme or "www.",
+// and bare domains followed by a path (e.g. "bit.ly/x")
+var linkPattern = regexp.MustCompile(`(?i)\b(?:https?://\S+|www\.\S+|[a-z0-9](?:[a-z0-9-]*[a-z0-9])?(?:\.[a-z0-9](?:[a-z0-9-]*[a-z0-9])?)*\.[a-z]{2,}/\S*)`)
+
+// FindLinks returns the start and end offsets of the links in a message.
+// Trailing punctuation that usually ends a sentence is not part of a link.
+func FindLinks(text string) [][2]int {
+	var links [][2]int
+	for _, match := range linkPattern.FindAllStringIndex(text, -1) {
+		start, end := match[0], match[1]
+		end = start + len(strings.TrimRight(text[start:end], ".,;:!?)]}'\""))
+		links = append(links, [2]int{start, end})
+	}
+	return links
+}
+
+// ReplaceLinks returns the text with each link replaced by what replace returns for it
+func ReplaceLinks(text string, replace func(link string) string) string {
+	links := FindLinks(text)
+	if len(links) == 0 {
+		return text
+	}
+
+	var b strings.Builder
+	last := 0
+	for _, link := range links {
+		b.WriteString(text[last:link[0]])
+		b.WriteString(replace(text[link[0]:link[1]]))
+		last = link[1]
+	}
+	b.WriteString(text[last:])
+	return b.String()
+}
+
+// LinkHost returns the lower-case host name of a link found in a message
+func LinkHost(link string) string {
+	if !strings.Contains(link, "://") {
+		link = "http://" + link
+	}
+	parsed, err := url.Parse(link)
+	if err != nil {
+		return ""
+	}
+	return strings.ToLower(parsed.Hostname())
+}
+
+// HostInDomain reports whether a host is the domain or one of its subdomains
+func HostInDomain(host, domain string) bool {
+	domain = strings.ToLower(strings.TrimPrefix(domain, "."))
+	return host == domain || strings.HasSuffix(host, "."+domain)
+}
@@ -0,0 +1,116 @@
+package utils
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Line types reported by LookupOperator
+const (
+	LineTypeMobile = "mobile"
+	LineTypeFixed  = "fixed"
+)
+
+// OperatorPrefix maps the leading digits of E.164 numbers (without "+") to a network
+type OperatorPrefix struct {
+	Prefix   string `json:"prefix"`
+	Operator string `json:"operator"`
+	LineType string `json:"line_type"`
+}
+
+// defaultOperatorPrefixes is the prefix table shipped with the gateway
+//
+//go:embed data/operators.json
+var defaultOperatorPrefixes []byte
+
+var (
+	operatorMu       sync.RWMutex
+	operatorPrefixes []OperatorPrefix // Sorted longest prefix first
+)
+
+func init() {
+	if err := setOperatorPrefixes(defaultOperatorPrefixes); err != nil {
+		panic(fmt.Sprintf("invalid built-in operator prefix table: %v", err))
+	}
+}
+
+// LoadOperatorPrefixes replaces the operator prefix table with the contents of a JSON file,
+// or with the built-in table when path is empty. The current table is kept on error.
+func LoadOperatorPrefixes(path string) error {
+	if path == "" {
+		return setOperatorPrefixes(defaultOperatorPrefixes)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read operator prefix file: %w", err)
+	}
+	return setOperatorPrefixes(data)
+}
+
+// OperatorPrefixes returns a copy of the current operator prefix table
+func OperatorPrefixes() []OperatorPrefix {
+	operatorMu.RLock()
+	defer operatorMu.RUnlock()
+
+	prefixes := make([]OperatorPrefix, len(operatorPrefixes))
+	copy(prefixes, operatorPrefixes)
+	return prefixes
+}
+
+// LookupOperator returns the operator and line type of a normalized (E.164) phone number
+// by matching the longest known prefix
+func LookupOperator(phone string) (string, string, bool) {
+	digits := strings.TrimPrefix(phone, "+")
+
+	operatorMu.RLock()
+	defer operatorMu.RUnlock()
+
+	for _, entry := range operatorPrefixes {
+		if strings.HasPrefix(digits, entry.Prefix) {
+			return entry.Operator, entry.LineType, true
+		}
+	}
+	return "", "", false
+}
+
+// setOperatorPrefixes validates a JSON prefix table and makes it the current one
+func setOperatorPrefixes(data []byte) error {
+	var prefixes []OperatorPrefix
+	if err := json.Unmarshal(data, &prefixes); err != nil {
+		return fmt.Errorf("invalid operator prefix table: %w", err)
+	}
+
+	seen := make(map[string]bool, len(prefixes))
+	for i, entry := range prefixes {
+		entry.Prefix = strings.TrimPrefix(strings.TrimSpace(entry.Prefix), "+")
+		if entry.Prefix == "" || strings.Trim(entry.Prefix, "0123456789") != "" {
+			return fmt.Errorf("entry %d: prefix %q must contain only digits", i, entry.Prefix)
+		}
+		if entry.Operator == "" {
+			return fmt.Errorf("entry %d: operator is required", i)
+		}
+		if entry.LineType != LineTypeMobile && entry.LineType != LineTypeFixed {
+			return fmt.Errorf("entry %d: line_type must be %q or %q", i, LineTypeMobile, LineTypeFixed)
+		}
+		if seen[entry.Prefix] {
+			return fmt.Errorf("entry %d: duplicate prefix %s", i, entry.Prefix)
+		}
+		seen[entry.Prefix] = true
+		prefixes[i] = entry
+	}
+
+	sort.SliceStable(prefixes, func(i, j int) bool {
+		return len(prefixes[i].Prefix) > len(prefixes[j].Prefix)
+	})
+
+	operatorMu.Lock()
+	operatorPrefixes = prefixes
+	operatorMu.Unlock()
+	return nil
+}
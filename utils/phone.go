@@ -1,38 +1,187 @@
 package utils
 
 import (
-	"regexp"
+	"fmt"
 	"strings"
+
+	"github.com/Ian-Balijawa/sms-gateway/config"
 )
 
-// FormatPhone formats a phone number to a standard format
-// Removes spaces, dashes, and other non-digit characters except +
-// Ensures the number starts with the country code
-func FormatPhone(phone string) string {
-	// Remove all non-digit characters except +
-	re := regexp.MustCompile(`[^\d+]`)
-	cleaned := re.ReplaceAllString(phone, "")
-
-	// If it doesn't start with +, assume it's a local number
-	// You may want to customize this based on your country's format
-	if !strings.HasPrefix(cleaned, "+") {
-		// Remove leading zeros
-		cleaned = strings.TrimLeft(cleaned, "0")
-		// Add country code if needed (default: +256 for Uganda, adjust as needed)
-		if !strings.HasPrefix(cleaned, "256") {
-			cleaned = "256" + cleaned
+// fallbackRegion is used when neither the client nor the configuration sets a default region
+const fallbackRegion = "UG"
+
+// E.164 allows at most 15 digits including the calling code. Numbers with a calling code
+// the gateway has no rules for are accepted if they fall within these bounds.
+const (
+	minE164Digits = 8
+	maxE164Digits = 15
+)
+
+// Reasons reported in PhoneError
+const (
+	PhoneErrorEmpty             = "empty"
+	PhoneErrorInvalidCharacters = "invalid_characters"
+	PhoneErrorUnknownRegion     = "unknown_region"
+	PhoneErrorTooShort          = "too_short"
+	PhoneErrorTooLong           = "too_long"
+)
+
+// PhoneError describes why a phone number could not be parsed
+type PhoneError struct {
+	Phone   string // The number as given
+	Reason  string // One of the PhoneError* reasons
+	Message string
+}
+
+func (e *PhoneError) Error() string {
+	return fmt.Sprintf("phone number %q is invalid: %s", e.Phone, e.Message)
+}
+
+// PhoneNumber is a parsed phone number
+type PhoneNumber struct {
+	E164           string   // e.g. +256701234567
+	NationalNumber string   // National significant number, without the trunk prefix
+	Country        *Country // Nil if the calling code is not in the country table
+}
+
+// DefaultRegion returns the region used for national-format numbers when the client has none set
+func DefaultRegion() string {
+	if config.AppConfig != nil && config.AppConfig.DefaultPhoneRegion != "" {
+		return config.AppConfig.DefaultPhoneRegion
+	}
+	return fallbackRegion
+}
+
+// ParsePhone parses a phone number into E.164 form.
+// Numbers starting with "+" or "00" are international. Other numbers are read in the
+// given region (ISO 3166-1 alpha-2, empty for the default region): the region's trunk
+// prefix is dropped and its calling code added, and numbers already starting with the
+// calling code (e.g. 256701234567) are accepted as well.
+// Spaces, dashes, dots and parentheses are ignored.
+func ParsePhone(phone, region string) (*PhoneNumber, error) {
+	cleaned := strings.NewReplacer(" ", "", "-", "", ".", "", "(", "", ")", "", "\t", "").Replace(strings.TrimSpace(phone))
+	if cleaned == "" {
+		return nil, &PhoneError{Phone: phone, Reason: PhoneErrorEmpty, Message: "number is empty"}
+	}
+
+	international := false
+	switch {
+	case strings.HasPrefix(cleaned, "+"):
+		international = true
+		cleaned = cleaned[1:]
+	case strings.HasPrefix(cleaned, "00"):
+		international = true
+		cleaned = cleaned[2:]
+	}
+	for _, r := range cleaned {
+		if r < '0' || r > '9' {
+			return nil, &PhoneError{Phone: phone, Reason: PhoneErrorInvalidCharacters, Message: "number may only contain digits and a leading +"}
+		}
+	}
+
+	if international {
+		return parseInternational(phone, cleaned)
+	}
+
+	if region == "" {
+		region = DefaultRegion()
+	}
+	country, ok := CountryByCode(region)
+	if !ok {
+		return nil, &PhoneError{Phone: phone, Reason: PhoneErrorUnknownRegion, Message: fmt.Sprintf("unknown region %q", region)}
+	}
+
+	// Already includes the calling code, just without the "+"
+	if strings.HasPrefix(cleaned, country.CallingCode) {
+		if national := cleaned[len(country.CallingCode):]; validNationalLength(country, national) {
+			return newPhoneNumber(country, national), nil
 		}
-		cleaned = "+" + cleaned
 	}
 
-	return cleaned
+	national := cleaned
+	if country.TrunkPrefix != "" {
+		national = strings.TrimPrefix(national, country.TrunkPrefix)
+	}
+	if err := checkNationalLength(phone, country, national); err != nil {
+		return nil, err
+	}
+	return newPhoneNumber(country, national), nil
 }
 
-// ValidatePhone performs basic phone number validation
-func ValidatePhone(phone string) bool {
-	formatted := FormatPhone(phone)
-	// Basic validation: should be at least 10 digits (including country code)
-	digits := regexp.MustCompile(`\d`).FindAllString(formatted, -1)
-	return len(digits) >= 10 && len(digits) <= 15
+// parseInternational parses the digits of a number given with its calling code
+func parseInternational(phone, digits string) (*PhoneNumber, error) {
+	country, ok := CountryForPhone(digits)
+	if !ok {
+		// No rules for this calling code, apply the E.164 bounds only
+		if len(digits) < minE164Digits {
+			return nil, &PhoneError{Phone: phone, Reason: PhoneErrorTooShort, Message: fmt.Sprintf("expected at least %d digits", minE164Digits)}
+		}
+		if len(digits) > maxE164Digits {
+			return nil, &PhoneError{Phone: phone, Reason: PhoneErrorTooLong, Message: fmt.Sprintf("expected at most %d digits", maxE164Digits)}
+		}
+		return &PhoneNumber{E164: "+" + digits}, nil
+	}
+
+	national := digits[len(country.CallingCode):]
+	if err := checkNationalLength(phone, country, national); err != nil {
+		return nil, err
+	}
+	return newPhoneNumber(country, national), nil
 }
 
+// checkNationalLength checks a national significant number against the country's length rules
+func checkNationalLength(phone string, country *Country, national string) error {
+	if len(national) < country.MinLength {
+		return &PhoneError{Phone: phone, Reason: PhoneErrorTooShort, Message: fmt.Sprintf("too short for %s, %s", country.Name, expectedLength(country))}
+	}
+	if len(national) > country.MaxLength {
+		return &PhoneError{Phone: phone, Reason: PhoneErrorTooLong, Message: fmt.Sprintf("too long for %s, %s", country.Name, expectedLength(country))}
+	}
+	return nil
+}
+
+func validNationalLength(country *Country, national string) bool {
+	return len(national) >= country.MinLength && len(national) <= country.MaxLength
+}
+
+func expectedLength(country *Country) string {
+	if country.MinLength == country.MaxLength {
+		return fmt.Sprintf("expected %d digits after the country code", country.MinLength)
+	}
+	return fmt.Sprintf("expected %d to %d digits after the country code", country.MinLength, country.MaxLength)
+}
+
+func newPhoneNumber(country *Country, national string) *PhoneNumber {
+	return &PhoneNumber{
+		E164:           "+" + country.CallingCode + national,
+		NationalNumber: national,
+		Country:        country,
+	}
+}
+
+// FormatPhone formats a phone number to E.164 using the default region.
+// Numbers that cannot be parsed are returned with formatting characters removed.
+func FormatPhone(phone string) string {
+	return FormatPhoneForRegion(phone, "")
+}
+
+// FormatPhoneForRegion formats a phone number to E.164, reading national numbers in the given region.
+// Numbers that cannot be parsed are returned with formatting characters removed.
+func FormatPhoneForRegion(phone, region string) string {
+	parsed, err := ParsePhone(phone, region)
+	if err != nil {
+		return strings.Map(func(r rune) rune {
+			if (r >= '0' && r <= '9') || r == '+' {
+				return r
+			}
+			return -1
+		}, phone)
+	}
+	return parsed.E164
+}
+
+// ValidatePhone reports whether the phone number can be parsed in the default region
+func ValidatePhone(phone string) bool {
+	_, err := ParsePhone(phone, "")
+	return err == nil
+}
@@ -0,0 +1,79 @@
+package utils
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestParsePhone(t *testing.T) {
+	tests := []struct {
+		name     string
+		phone    string
+		region   string
+		e164     string
+		national string
+		country  string // Empty for numbers outside the country table
+		reason   string // Expected PhoneError reason, empty if the number is valid
+	}{
+		{name: "ugandan trunk prefix", phone: "0701234567", region: "UG", e164: "+256701234567", national: "701234567", country: "UG"},
+		{name: "default region", phone: "0701234567", e164: "+256701234567", national: "701234567", country: "UG"},
+		{name: "calling code without plus", phone: "256701234567", region: "UG", e164: "+256701234567", national: "701234567", country: "UG"},
+		{name: "national number without trunk prefix", phone: "701234567", region: "UG", e164: "+256701234567", national: "701234567", country: "UG"},
+		{name: "formatting characters", phone: " (070) 123-45.67 ", region: "UG", e164: "+256701234567", national: "701234567", country: "UG"},
+		{name: "international with plus", phone: "+254712345678", region: "UG", e164: "+254712345678", national: "712345678", country: "KE"},
+		{name: "international with 00", phone: "00254712345678", region: "UG", e164: "+254712345678", national: "712345678", country: "KE"},
+		{name: "kenyan trunk prefix", phone: "0712345678", region: "KE", e164: "+254712345678", national: "712345678", country: "KE"},
+		{name: "lower case region", phone: "0712345678", region: "ke", e164: "+254712345678", national: "712345678", country: "KE"},
+		{name: "burundi has no trunk prefix", phone: "79123456", region: "BI", e164: "+25779123456", national: "79123456", country: "BI"},
+		{name: "us trunk prefix", phone: "12025550123", region: "US", e164: "+12025550123", national: "2025550123", country: "US"},
+		{name: "us without trunk prefix", phone: "2025550123", region: "US", e164: "+12025550123", national: "2025550123", country: "US"},
+		{name: "nigerian shortest", phone: "012345678", region: "NG", e164: "+23412345678", national: "12345678", country: "NG"},
+		{name: "nigerian longest", phone: "08031234567", region: "NG", e164: "+2348031234567", national: "8031234567", country: "NG"},
+		{name: "somali variable length", phone: "+2521234567", e164: "+2521234567", national: "1234567", country: "SO"},
+		{name: "unknown calling code", phone: "+8801712345678", e164: "+8801712345678"},
+
+		{name: "empty", phone: "  ", reason: PhoneErrorEmpty},
+		{name: "letters", phone: "0701abc567", region: "UG", reason: PhoneErrorInvalidCharacters},
+		{name: "plus inside number", phone: "0701+34567", region: "UG", reason: PhoneErrorInvalidCharacters},
+		{name: "unknown region", phone: "0701234567", region: "XX", reason: PhoneErrorUnknownRegion},
+		{name: "ugandan too short", phone: "070123456", region: "UG", reason: PhoneErrorTooShort},
+		{name: "ugandan too long", phone: "07012345678", region: "UG", reason: PhoneErrorTooLong},
+		{name: "kenyan too long", phone: "+2547123456789", reason: PhoneErrorTooLong},
+		{name: "nigerian too short", phone: "01234567", region: "NG", reason: PhoneErrorTooShort},
+		{name: "unknown calling code too short", phone: "+8801234", reason: PhoneErrorTooShort},
+		{name: "unknown calling code too long", phone: "+8801234567890123", reason: PhoneErrorTooLong},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			parsed, err := ParsePhone(tt.phone, tt.region)
+			if tt.reason != "" {
+				var phoneErr *PhoneError
+				if !errors.As(err, &phoneErr) {
+					t.Fatalf("ParsePhone(%q, %q) error = %v, want a PhoneError", tt.phone, tt.region, err)
+				}
+				if phoneErr.Reason != tt.reason {
+					t.Errorf("ParsePhone(%q, %q) reason = %q, want %q", tt.phone, tt.region, phoneErr.Reason, tt.reason)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParsePhone(%q, %q) returned error: %v", tt.phone, tt.region, err)
+			}
+
+			if parsed.E164 != tt.e164 {
+				t.Errorf("E164 = %q, want %q", parsed.E164, tt.e164)
+			}
+			if parsed.NationalNumber != tt.national {
+				t.Errorf("NationalNumber = %q, want %q", parsed.NationalNumber, tt.national)
+			}
+			country := ""
+			if parsed.Country != nil {
+				country = parsed.Country.Code
+			}
+			if country != tt.country {
+				t.Errorf("Country = %q, want %q", country, tt.country)
+			}
+		})
+	}
+}
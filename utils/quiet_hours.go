@@ -0,0 +1,50 @@
+package utils
+
+import (
+	"fmt"
+	"time"
+)
+
+// ParseClock parses a "HH:MM" time of day into minutes after midnight
+func ParseClock(value string) (int, error) {
+	t, err := time.Parse("15:04", value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid time %q, expected HH:MM", value)
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+// QuietHoursEnd reports whether now falls within the quiet hours start-end ("HH:MM")
+// in the given location and, if so, returns the time at which quiet hours end.
+// Windows may wrap past midnight (e.g. 21:00-08:00). Equal start and end disable the window.
+func QuietHoursEnd(now time.Time, loc *time.Location, start, end string) (time.Time, bool, error) {
+	startMin, err := ParseClock(start)
+	if err != nil {
+		return time.Time{}, false, err
+	}
+	endMin, err := ParseClock(end)
+	if err != nil {
+		return time.Time{}, false, err
+	}
+
+	local := now.In(loc)
+	current := local.Hour()*60 + local.Minute()
+
+	var quiet bool
+	switch {
+	case startMin < endMin:
+		quiet = current >= startMin && current < endMin
+	case startMin > endMin:
+		quiet = current >= startMin || current < endMin
+	}
+	if !quiet {
+		return time.Time{}, false, nil
+	}
+
+	// Quiet hours end later today, or tomorrow if the window started before midnight
+	day := local
+	if current >= endMin {
+		day = local.AddDate(0, 0, 1)
+	}
+	return time.Date(day.Year(), day.Month(), day.Day(), endMin/60, endMin%60, 0, 0, loc), true, nil
+}
@@ -0,0 +1,58 @@
+package utils
+
+import (
+	"testing"
+	"time"
+)
+
+func TestQuietHoursEnd(t *testing.T) {
+	kampala := time.FixedZone("EAT", 3*60*60)
+	at := func(day, hour, minute int) time.Time {
+		return time.Date(2024, time.May, day, hour, minute, 0, 0, kampala)
+	}
+
+	tests := []struct {
+		name       string
+		now        time.Time
+		start, end string
+		quiet      bool
+		until      time.Time
+	}{
+		{name: "before daytime window", now: at(10, 11, 59), start: "12:00", end: "14:00"},
+		{name: "start of daytime window", now: at(10, 12, 0), start: "12:00", end: "14:00", quiet: true, until: at(10, 14, 0)},
+		{name: "inside daytime window", now: at(10, 13, 30), start: "12:00", end: "14:00", quiet: true, until: at(10, 14, 0)},
+		{name: "end of daytime window", now: at(10, 14, 0), start: "12:00", end: "14:00"},
+		{name: "overnight window before midnight", now: at(10, 22, 15), start: "21:00", end: "08:00", quiet: true, until: at(11, 8, 0)},
+		{name: "overnight window after midnight", now: at(11, 3, 0), start: "21:00", end: "08:00", quiet: true, until: at(11, 8, 0)},
+		{name: "overnight window at start", now: at(10, 21, 0), start: "21:00", end: "08:00", quiet: true, until: at(11, 8, 0)},
+		{name: "overnight window at end", now: at(11, 8, 0), start: "21:00", end: "08:00"},
+		{name: "outside overnight window", now: at(10, 12, 0), start: "21:00", end: "08:00"},
+		{name: "end of month", now: at(31, 23, 0), start: "21:00", end: "08:00", quiet: true, until: time.Date(2024, time.June, 1, 8, 0, 0, 0, kampala)},
+		{name: "window ending at minutes", now: at(10, 6, 0), start: "22:30", end: "06:45", quiet: true, until: at(10, 6, 45)},
+		{name: "equal start and end", now: at(10, 9, 0), start: "09:00", end: "09:00"},
+		{name: "now in another zone", now: at(10, 22, 0).UTC(), start: "21:00", end: "08:00", quiet: true, until: at(11, 8, 0)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			until, quiet, err := QuietHoursEnd(tt.now, kampala, tt.start, tt.end)
+			if err != nil {
+				t.Fatalf("QuietHoursEnd returned error: %v", err)
+			}
+			if quiet != tt.quiet {
+				t.Fatalf("quiet = %v, want %v", quiet, tt.quiet)
+			}
+			if quiet && !until.Equal(tt.until) {
+				t.Errorf("until = %v, want %v", until, tt.until)
+			}
+		})
+	}
+}
+
+func TestQuietHoursEndInvalidClock(t *testing.T) {
+	for _, window := range [][2]string{{"9pm", "08:00"}, {"21:00", "24:30"}, {"", "08:00"}} {
+		if _, _, err := QuietHoursEnd(time.Now(), time.UTC, window[0], window[1]); err == nil {
+			t.Errorf("QuietHoursEnd(%q, %q) returned no error", window[0], window[1])
+		}
+	}
+}
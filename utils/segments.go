@@ -0,0 +1,59 @@
+package utils
+
+import (
+	"strings"
+	"unicode/utf16"
+)
+
+// Characters of the GSM 03.38 default alphabet, and of its extension table which take two septets
+const (
+	gsmBasicChars = "@£$¥èéùìòÇ\nØø\rÅåΔ_ΦΓΛΩΠΨΣΘΞÆæßÉ !\"#¤%&'()*+,-./0123456789:;<=>?" +
+		"¡ABCDEFGHIJKLMNOPQRSTUVWXYZÄÖÑÜ§¿abcdefghijklmnopqrstuvwxyzäöñüà"
+	gsmExtensionChars = "\f^{}\\[~]|€"
+)
+
+// Segment sizes for single and concatenated messages
+const (
+	gsmSegmentSize       = 160
+	gsmMultipartSize     = 153
+	unicodeSegmentSize   = 70
+	unicodeMultipartSize = 67
+)
+
+// MessageSegments returns the number of SMS segments needed to send a message.
+// Messages using only the GSM 7-bit alphabet fit 160 characters in one segment (153 per part
+// when split); any other character switches the whole message to UCS-2 with 70 (67) characters.
+func MessageSegments(message string) int {
+	if message == "" {
+		return 1
+	}
+
+	septets := 0
+	gsm := true
+	for _, r := range message {
+		switch {
+		case strings.ContainsRune(gsmBasicChars, r):
+			septets++
+		case strings.ContainsRune(gsmExtensionChars, r):
+			septets += 2
+		default:
+			gsm = false
+		}
+		if !gsm {
+			break
+		}
+	}
+
+	if gsm {
+		return segmentCount(septets, gsmSegmentSize, gsmMultipartSize)
+	}
+	return segmentCount(len(utf16.Encode([]rune(message))), unicodeSegmentSize, unicodeMultipartSize)
+}
+
+// segmentCount splits a message length into single or multipart segments
+func segmentCount(length, single, multipart int) int {
+	if length <= single {
+		return 1
+	}
+	return (length + multipart - 1) / multipart
+}
@@ -0,0 +1,20 @@
+package utils
+
+import (
+	"regexp"
+	"strings"
+)
+
+var templateVarPattern = regexp.MustCompile(`\{\{\s*([A-Za-z0-9_.-]+)\s*\}\}`)
+
+// RenderTemplate replaces {{name}} placeholders in a message template with values from vars
+// Placeholders without a matching variable are replaced with an empty string
+func RenderTemplate(template string, vars map[string]string) string {
+	return templateVarPattern.ReplaceAllStringFunc(template, func(match string) string {
+		key := templateVarPattern.FindStringSubmatch(match)[1]
+		if value, ok := vars[key]; ok {
+			return value
+		}
+		return vars[strings.ToLower(key)]
+	})
+}